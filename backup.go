@@ -0,0 +1,220 @@
+package webui
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// backupFormatVersion is the version of the backup archive format
+// if you make an incompatible change to the format, increment this version
+const backupFormatVersion = 1
+
+const (
+	backupManifestFile     = "manifest.json"
+	backupEventsFile       = "events.ndjson"
+	backupJobsFile         = "jobs.ndjson"
+	backupMergeHistoryFile = "merge-history.ndjson"
+)
+
+// BackupManifest describes the content of a backup archive
+type BackupManifest struct {
+	Version      int
+	Time         time.Time
+	Events       int
+	Jobs         int
+	MergeRecords int
+}
+
+// Backup writes a portable archive of the events, jobs and merge history of the store:
+// a gzipped tarball with 1 NDJSON file per type of document, and a manifest.
+// It doesn't depend on the store backend, so it can be restored into another backend.
+func Backup(store Store, w io.Writer) (*BackupManifest, error) {
+	manifest := BackupManifest{
+		Version: backupFormatVersion,
+		Time:    time.Now().UTC(),
+	}
+
+	events, err := encodeBackupFile(func(enc *json.Encoder) error {
+		return store.ExportEvents(EventsQuery{}, func(event Event) error {
+			manifest.Events++
+			return enc.Encode(event)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to backup the events: %w", err)
+	}
+
+	jobs, err := encodeBackupFile(func(enc *json.Encoder) error {
+		return store.ExportJobs(JobsQuery{}, func(job Job) error {
+			manifest.Jobs++
+			return enc.Encode(job)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to backup the jobs: %w", err)
+	}
+
+	mergeHistory, err := encodeBackupFile(func(enc *json.Encoder) error {
+		for _, record := range store.QueryMergeHistory(MergeHistoryQuery{}) {
+			manifest.MergeRecords++
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to backup the merge history: %w", err)
+	}
+
+	// the manifest must be the first file, so that we can check the version before restoring anything
+	manifestFile, err := encodeBackupFile(func(enc *json.Encoder) error {
+		return enc.Encode(manifest)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, file := range []struct {
+		name    string
+		content *bytes.Buffer
+	}{
+		{name: backupManifestFile, content: manifestFile},
+		{name: backupEventsFile, content: events},
+		{name: backupJobsFile, content: jobs},
+		{name: backupMergeHistoryFile, content: mergeHistory},
+	} {
+		err = tarWriter.WriteHeader(&tar.Header{
+			Name:    file.name,
+			Mode:    0644,
+			Size:    int64(file.content.Len()),
+			ModTime: manifest.Time,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write the header of %s: %w", file.name, err)
+		}
+		if _, err = file.content.WriteTo(tarWriter); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close the backup archive: %w", err)
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close the backup archive: %w", err)
+	}
+	return &manifest, nil
+}
+
+// encodeBackupFile buffers the file in memory, because the size of a tar entry must be known before writing it
+func encodeBackupFile(write func(*json.Encoder) error) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	if err := write(json.NewEncoder(buf)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Restore adds the content of a backup archive to the store.
+// The existing events and jobs are kept - or replaced if they have the same ID - and the merge history
+// of each Keeper found in the archive is replaced.
+func Restore(store Store, r io.Reader) (*BackupManifest, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer gzipReader.Close()
+
+	var (
+		tarReader    = tar.NewReader(gzipReader)
+		manifest     *BackupManifest
+		restored     BackupManifest
+		mergeHistory = map[string][]MergeRecord{}
+	)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+
+		if manifest == nil && header.Name != backupManifestFile {
+			return nil, fmt.Errorf("invalid backup archive: the first file must be %s, not %s", backupManifestFile, header.Name)
+		}
+
+		switch header.Name {
+		case backupManifestFile:
+			manifest = new(BackupManifest)
+			if err = json.NewDecoder(tarReader).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+			if manifest.Version > backupFormatVersion {
+				return nil, fmt.Errorf("unsupported backup version %d - the max supported version is %d", manifest.Version, backupFormatVersion)
+			}
+		case backupEventsFile:
+			err = readBackupFile(tarReader, func(dec *json.Decoder) error {
+				var event Event
+				if err := dec.Decode(&event); err != nil {
+					return err
+				}
+				restored.Events++
+				return store.AddEvent(event)
+			})
+		case backupJobsFile:
+			err = readBackupFile(tarReader, func(dec *json.Decoder) error {
+				var job Job
+				if err := dec.Decode(&job); err != nil {
+					return err
+				}
+				restored.Jobs++
+				return store.AddJob(job)
+			})
+		case backupMergeHistoryFile:
+			err = readBackupFile(tarReader, func(dec *json.Decoder) error {
+				var record MergeRecord
+				if err := dec.Decode(&record); err != nil {
+					return err
+				}
+				restored.MergeRecords++
+				mergeHistory[record.Source] = append(mergeHistory[record.Source], record)
+				return nil
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", header.Name, err)
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("invalid backup archive: no %s", backupManifestFile)
+	}
+	for source, records := range mergeHistory {
+		store.SetMergeHistory(source, records)
+	}
+
+	restored.Version = manifest.Version
+	restored.Time = manifest.Time
+	return &restored, nil
+}
+
+func readBackupFile(r io.Reader, decode func(*json.Decoder) error) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for dec.More() {
+		if err := decode(dec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package webui
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestBackupRestore(t *testing.T) {
+	var (
+		source = newTestStore(t)
+		now    = time.Now().UTC().Truncate(time.Second)
+	)
+
+	events := []Event{
+		{GUID: "guid-1", Owner: "owner", Repository: "repo", Branch: "main", Kind: "push", Sender: "alice", Time: now, SHA: "abc", CommitSHAs: []string{"abc"}},
+		{GUID: "guid-2", Owner: "owner", Repository: "repo", Branch: "PR-1", Kind: "pull_request", Action: "opened", Sender: "bob", Labels: []string{"bug"}, Time: now.Add(time.Minute)},
+	}
+	for _, event := range events {
+		if err := source.AddEvent(event); err != nil {
+			t.Fatalf("failed to add event %s: %v", event.GUID, err)
+		}
+	}
+	jobs := []Job{
+		{Name: "job-1", Type: "postsubmit", Owner: "owner", Repository: "repo", Branch: "main", Context: "build", State: "success", Start: now, End: now.Add(time.Minute), Duration: time.Minute, BaseSHA: "abc"},
+		{Name: "job-2", Type: "presubmit", Owner: "owner", Repository: "repo", Branch: "PR-1", Context: "build", State: "running", Start: now.Add(time.Minute)},
+	}
+	for _, job := range jobs {
+		if err := source.AddJob(job); err != nil {
+			t.Fatalf("failed to add job %s: %v", job.Name, err)
+		}
+	}
+	source.SetMergeHistory("keeper", []MergeRecord{
+		{Source: "keeper", Owner: "owner", Repository: "repo", Branch: "main", Time: now, Action: "MERGE", BaseSHA: "abc", PRs: []PullRequest{{Number: 1, Author: "bob", SHA: "def"}}},
+	})
+	source.SetMergeHistory("other-keeper", []MergeRecord{
+		{Source: "other-keeper", Owner: "owner", Repository: "other-repo", Branch: "main", Time: now, Action: "MERGE_BATCH", PRs: []PullRequest{{Number: 2}, {Number: 3}}},
+	})
+
+	var archive bytes.Buffer
+	manifest, err := Backup(source, &archive)
+	if err != nil {
+		t.Fatalf("failed to backup the store: %v", err)
+	}
+	if manifest.Events != 2 || manifest.Jobs != 2 || manifest.MergeRecords != 2 {
+		t.Errorf("unexpected backup manifest: %+v", manifest)
+	}
+
+	target := newTestStore(t)
+	restored, err := Restore(target, &archive)
+	if err != nil {
+		t.Fatalf("failed to restore the backup: %v", err)
+	}
+	if restored.Version != backupFormatVersion || !restored.Time.Equal(manifest.Time) {
+		t.Errorf("unexpected restore manifest %+v - the backup manifest was %+v", restored, manifest)
+	}
+	if restored.Events != manifest.Events || restored.Jobs != manifest.Jobs || restored.MergeRecords != manifest.MergeRecords {
+		t.Errorf("expected the restored counts to match the backup: %+v vs %+v", restored, manifest)
+	}
+
+	restoredEvents, err := target.QueryEvents(EventsQuery{})
+	if err != nil {
+		t.Fatalf("failed to query the events: %v", err)
+	}
+	if len(restoredEvents.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(restoredEvents.Events))
+	}
+	if event := restoredEvents.Events[1]; event.GUID != "guid-1" || !event.Time.Equal(now) || event.SHA != "abc" || len(event.CommitSHAs) != 1 {
+		t.Errorf("unexpected restored event: %+v", event)
+	}
+	if event := restoredEvents.Events[0]; event.GUID != "guid-2" || event.Action != "opened" || len(event.Labels) != 1 {
+		t.Errorf("unexpected restored event: %+v", event)
+	}
+
+	restoredJobs, err := target.QueryJobs(JobsQuery{})
+	if err != nil {
+		t.Fatalf("failed to query the jobs: %v", err)
+	}
+	if len(restoredJobs.Jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(restoredJobs.Jobs))
+	}
+	if job := restoredJobs.Jobs[1]; job.Name != "job-1" || job.State != "success" || job.Duration != time.Minute || job.BaseSHA != "abc" {
+		t.Errorf("unexpected restored job: %+v", job)
+	}
+
+	for _, source := range []string{"keeper", "other-keeper"} {
+		records := target.QueryMergeHistory(MergeHistoryQuery{Source: source})
+		if len(records) != 1 {
+			t.Errorf("expected 1 merge record for %s, got %d", source, len(records))
+		}
+	}
+	if records := target.QueryMergeHistory(MergeHistoryQuery{Source: "other-keeper"}); len(records) == 1 && len(records[0].PRs) != 2 {
+		t.Errorf("expected the PRs of the batch to be restored, got %+v", records[0].PRs)
+	}
+}
+
+func TestRestoreInvalidArchive(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := Restore(store, bytes.NewReader([]byte("not a backup"))); err == nil {
+		t.Error("expected an error for an invalid archive")
+	}
+}
@@ -0,0 +1,159 @@
+package webui
+
+import (
+	"sort"
+	"time"
+)
+
+// BranchHistory is the timeline of the postsubmit jobs of a branch, per context
+type BranchHistory struct {
+	Owner      string
+	Repository string
+	Branch     string
+	// Health is the health of the latest postsubmit job of each context, as for the BranchHealth
+	Health   string
+	Contexts []ContextHistory
+}
+
+// ContextHistory is the timeline of the postsubmit jobs of a context, as streaks of passing or failing jobs
+type ContextHistory struct {
+	Context string
+	Health  string
+	// Streaks are the consecutive completed jobs with the same health, most recent first
+	Streaks []HealthStreak
+	// BrokenBy is set when the latest completed job failed: it is the first job of the current failing streak
+	BrokenBy *BrokenBy
+}
+
+// HealthStreak is a sequence of completed jobs which either all passed or all failed
+type HealthStreak struct {
+	// Health is either HealthSuccess or HealthFailure
+	Health string
+	From   time.Time
+	To     time.Time
+	// Jobs are sorted by most recent first
+	Jobs []Job
+}
+
+// BrokenBy is the first failing job of a context, with the merges which produced its commit
+type BrokenBy struct {
+	Job Job
+	// LastSuccess is the last passing job before Job - if any
+	LastSuccess *Job
+	// Merges are the keeper merges which produced the commit of the job - or if we can't find them from the push events,
+	// the merges on the branch between the last passing job and the first failing job
+	Merges []MergeRecord
+	// Authors are the authors of the merged PRs
+	Authors []string
+}
+
+// newBranchHistory builds the timeline of each context, from the postsubmit jobs of the branch
+// jobs and events are expected to be sorted by most recent first, as returned by Store.QueryJobs and Store.QueryEvents
+func newBranchHistory(owner, repository, branch string, jobs []Job, events []Event, records []MergeRecord) *BranchHistory {
+	var (
+		contexts      []string
+		jobsByContext = map[string][]Job{}
+	)
+	for _, job := range jobs {
+		if job.Type != "postsubmit" || job.Branch != branch {
+			continue
+		}
+		if _, found := jobsByContext[job.Context]; !found {
+			contexts = append(contexts, job.Context)
+		}
+		jobsByContext[job.Context] = append(jobsByContext[job.Context], job)
+	}
+	sort.Strings(contexts)
+
+	var branchRecords []MergeRecord
+	for _, record := range records {
+		if record.Branch == branch {
+			branchRecords = append(branchRecords, record)
+		}
+	}
+
+	history := BranchHistory{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     branch,
+	}
+	var healths []string
+	for _, context := range contexts {
+		contextJobs := jobsByContext[context]
+		contextHistory := ContextHistory{
+			Context: context,
+			Health:  jobsHealth(contextJobs[:1]),
+			Streaks: healthStreaks(contextJobs),
+		}
+		if len(contextHistory.Streaks) > 0 && contextHistory.Streaks[0].Health == HealthFailure {
+			contextHistory.BrokenBy = brokenBy(contextHistory.Streaks, events, branchRecords)
+		}
+		history.Contexts = append(history.Contexts, contextHistory)
+		healths = append(healths, contextHistory.Health)
+	}
+	history.Health = worstHealth(healths...)
+	return &history
+}
+
+// healthStreaks groups the completed jobs into streaks - ignoring the jobs which are still running
+func healthStreaks(jobs []Job) []HealthStreak {
+	var streaks []HealthStreak
+	for _, job := range jobs {
+		health := jobsHealth([]Job{job})
+		if health != HealthSuccess && health != HealthFailure {
+			continue
+		}
+		if len(streaks) == 0 || streaks[len(streaks)-1].Health != health {
+			streaks = append(streaks, HealthStreak{
+				Health: health,
+				To:     job.Start,
+			})
+		}
+		streak := &streaks[len(streaks)-1]
+		streak.From = job.Start
+		streak.Jobs = append(streak.Jobs, job)
+	}
+	return streaks
+}
+
+// brokenBy finds the merges which broke the context, for the current failing streak - which is the first one
+func brokenBy(streaks []HealthStreak, events []Event, records []MergeRecord) *BrokenBy {
+	failingJobs := streaks[0].Jobs
+	broken := BrokenBy{
+		Job: failingJobs[len(failingJobs)-1],
+	}
+	if len(streaks) > 1 {
+		lastSuccess := streaks[1].Jobs[0]
+		broken.LastSuccess = &lastSuccess
+	}
+
+	if broken.Job.BaseSHA != "" {
+		broken.Merges = producedByMerges(broken.Job.BaseSHA, events, records)
+	}
+	if len(broken.Merges) == 0 && broken.LastSuccess != nil {
+		for _, record := range records {
+			if record.Action != "MERGE" && record.Action != "MERGE_BATCH" {
+				continue
+			}
+			if record.Time.After(broken.Job.Start) || !record.Time.After(broken.LastSuccess.Start) {
+				continue
+			}
+			broken.Merges = append(broken.Merges, record)
+		}
+		sort.SliceStable(broken.Merges, func(i, j int) bool {
+			return broken.Merges[i].Time.After(broken.Merges[j].Time)
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, record := range broken.Merges {
+		for _, pr := range record.PRs {
+			if pr.Author != "" && !seen[pr.Author] {
+				seen[pr.Author] = true
+				broken.Authors = append(broken.Authors, pr.Author)
+			}
+		}
+	}
+	sort.Strings(broken.Authors)
+	return &broken
+}
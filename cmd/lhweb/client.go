@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+)
+
+// client uses the export endpoints of the plugin, which return all the fields of the events, jobs and merge records
+type client struct {
+	baseURL    string
+	httpClient http.Client
+}
+
+// exportRows reads the NDJSON export of the given path, up to limit rows - if non-zero
+func (c *client) exportRows(ctx context.Context, path string, params url.Values, limit int) ([]map[string]interface{}, error) {
+	resp, err := c.get(ctx, path+".ndjson", params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	rows := []map[string]interface{}{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var row map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("invalid response from %s: %w", path, err)
+		}
+		rows = append(rows, row)
+		if limit > 0 && len(rows) >= limit {
+			// no need to read the rest of the export
+			break
+		}
+	}
+	return rows, scanner.Err()
+}
+
+func (c *client) mergeStatus(ctx context.Context, path string, params url.Values) ([]webui.MergePool, error) {
+	resp, err := c.get(ctx, path+".json", params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var pools []webui.MergePool
+	if err = json.NewDecoder(resp.Body).Decode(&pools); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	return pools, nil
+}
+
+func (c *client) get(ctx context.Context, path string, params url.Values) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("GET %s: %s: %s", u, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
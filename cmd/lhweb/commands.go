@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// result is the output of a command
+type result struct {
+	// columns are the fields displayed in the table output
+	columns []string
+	rows    []map[string]interface{}
+	// data is used for the JSON and YAML outputs - if nil, the rows are used
+	data interface{}
+}
+
+type command struct {
+	run func(ctx context.Context, c *client, path string, opts options) (*result, error)
+}
+
+var commands = map[string]command{
+	"events": {
+		run: func(ctx context.Context, c *client, path string, opts options) (*result, error) {
+			rows, err := c.exportRows(ctx, pathWithPrefix("/events", path), opts.queryParams(), opts.limit)
+			if err != nil {
+				return nil, err
+			}
+			return &result{
+				columns: []string{"Time", "Owner", "Repository", "Branch", "Kind", "Action", "Sender", "Details"},
+				rows:    rows,
+			}, nil
+		},
+	},
+	"jobs": {
+		run: func(ctx context.Context, c *client, path string, opts options) (*result, error) {
+			rows, err := c.exportRows(ctx, pathWithPrefix("/jobs", path), opts.queryParams(), opts.limit)
+			if err != nil {
+				return nil, err
+			}
+			return &result{
+				columns: []string{"Start", "Owner", "Repository", "Branch", "Context", "State", "Duration", "Author"},
+				rows:    rows,
+			}, nil
+		},
+	},
+	"merge-history": {
+		run: func(ctx context.Context, c *client, path string, opts options) (*result, error) {
+			params, err := opts.mergeParams()
+			if err != nil {
+				return nil, err
+			}
+			rows, err := c.exportRows(ctx, pathWithPrefix("/merge/history", path), params, opts.limit)
+			if err != nil {
+				return nil, err
+			}
+			return &result{
+				columns: []string{"Time", "Owner", "Repository", "Branch", "Action", "Number", "Title", "Author"},
+				rows:    rows,
+			}, nil
+		},
+	},
+	"merge-status": {
+		run: func(ctx context.Context, c *client, path string, opts options) (*result, error) {
+			if opts.from != "" || opts.to != "" {
+				return nil, errors.New("the -from and -to flags are not supported by the merge-status command")
+			}
+			params, err := opts.mergeParams()
+			if err != nil {
+				return nil, err
+			}
+			pools, err := c.mergeStatus(ctx, pathWithPrefix("/merge/status", path), params)
+			if err != nil {
+				return nil, err
+			}
+
+			rows := make([]map[string]interface{}, 0, len(pools))
+			for _, pool := range pools {
+				rows = append(rows, map[string]interface{}{
+					"Owner":      pool.Owner,
+					"Repository": pool.Repository,
+					"Branch":     pool.Branch,
+					"Action":     pool.Action,
+					"Success":    len(pool.SuccessPRs),
+					"Pending":    len(pool.PendingPRs),
+					"Missing":    len(pool.MissingPRs),
+					"Blockers":   len(pool.Blockers),
+					"Error":      pool.Error,
+				})
+			}
+			return &result{
+				columns: []string{"Owner", "Repository", "Branch", "Action", "Success", "Pending", "Missing", "Blockers", "Error"},
+				rows:    rows,
+				data:    pools,
+			}, nil
+		},
+	},
+}
+
+// pathWithPrefix returns the API path for the given owner[/repository[/branch]] path
+func pathWithPrefix(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		segments = append(segments, url.PathEscape(segment))
+	}
+	return prefix + "/" + strings.Join(segments, "/")
+}
+
+func (o options) queryParams() url.Values {
+	params := url.Values{}
+	if o.query != "" {
+		params.Set("q", o.query)
+	}
+	if o.from != "" {
+		params.Set("from", o.from)
+	}
+	if o.to != "" {
+		params.Set("to", o.to)
+	}
+	return params
+}
+
+// mergeParams returns the query parameters for the merge commands, which don't support queries
+func (o options) mergeParams() (url.Values, error) {
+	if o.query != "" {
+		return nil, fmt.Errorf("the -q flag is only supported by the events and jobs commands")
+	}
+	params := o.queryParams()
+	if o.source != "" {
+		params.Set("source", o.source)
+	}
+	return params, nil
+}
@@ -0,0 +1,151 @@
+// lhweb is a command-line client for the Lighthouse Web UI plugin
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/internal/version"
+)
+
+const usage = `lhweb queries a Lighthouse Web UI plugin.
+
+Usage:
+  lhweb COMMAND [OWNER[/REPOSITORY[/BRANCH]]] [flags]
+
+Commands:
+  events         the webhook events received by Lighthouse
+  jobs           the Lighthouse jobs
+  merge-status   the Keeper merge pools
+  merge-history  the PRs merged by Keeper
+  version        print the version
+
+Examples:
+  lhweb jobs my-org/my-repo/PR-123 -q State:running
+  lhweb events my-org -from "last 24h" -o json
+  lhweb merge-status my-org/my-repo -watch
+
+Flags:
+`
+
+type options struct {
+	url      string
+	query    string
+	from     string
+	to       string
+	source   string
+	output   string
+	limit    int
+	watch    bool
+	interval time.Duration
+}
+
+func main() {
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		fmt.Fprint(os.Stderr, usage)
+		newFlagSet("lhweb", new(options)).PrintDefaults()
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	if command == "version" {
+		fmt.Printf("Version %s - Revision %s - Date %s\n", version.Version, version.Revision, version.Date)
+		return
+	}
+	c, found := commands[command]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command %q - run lhweb without arguments for the usage\n", command)
+		os.Exit(2)
+	}
+
+	var (
+		opts  options
+		flags = newFlagSet(command, &opts)
+		path  string
+	)
+	// the path can be given before or after the flags
+	args := os.Args[2:]
+	for {
+		if err := flags.Parse(args); err != nil {
+			os.Exit(2)
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		if path != "" {
+			fmt.Fprintf(os.Stderr, "unexpected argument %q\n", flags.Arg(0))
+			os.Exit(2)
+		}
+		path = strings.Trim(flags.Arg(0), "/")
+		args = flags.Args()[1:]
+	}
+	if strings.Count(path, "/") > 2 {
+		fmt.Fprintf(os.Stderr, "invalid path %q - expected OWNER[/REPOSITORY[/BRANCH]]\n", path)
+		os.Exit(2)
+	}
+
+	w, err := newWriter(opts.output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	apiClient := &client{baseURL: strings.TrimSuffix(opts.url, "/")}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	for {
+		result, err := c.run(ctx, apiClient, path, opts)
+		if ctx.Err() != nil {
+			return
+		}
+		if opts.watch {
+			// clear the screen, like the watch command
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Every %s: lhweb %s %s - %s\n\n", opts.interval, command, path, time.Now().Format("2006-01-02 15:04:05"))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			if !opts.watch {
+				os.Exit(1)
+			}
+		} else if err = w.write(os.Stdout, result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if !opts.watch {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.interval):
+		}
+	}
+}
+
+func newFlagSet(name string, opts *options) *flag.FlagSet {
+	defaultURL := os.Getenv("LHWEB_URL")
+	if defaultURL == "" {
+		defaultURL = "http://localhost:8080"
+	}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&opts.url, "url", defaultURL, "URL of the Lighthouse Web UI plugin. Default: LHWEB_URL env var value")
+	flags.StringVar(&opts.query, "q", "", "Query to filter the events or jobs, using the same syntax as the UI - such as State:running")
+	flags.StringVar(&opts.from, "from", "", "Start of the time range - such as 2021-06-01 or 'last 24h'")
+	flags.StringVar(&opts.to, "to", "", "End of the time range - such as 2021-06-01 or 'now-1h'")
+	flags.StringVar(&opts.source, "source", "", "Name of the Keeper, for the merge-status and merge-history commands")
+	flags.StringVar(&opts.output, "o", outputTable, fmt.Sprintf("Output format - one of: %s, %s or %s", outputTable, outputJSON, outputYAML))
+	flags.IntVar(&opts.limit, "limit", 20, "Max number of results, most recent first. If zero, all the results are returned")
+	flags.BoolVar(&opts.watch, "watch", false, "Refresh the results periodically, until interrupted")
+	flags.DurationVar(&opts.interval, "interval", 5*time.Second, "Interval between the refreshes, in watch mode")
+	return flags
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// maxTableValueLength is the max number of characters of a value in the table output
+const maxTableValueLength = 60
+
+type writer struct {
+	format string
+}
+
+func newWriter(format string) (*writer, error) {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return &writer{format: format}, nil
+	default:
+		return nil, fmt.Errorf("invalid output format %q - valid formats are %s, %s and %s", format, outputTable, outputJSON, outputYAML)
+	}
+}
+
+func (w *writer) write(out io.Writer, r *result) error {
+	data := r.data
+	if data == nil {
+		data = r.rows
+	}
+
+	switch w.format {
+	case outputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case outputYAML:
+		// going through JSON, so that the YAML has the same field names as the JSON
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		var yamlData interface{}
+		if err = yaml.Unmarshal(jsonData, &yamlData); err != nil {
+			return err
+		}
+		yamlBytes, err := yaml.Marshal(yamlData)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(yamlBytes)
+		return err
+	default:
+		return writeTable(out, r)
+	}
+}
+
+func writeTable(out io.Writer, r *result) error {
+	if len(r.rows) == 0 {
+		_, err := fmt.Fprintln(out, "No results")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(r.columns, "\t")))
+	for _, row := range r.rows {
+		values := make([]string, 0, len(r.columns))
+		for _, column := range r.columns {
+			values = append(values, tableValue(column, row[column]))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+func tableValue(column string, value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+		// the times are exported in RFC3339
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			s = t.Local().Format("2006-01-02 15:04:05")
+		}
+	case float64:
+		if column == "Duration" {
+			// the durations are exported in seconds
+			s = (time.Duration(v * float64(time.Second))).Round(time.Second).String()
+		} else {
+			s = fmt.Sprint(v)
+		}
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxTableValueLength {
+		s = string(runes[:maxTableValueLength-3]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runStoreCommand runs the backup or restore command against the store configured with the -store-* flags.
+// The file defaults to stdout/stdin, and the store must not be used by a running server at the same time.
+func runStoreCommand(command, file string, logger *logrus.Logger) error {
+	if !options.storeConfig.PersistsEvents() {
+		return fmt.Errorf("the %s command needs a store with a data path: without it, the store is only kept in memory by the server", command)
+	}
+	if !options.storeConfig.PersistsJobsAndMergeHistory() {
+		logger.WithField("backend", options.storeConfig.Backend).Warningf("this store backend keeps the jobs and merge history in memory: the %s only has the events", command)
+	}
+
+	store, err := webui.NewStore(options.storeConfig, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open the store: %w", err)
+	}
+	defer store.Close()
+
+	var manifest *webui.BackupManifest
+	switch command {
+	case "backup":
+		var w io.Writer = os.Stdout
+		if file != "" && file != "-" {
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		manifest, err = webui.Backup(store, w)
+	case "restore":
+		var r io.Reader = os.Stdin
+		if file != "" && file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		manifest, err = webui.Restore(store, r)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.WithField("events", manifest.Events).WithField("jobs", manifest.Jobs).WithField("mergeRecords", manifest.MergeRecords).Infof("%s done", command)
+	if command == "restore" && options.storeConfig.PersistsJobsAndMergeHistory() {
+		logger.Warning("the restored jobs which are not in the cluster anymore will be pruned on startup, and the restored merge history will be replaced by the next sync of each Keeper")
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/web/handlers/functions"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// loadOptions parses the command-line arguments, and the config file - if any.
+// The flags set on the command line take precedence over the config file.
+func loadOptions(args []string) (*serverOptions, error) {
+	opts := new(serverOptions)
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	opts.registerFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	opts.args = flags.Args()
+
+	if opts.configFile != "" {
+		if err := applyConfigFile(flags, opts.configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// repeatableFlag is implemented by the flags which are set once per value of a list or map in the config file
+type repeatableFlag interface {
+	repeatable()
+}
+
+// applyConfigFile sets the flags from a YAML config file.
+// The keys are the names of the flags, and the values can be:
+// - scalars
+// - lists, for the comma-separated or repeatable flags, such as badge-repositories
+// - maps, for the name=value flags, such as keeper-endpoint or link-template
+func applyConfigFile(flags *flag.FlagSet, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the config file: %w", err)
+	}
+
+	var config map[string]interface{}
+	if err = yaml.Unmarshal(content, &config); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	setOnCommandLine := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	for _, name := range sortedKeys(config) {
+		if name == "config" {
+			return fmt.Errorf("invalid config file %s: the config option can only be set on the command line", path)
+		}
+		f := flags.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("invalid config file %s: unknown option %q", path, name)
+		}
+		if setOnCommandLine[name] {
+			continue
+		}
+
+		values := []interface{}{config[name]}
+		if _, ok := f.Value.(repeatableFlag); ok {
+			values = configValueToRepeatedValues(config[name])
+		}
+		for _, v := range values {
+			value, err := configValueToFlagValue(v)
+			if err != nil {
+				return fmt.Errorf("invalid config file %s: invalid value for %s: %w", path, name, err)
+			}
+			if err = flags.Set(name, value); err != nil {
+				return fmt.Errorf("invalid config file %s: invalid value for %s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configValueToRepeatedValues splits a list or map into its values - the map entries in the key=value format, sorted by key
+func configValueToRepeatedValues(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case map[interface{}]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, fmt.Sprint(key))
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, fmt.Sprintf("%s=%v", key, v[key]))
+		}
+		return values
+	default:
+		return []interface{}{value}
+	}
+}
+
+func configValueToFlagValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			itemValue, err := configValueToFlagValue(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, itemValue)
+		}
+		return strings.Join(values, ","), nil
+	case map[interface{}]interface{}:
+		var values []string
+		for key, item := range v {
+			itemValue, err := configValueToFlagValue(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, fmt.Sprintf("%v=%s", key, itemValue))
+		}
+		sort.Strings(values)
+		return strings.Join(values, ","), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (o *serverOptions) validate() error {
+	if _, err := logrus.ParseLevel(o.logLevel); err != nil {
+		return fmt.Errorf("invalid log-level: %w", err)
+	}
+	switch o.storeConfig.Backend {
+	case webui.StoreBackendBleve, webui.StoreBackendSQLite:
+	default:
+		return fmt.Errorf("invalid store-backend %q - valid values are %s and %s", o.storeConfig.Backend, webui.StoreBackendBleve, webui.StoreBackendSQLite)
+	}
+	if o.jobArchiveEnabled && o.storeConfig.DataPath == "" {
+		return errors.New("job-archive requires a store-data-path")
+	}
+	if o.jobArchiveConfig.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid job-archive-max-size-mb %d: must be positive", o.jobArchiveConfig.MaxSizeMB)
+	}
+	if o.storeConfig.MaxEvents < 0 {
+		return fmt.Errorf("invalid store-max-events %d: must be positive", o.storeConfig.MaxEvents)
+	}
+	for name, d := range map[string]time.Duration{
+		"resync-interval":        o.resyncInterval,
+		"keeper-sync-interval":   o.keeperSyncInterval,
+		"badge-cache-max-age":    o.badgeCacheMaxAge,
+		"store-events-max-age":   o.storeConfig.EventsMaxAge,
+		"job-archive-max-age":    o.jobArchiveConfig.MaxAge,
+		"config-reload-interval": o.configReloadInterval,
+	} {
+		if d < 0 {
+			return fmt.Errorf("invalid %s %s: must be positive", name, d)
+		}
+	}
+	if o.tracingConfig.SamplingRatio < 0 || o.tracingConfig.SamplingRatio > 1 {
+		return fmt.Errorf("invalid tracing-sampling-ratio %v: must be between 0 and 1", o.tracingConfig.SamplingRatio)
+	}
+	if _, err := functions.NewURLTemplate(o.eventTraceURLTemplate); err != nil {
+		return fmt.Errorf("invalid event-trace-url-template: %w", err)
+	}
+	if _, err := functions.NewLinkTemplates(o.linkTemplates.templates); err != nil {
+		return fmt.Errorf("invalid link-template: %w", err)
+	}
+	return nil
+}
+
+// reloadableComponents are the components which can be re-configured at runtime
+type reloadableComponents struct {
+	logger                *logrus.Logger
+	store                 webui.Store
+	eventTraceURLTemplate *functions.URLTemplate
+	linkTemplates         *functions.LinkTemplates
+	keeperSyncers         []*webui.KeeperSyncer
+	jobArchive            *webui.JobArchive
+	globalSearches        *webui.GlobalSavedSearches
+}
+
+// watchConfigFile reloads the config file when its content changes - which works with mounted ConfigMaps
+// only the log level, event trace URL, link templates and saved searches, store GC limits, job archive retention and Keeper sync interval can be changed at runtime:
+// the other changes require a restart
+func watchConfigFile(ctx context.Context, current *serverOptions, components reloadableComponents) {
+	log := components.logger.WithField("configFile", current.configFile)
+	previousContent, _ := os.ReadFile(current.configFile)
+	previousHash := sha256.Sum256(previousContent)
+
+	ticker := time.NewTicker(current.configReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		content, err := os.ReadFile(current.configFile)
+		if err != nil {
+			log.WithError(err).Error("failed to read the config file")
+			continue
+		}
+		hash := sha256.Sum256(content)
+		if bytes.Equal(hash[:], previousHash[:]) {
+			continue
+		}
+		previousHash = hash
+
+		next, err := loadOptions(os.Args[1:])
+		if err != nil {
+			log.WithError(err).Error("invalid config file - keeping the previous config")
+			continue
+		}
+		reloaded, err := reloadOptions(current, next, components)
+		if err != nil {
+			log.WithError(err).Error("failed to reload the config")
+			continue
+		}
+		log.Info("Reloaded the config file")
+		current = reloaded
+	}
+}
+
+// reloadOptions applies the changes which can be applied at runtime, and returns the options in use after the reload
+func reloadOptions(current, next *serverOptions, components reloadableComponents) (*serverOptions, error) {
+	// everything which is not reloaded must be unchanged
+	reloaded := *current
+	reloaded.logLevel = next.logLevel
+	reloaded.eventTraceURLTemplate = next.eventTraceURLTemplate
+	reloaded.linkTemplates = next.linkTemplates
+	reloaded.savedSearches = next.savedSearches
+	reloaded.storeConfig.MaxEvents = next.storeConfig.MaxEvents
+	reloaded.storeConfig.EventsMaxAge = next.storeConfig.EventsMaxAge
+	reloaded.jobArchiveConfig = next.jobArchiveConfig
+	if current.keeperSyncInterval > 0 && next.keeperSyncInterval > 0 {
+		reloaded.keeperSyncInterval = next.keeperSyncInterval
+	}
+	if !reflect.DeepEqual(&reloaded, next) {
+		components.logger.Warning("Some changes in the config file can't be applied at runtime, and require a restart")
+	}
+
+	if next.logLevel != current.logLevel {
+		logLevel, _ := logrus.ParseLevel(next.logLevel)
+		components.logger.SetLevel(logLevel)
+	}
+	if next.eventTraceURLTemplate != current.eventTraceURLTemplate {
+		if err := components.eventTraceURLTemplate.Set(next.eventTraceURLTemplate); err != nil {
+			return nil, err
+		}
+	}
+	if !reflect.DeepEqual(next.linkTemplates, current.linkTemplates) {
+		if err := components.linkTemplates.Set(next.linkTemplates.templates); err != nil {
+			return nil, err
+		}
+	}
+	if !reflect.DeepEqual(next.savedSearches, current.savedSearches) {
+		components.globalSearches.Set(next.savedSearches.searches)
+	}
+	if next.storeConfig.MaxEvents != current.storeConfig.MaxEvents || next.storeConfig.EventsMaxAge != current.storeConfig.EventsMaxAge {
+		components.store.SetGarbageCollectionLimits(next.storeConfig.MaxEvents, next.storeConfig.EventsMaxAge)
+	}
+	if next.jobArchiveConfig != current.jobArchiveConfig && components.jobArchive != nil {
+		components.jobArchive.SetRetentionLimits(next.jobArchiveConfig.MaxSizeMB, next.jobArchiveConfig.MaxAge)
+	}
+	if reloaded.keeperSyncInterval != current.keeperSyncInterval {
+		for _, syncer := range components.keeperSyncers {
+			syncer.SetSyncInterval(reloaded.keeperSyncInterval)
+		}
+	}
+	return &reloaded, nil
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -15,47 +17,97 @@ import (
 	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
 	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/internal/kube"
 	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/internal/lighthouse"
+	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/internal/tracing"
 	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/internal/version"
 	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/web/handlers"
+	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/web/handlers/functions"
 
 	lhclientset "github.com/jenkins-x/lighthouse/pkg/client/clientset/versioned"
 	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/dynamic"
 )
 
-var (
-	options struct {
-		namespace             string
-		resyncInterval        time.Duration
-		lighthouseHMACKey     string
-		keeperEndpoint        string
-		keeperSyncInterval    time.Duration
-		eventTraceURLTemplate string
-		storeConfig           webui.StoreConfig
-		kubeConfigPath        string
-		listenAddr            string
-		logLevel              string
-		printVersion          bool
-	}
-)
+// options are the options of the server, set by the flags and the config file
+var options *serverOptions
+
+type serverOptions struct {
+	namespace             string
+	resyncInterval        time.Duration
+	lighthouseHMACKey     string
+	keeperEndpoints       keeperEndpoints
+	keeperSyncInterval    time.Duration
+	keeperIngestToken     string
+	backupToken           string
+	eventTraceURLTemplate string
+	linkTemplates         linkTemplates
+	savedSearches         savedSearches
+	userHeader            string
+	badgeRepositories     string
+	badgeCacheMaxAge      time.Duration
+	storeConfig           webui.StoreConfig
+	tracingConfig         tracing.Config
+	tektonEnabled         bool
+	jobArchiveEnabled     bool
+	jobArchiveConfig      webui.JobArchiveConfig
+	peersDNSName          string
+	peersPort             int
+	podIP                 string
+	kubeConfigPath        string
+	listenAddr            string
+	logLevel              string
+	printVersion          bool
+	configFile            string
+	configReloadInterval  time.Duration
+	// args are the remaining command-line arguments, after the flags
+	args []string
+}
 
-func init() {
-	flag.StringVar(&options.namespace, "namespace", "jx", "Name of the namespace with the lighthouse jobs")
-	flag.DurationVar(&options.resyncInterval, "resync-interval", 1*time.Hour, "Resync interval between full re-list operations")
-	flag.StringVar(&options.lighthouseHMACKey, "lighthouse-hmac-key", os.Getenv("LIGHTHOUSE_HMAC_KEY"), "HMAC key used by Lighthouse to sign the webhooks")
-	flag.StringVar(&options.keeperEndpoint, "keeper-endpoint", "http://lighthouse-keeper.jx", "Endpoint of the Lighthouse Keeper service, to retrieve the Keeper state. Format: scheme://host:port")
-	flag.DurationVar(&options.keeperSyncInterval, "keeper-sync-interval", 1*time.Minute, "Interval to poll the Lighthouse Keeper service for its state")
-	flag.StringVar(&options.eventTraceURLTemplate, "event-trace-url-template", "", "Go template string used to build the event trace URL")
-	flag.StringVar(&options.logLevel, "log-level", "INFO", "Log level - one of: trace, debug, info, warn(ing), error, fatal or panic")
-	flag.StringVar(&options.storeConfig.DataPath, "store-data-path", "", "If non-empty, the events store will be persisted on disk in the directory")
-	flag.IntVar(&options.storeConfig.MaxEvents, "store-max-events", 0, "If non-zero, the internal GC will ensure that no more than that many number of events will be stored/persisted")
-	flag.DurationVar(&options.storeConfig.EventsMaxAge, "store-events-max-age", 0, "If non-zero, the internal GC will ensure to events older than this age (duration) will be removed from the store")
-	flag.StringVar(&options.kubeConfigPath, "kubeconfig", kube.DefaultKubeConfigPath(), "Kubernetes Config Path. Default: KUBECONFIG env var value")
-	flag.StringVar(&options.listenAddr, "listen-addr", ":8080", "Address on which the server will listen for incoming connections")
-	flag.BoolVar(&options.printVersion, "version", false, "Print the version")
+func (o *serverOptions) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.namespace, "namespace", "jx", "Name of the namespace with the lighthouse jobs")
+	fs.DurationVar(&o.resyncInterval, "resync-interval", 1*time.Hour, "Resync interval between full re-list operations")
+	fs.StringVar(&o.lighthouseHMACKey, "lighthouse-hmac-key", os.Getenv("LIGHTHOUSE_HMAC_KEY"), "HMAC key used by Lighthouse to sign the webhooks")
+	o.keeperEndpoints.endpoints = []keeperEndpoint{{name: webui.DefaultKeeperName, url: "http://lighthouse-keeper.jx"}}
+	fs.Var(&o.keeperEndpoints, "keeper-endpoint", "Endpoint of a Lighthouse Keeper service, to retrieve the Keeper state. Format: [name=]scheme://host:port. Can be repeated (or comma-separated) to sync multiple Keepers. If empty, Keeper won't be polled")
+	fs.DurationVar(&o.keeperSyncInterval, "keeper-sync-interval", 1*time.Minute, "Interval to poll the Lighthouse Keeper service for its state. If zero, Keeper won't be polled")
+	fs.StringVar(&o.keeperIngestToken, "keeper-ingest-token", os.Getenv("KEEPER_INGEST_TOKEN"), "If non-empty, enables the /keeper/pools and /keeper/history endpoints to receive the Keeper state, authenticated with this bearer token")
+	fs.StringVar(&o.backupToken, "backup-token", os.Getenv("BACKUP_TOKEN"), "If non-empty, enables the /backup and /restore endpoints, authenticated with this bearer token")
+	fs.StringVar(&o.eventTraceURLTemplate, "event-trace-url-template", "", "Go template string used to build the event trace URL")
+	fs.Var(&o.linkTemplates, "link-template", "Named Go template used to build an external link of the jobs and events - such as to the logs or a dashboard - evaluated with the fields of the job or event. Format: name=template. Can be repeated")
+	fs.Var(&o.savedSearches, "saved-search", "Named search of the events or jobs page, listed for all the users - such as failing-postsubmits=/jobs/my-org?q=State:failure Type:postsubmit. Format: name=URL. Can be repeated")
+	fs.StringVar(&o.userHeader, "user-header", "", "HTTP header with the name of the authenticated user - such as X-Forwarded-User, set by an authenticating proxy - to store the saved searches of each user. If empty, the users are identified by a cookie")
+	fs.StringVar(&o.badgeRepositories, "badge-repositories", "", "Comma-separated list of owner/repository patterns (such as my-org/*) for which the status badges can be rendered. If empty, badges are rendered for all repositories")
+	fs.DurationVar(&o.badgeCacheMaxAge, "badge-cache-max-age", 1*time.Minute, "Duration for which the clients can cache the status badges")
+	fs.StringVar(&o.logLevel, "log-level", "INFO", "Log level - one of: trace, debug, info, warn(ing), error, fatal or panic")
+	fs.StringVar(&o.storeConfig.Backend, "store-backend", webui.StoreBackendBleve, fmt.Sprintf("Backend of the store - either %s (Bleve indexes for the events and jobs, in memory for the rest) or %s (embedded SQLite database for everything, which can be queried with SQL)", webui.StoreBackendBleve, webui.StoreBackendSQLite))
+	fs.StringVar(&o.storeConfig.DataPath, "store-data-path", "", "If non-empty, the events store will be persisted on disk in the directory")
+	fs.IntVar(&o.storeConfig.MaxEvents, "store-max-events", 0, "If non-zero, the internal GC will ensure that no more than that many number of events will be stored/persisted")
+	fs.DurationVar(&o.storeConfig.EventsMaxAge, "store-events-max-age", 0, "If non-zero, the internal GC will ensure to events older than this age (duration) will be removed from the store")
+	fs.StringVar(&o.tracingConfig.OTLPEndpoint, "tracing-otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), "Endpoint of an OTLP/HTTP collector to send the traces of the plugin to - either host:port (using HTTPS) or http(s)://host:port/path. If empty, tracing is disabled. Default: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT env var value")
+	fs.Float64Var(&o.tracingConfig.SamplingRatio, "tracing-sampling-ratio", 1, "Ratio of the new traces which are sampled, between 0 and 1. The traces started by Lighthouse follow its own sampling decision")
+	fs.BoolVar(&o.tektonEnabled, "tekton-enabled", false, "Watch the Tekton PipelineRuns and TaskRuns of the jobs, to display their pipeline and stream the logs of their steps. Requires read access to the Tekton resources and to the logs of the pods")
+	fs.BoolVar(&o.jobArchiveEnabled, "job-archive", false, "Archive the completed jobs in the store data path - their YAML, and the logs of their steps if tekton-enabled - so that they are still available once garbage-collected from the cluster")
+	fs.Int64Var(&o.jobArchiveConfig.MaxSizeMB, "job-archive-max-size-mb", 1024, "If non-zero, the oldest archived jobs are removed once the archive is larger than this size, in MB")
+	fs.DurationVar(&o.jobArchiveConfig.MaxAge, "job-archive-max-age", 30*24*time.Hour, "If non-zero, the archived jobs older than this age (duration) are removed")
+	fs.StringVar(&o.peersDNSName, "peers-dns-name", "", "DNS name resolving to the IPs of all the replicas - such as a headless service. If non-empty, the webhooks and Keeper state received by a replica are forwarded to the other replicas, and a new replica loads the events of the others on startup")
+	fs.IntVar(&o.peersPort, "peers-port", 8080, "Port on which the other replicas are listening")
+	fs.StringVar(&o.podIP, "pod-ip", os.Getenv("POD_IP"), "IP of the current replica, to exclude it from the peers")
+	fs.StringVar(&o.kubeConfigPath, "kubeconfig", kube.DefaultKubeConfigPath(), "Kubernetes Config Path. Default: KUBECONFIG env var value")
+	fs.StringVar(&o.listenAddr, "listen-addr", ":8080", "Address on which the server will listen for incoming connections")
+	fs.BoolVar(&o.printVersion, "version", false, "Print the version")
+	fs.StringVar(&o.configFile, "config", os.Getenv("CONFIG_FILE"), "Path to a YAML config file, with the same options as the flags - which take precedence over the config file")
+	fs.DurationVar(&o.configReloadInterval, "config-reload-interval", 10*time.Second, "Interval to check the config file for changes, and reload the options which can be changed at runtime. If zero, the config file is not reloaded")
 }
 
 func main() {
-	flag.Parse()
+	var err error
+	options, err = loadOptions(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
 	if options.printVersion {
 		fmt.Printf("Version %s - Revision %s - Date %s", version.Version, version.Revision, version.Date)
@@ -66,12 +118,29 @@ func main() {
 	defer cancelCtx()
 
 	logger := logrus.New()
-	logLevel, err := logrus.ParseLevel(options.logLevel)
-	if err != nil {
-		logger.WithField("logLevel", options.logLevel).Error("Failed to set log level")
-	} else {
-		logger.SetLevel(logLevel)
+	// already validated
+	logLevel, _ := logrus.ParseLevel(options.logLevel)
+	logger.SetLevel(logLevel)
+
+	// the backup and restore commands work on the store directly, without starting the server
+	var command, commandArg string
+	if len(options.args) > 0 {
+		command = options.args[0]
+	}
+	if len(options.args) > 1 {
+		commandArg = options.args[1]
 	}
+	switch command {
+	case "":
+	case "backup", "restore":
+		if err := runStoreCommand(command, commandArg, logger); err != nil {
+			logger.WithError(err).Fatalf("failed to %s the store", command)
+		}
+		return
+	default:
+		logger.WithField("command", command).Fatal("unknown command - valid commands are: backup [FILE] and restore [FILE]")
+	}
+
 	logger.WithField("logLevel", logLevel).WithField("version", version.Version).Info("Starting")
 
 	kConfig, err := kube.NewConfig(options.kubeConfigPath)
@@ -83,21 +152,56 @@ func main() {
 		logger.WithError(err).Fatal("failed to create a Lighthouse client")
 	}
 
+	shutdownTracing, err := tracing.Setup(ctx, options.tracingConfig)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to setup tracing")
+	}
+	if options.tracingConfig.OTLPEndpoint != "" {
+		logger.WithField("endpoint", options.tracingConfig.OTLPEndpoint).WithField("samplingRatio", options.tracingConfig.SamplingRatio).Info("Sending the traces to the OTLP collector")
+	}
+
 	store, err := webui.NewStore(options.storeConfig, logger)
 	if err != nil {
 		logger.WithError(err).Fatal("failed to create a new store")
 	}
+	store = webui.NewTracedStore(store)
 
-	logger.WithField("endpoint", options.keeperEndpoint).WithField("syncInterval", options.keeperSyncInterval).Info("Starting Keeper Syncer")
-	(&webui.KeeperSyncer{
-		KeeperEndpoint: options.keeperEndpoint,
-		SyncInterval:   options.keeperSyncInterval,
-		Store:          store,
-		Logger:         logger,
-	}).Start(ctx)
+	var keeperSyncers []*webui.KeeperSyncer
+	if options.keeperSyncInterval > 0 {
+		for _, endpoint := range options.keeperEndpoints.endpoints {
+			logger.WithField("keeper", endpoint.name).WithField("endpoint", endpoint.url).WithField("syncInterval", options.keeperSyncInterval).Info("Starting Keeper Syncer")
+			keeperSyncer := &webui.KeeperSyncer{
+				KeeperName:     endpoint.name,
+				KeeperEndpoint: endpoint.url,
+				SyncInterval:   options.keeperSyncInterval,
+				Store:          store,
+				Logger:         logger,
+			}
+			keeperSyncer.Start(ctx)
+			keeperSyncers = append(keeperSyncers, keeperSyncer)
+		}
+	}
+	if options.keeperIngestToken != "" {
+		logger.Info("Accepting Keeper state pushes on /keeper/pools and /keeper/history")
+	}
+
+	var replicator *webui.Replicator
+	if options.peersDNSName != "" {
+		logger.WithField("peersDNSName", options.peersDNSName).Info("Replicating the webhooks and Keeper state with the peers")
+		replicator = &webui.Replicator{
+			Resolver: &webui.DNSPeerResolver{
+				Host:   options.peersDNSName,
+				Port:   options.peersPort,
+				SelfIP: options.podIP,
+			},
+			Store:  store,
+			Logger: logger,
+		}
+	}
 
 	lighthouseHandler := &lighthouse.Handler{
 		SecretToken: options.lighthouseHMACKey,
+		Deliveries:  &lighthouse.Deliveries{},
 		Logger:      logger,
 	}
 	lighthouseHandler.RegisterWebhookHandler((&webui.EventHandler{
@@ -105,20 +209,85 @@ func main() {
 		Logger: logger,
 	}).HandleWebhook)
 
+	var (
+		pipelineInformer *webui.PipelineInformer
+		podLogs          webui.PodLogsStreamer
+	)
+	if options.tektonEnabled {
+		dynamicClient, err := dynamic.NewForConfig(kConfig)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to create a Kubernetes dynamic client")
+		}
+		podLogsClient, err := kube.NewPodLogsClient(kConfig)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to create a Kubernetes logs client")
+		}
+		podLogs = podLogsClient
+		logger.WithField("namespace", options.namespace).Info("Starting Tekton Pipeline Informer")
+		pipelineInformer = &webui.PipelineInformer{
+			Client:         dynamicClient,
+			Namespace:      options.namespace,
+			ResyncInterval: options.resyncInterval,
+			Logger:         logger,
+		}
+		pipelineInformer.Start(ctx)
+	}
+
+	var jobArchive *webui.JobArchive
+	if options.jobArchiveEnabled {
+		jobArchiveConfig := options.jobArchiveConfig
+		jobArchiveConfig.Path = filepath.Join(options.storeConfig.DataPath, "archive")
+		logger.WithField("path", jobArchiveConfig.Path).WithField("maxSizeMB", jobArchiveConfig.MaxSizeMB).WithField("maxAge", jobArchiveConfig.MaxAge).Info("Archiving the completed jobs")
+		jobArchive, err = webui.NewJobArchive(jobArchiveConfig, pipelineInformer, podLogs, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to create the job archive")
+		}
+	}
+
 	logger.WithField("namespace", options.namespace).WithField("resyncInterval", options.resyncInterval).Info("Starting Informer")
 	(&webui.JobInformer{
 		LHClient:       lhClient,
 		Namespace:      options.namespace,
 		ResyncInterval: options.resyncInterval,
 		Store:          store,
+		Archive:        jobArchive,
 		Logger:         logger,
 	}).Start(ctx)
 
+	// already validated
+	eventTraceURLTemplate, _ := functions.NewURLTemplate(options.eventTraceURLTemplate)
+	linkTemplates, _ := functions.NewLinkTemplates(options.linkTemplates.templates)
+	globalSearches := webui.NewGlobalSavedSearches(options.savedSearches.searches)
+
+	if options.configFile != "" && options.configReloadInterval > 0 {
+		logger.WithField("configFile", options.configFile).WithField("reloadInterval", options.configReloadInterval).Info("Watching the config file for changes")
+		go watchConfigFile(ctx, options, reloadableComponents{
+			logger:                logger,
+			store:                 store,
+			eventTraceURLTemplate: eventTraceURLTemplate,
+			linkTemplates:         linkTemplates,
+			keeperSyncers:         keeperSyncers,
+			jobArchive:            jobArchive,
+			globalSearches:        globalSearches,
+		})
+	}
+
 	handler, err := handlers.Router{
 		Store:                 store,
-		EventTraceURLTemplate: options.eventTraceURLTemplate,
+		EventTraceURLTemplate: eventTraceURLTemplate,
+		LinkTemplates:         linkTemplates,
+		PipelineInformer:      pipelineInformer,
+		PodLogs:               podLogs,
+		JobArchive:            jobArchive,
+		GlobalSearches:        globalSearches,
+		UserHeader:            options.userHeader,
+		KeeperIngestToken:     options.keeperIngestToken,
+		BackupToken:           options.backupToken,
+		BadgeRepositories:     splitList(options.badgeRepositories),
+		BadgeCacheMaxAge:      options.badgeCacheMaxAge,
 		LighthouseJobClient:   lhClient.LighthouseV1alpha1().LighthouseJobs(options.namespace),
 		LighthouseHandler:     lighthouseHandler,
+		Replicator:            replicator,
 		Logger:                logger,
 	}.Handler()
 	if err != nil {
@@ -141,11 +310,20 @@ func main() {
 			logger.Warn("Timed-out while waiting for the HTTP server to shutdown!")
 		}
 
+		if jobArchive != nil {
+			_ = jobArchive.Close()
+		}
+
 		logger.Info("Closing the store...")
 		if err := store.Close(); err != nil {
 			logger.WithError(err).Warn("failed to close the store")
 		}
 
+		logger.Info("Flushing the traces...")
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			logger.WithError(err).Warn("failed to flush the traces")
+		}
+
 		wg.Done()
 	}()
 	go func() {
@@ -155,6 +333,14 @@ func main() {
 			logger.WithError(err).Fatal("failed to start HTTP server")
 		}
 	}()
+	if replicator != nil {
+		// once the HTTP server is started, so that we don't miss the webhooks forwarded while loading the events
+		go func() {
+			if err := replicator.Bootstrap(ctx); err != nil {
+				logger.WithError(err).Error("failed to load the events from the peers")
+			}
+		}()
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
@@ -166,3 +352,134 @@ func main() {
 
 	logger.Info("This is the end, my friend")
 }
+
+// splitList splits a comma-separated list, ignoring the empty values
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// keeperEndpoints is a flag.Value for the (repeatable) Keeper endpoints, in the [name=]url format
+type keeperEndpoints struct {
+	endpoints []keeperEndpoint
+	// set is true once a value has been given on the command line, to replace the default value
+	set bool
+}
+
+type keeperEndpoint struct {
+	name string
+	url  string
+}
+
+func (e *keeperEndpoints) String() string {
+	if e == nil {
+		return ""
+	}
+	var values []string
+	for _, endpoint := range e.endpoints {
+		values = append(values, endpoint.name+"="+endpoint.url)
+	}
+	return strings.Join(values, ",")
+}
+
+// Set adds the given endpoints - the first call replaces the default endpoint, so an empty value disables polling Keeper
+func (e *keeperEndpoints) Set(value string) error {
+	if !e.set {
+		e.endpoints = nil
+		e.set = true
+	}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		endpoint := keeperEndpoint{name: webui.DefaultKeeperName, url: v}
+		if name, url, found := strings.Cut(v, "="); found && !strings.Contains(name, "/") {
+			endpoint = keeperEndpoint{name: name, url: url}
+		}
+		for _, existing := range e.endpoints {
+			if existing.name == endpoint.name {
+				return fmt.Errorf("duplicate Keeper name %q: use the name=url format to give a distinct name to each Keeper", endpoint.name)
+			}
+		}
+		e.endpoints = append(e.endpoints, endpoint)
+	}
+	return nil
+}
+
+// linkTemplates is a flag.Value for the (repeatable) link templates, in the name=template format
+// the values are not comma-separated, because the URLs can contain commas
+type linkTemplates struct {
+	templates []functions.NamedTemplate
+}
+
+func (t *linkTemplates) String() string {
+	if t == nil {
+		return ""
+	}
+	var values []string
+	for _, template := range t.templates {
+		values = append(values, template.Name+"="+template.Text)
+	}
+	return strings.Join(values, " ")
+}
+
+func (t *linkTemplates) Set(value string) error {
+	name, text, found := strings.Cut(value, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return fmt.Errorf("invalid link template %q: the format is name=template", value)
+	}
+	for _, existing := range t.templates {
+		if existing.Name == name {
+			return fmt.Errorf("duplicate link template %q", name)
+		}
+	}
+	t.templates = append(t.templates, functions.NamedTemplate{Name: name, Text: text})
+	return nil
+}
+
+// repeatable means that the values of a list or map in the config file are set one by one, instead of comma-separated
+func (t *linkTemplates) repeatable() {}
+
+// savedSearches is a flag.Value for the (repeatable) global saved searches, in the name=URL format
+// the values are not comma-separated, because the queries can contain commas
+type savedSearches struct {
+	searches []webui.SavedSearch
+}
+
+func (s *savedSearches) String() string {
+	if s == nil {
+		return ""
+	}
+	var values []string
+	for _, search := range s.searches {
+		values = append(values, search.Name+"="+search.URL())
+	}
+	return strings.Join(values, " ")
+}
+
+func (s *savedSearches) Set(value string) error {
+	name, rawURL, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("invalid saved search %q: the format is name=URL", value)
+	}
+	search, err := webui.ParseSavedSearch(name, rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid saved search %q: %w", value, err)
+	}
+	for _, existing := range s.searches {
+		if existing.Name == search.Name {
+			return fmt.Errorf("duplicate saved search %q", search.Name)
+		}
+	}
+	s.searches = append(s.searches, *search)
+	return nil
+}
+
+func (s *savedSearches) repeatable() {}
@@ -0,0 +1,41 @@
+package webui
+
+import (
+	"sort"
+)
+
+// CommitActivity is everything related to a commit: the webhook events which pushed it or updated a PR with it,
+// the jobs which ran on it, and the merge records which included it - or produced it
+type CommitActivity struct {
+	Owner      string
+	Repository string
+	SHA        string
+	Events     []Event
+	Jobs       []Job
+	// Merges are the merge records with the commit as their base, or as the head of a merged PR
+	Merges []MergeRecord
+	// ProducedBy are the merges which pushed the commit to their branch
+	ProducedBy []MergeRecord
+}
+
+// producedByMerges returns the merges whose push produced the commit: the keeper merges on top of the base SHA of its record,
+// so the push event of the merge has the base SHA as its before SHA, and the commit as its head
+func producedByMerges(sha string, pushEvents []Event, records []MergeRecord) []MergeRecord {
+	var merges []MergeRecord
+	for _, record := range records {
+		if record.Action != "MERGE" && record.Action != "MERGE_BATCH" {
+			continue
+		}
+		for _, event := range pushEvents {
+			if event.Kind == "push" && event.SHA == sha && event.BeforeSHA != "" &&
+				event.BeforeSHA == record.BaseSHA && event.Branch == record.Branch && !event.Time.Before(record.Time) {
+				merges = append(merges, record)
+				break
+			}
+		}
+	}
+	sort.SliceStable(merges, func(i, j int) bool {
+		return merges[i].Time.After(merges[j].Time)
+	})
+	return merges
+}
@@ -12,6 +12,7 @@ type Events struct {
 		Repositories map[string]int
 		Senders      map[string]int
 	}
+	Histogram Histogram
 }
 
 type Event struct {
@@ -24,7 +25,14 @@ type Event struct {
 	Details    string
 	URL        string
 	Sender     string
+	Labels     []string
 	Time       time.Time
+	// SHA is the commit of the event: the new head of the ref for a push, or the head of the pull request
+	SHA string
+	// BeforeSHA is the previous head of the ref, for a push
+	BeforeSHA string
+	// CommitSHAs are the pushed commits
+	CommitSHAs []string
 }
 
 func (e Event) PullRequestNumber() string {
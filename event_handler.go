@@ -1,6 +1,7 @@
 package webui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -11,11 +12,11 @@ import (
 )
 
 type EventHandler struct {
-	Store  *Store
+	Store  Store
 	Logger *logrus.Logger
 }
 
-func (h *EventHandler) HandleWebhook(webhook scm.Webhook) error {
+func (h *EventHandler) HandleWebhook(ctx context.Context, webhook scm.Webhook) error {
 	log := h.Logger.
 		WithField("repo", webhook.Repository().FullName).
 		WithField("kind", webhook.Kind())
@@ -37,7 +38,7 @@ func (h *EventHandler) HandleWebhook(webhook scm.Webhook) error {
 		event.Time = time.Now()
 	}
 
-	return h.Store.AddEvent(*event)
+	return StoreWithContext(ctx, h.Store).AddEvent(*event)
 }
 
 func convertWebhookToEvent(webhook scm.Webhook) *Event {
@@ -52,10 +53,15 @@ func convertWebhookToEvent(webhook scm.Webhook) *Event {
 		ref = strings.TrimPrefix(ref, "refs/heads/")
 		ref = strings.TrimPrefix(ref, "refs/tags/")
 		e := Event{
-			GUID:    event.GUID,
-			Details: ref,
-			Sender:  event.Sender.Login,
-			Branch:  ref,
+			GUID:      event.GUID,
+			Details:   ref,
+			Sender:    event.Sender.Login,
+			Branch:    ref,
+			SHA:       event.After,
+			BeforeSHA: event.Before,
+		}
+		for _, commit := range event.Commits {
+			e.CommitSHAs = append(e.CommitSHAs, commit.ID)
 		}
 		if event.Deleted {
 			e.Action = scm.ActionDelete.String()
@@ -78,6 +84,10 @@ func convertWebhookToEvent(webhook scm.Webhook) *Event {
 		default:
 			details = event.Action.String()
 		}
+		var labels []string
+		for _, label := range event.PullRequest.Labels {
+			labels = append(labels, label.Name)
+		}
 		return &Event{
 			GUID:    event.GUID,
 			Action:  event.Action.String(),
@@ -85,6 +95,8 @@ func convertWebhookToEvent(webhook scm.Webhook) *Event {
 			Sender:  event.Sender.Login,
 			Branch:  fmt.Sprintf("PR-%d", event.PullRequest.Number),
 			URL:     event.PullRequest.Link,
+			Labels:  labels,
+			SHA:     event.PullRequest.Sha,
 		}
 	case *scm.PullRequestCommentHook:
 		comment, _ := goutils.Abbreviate(event.Comment.Body, 50)
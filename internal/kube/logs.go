@@ -0,0 +1,55 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+// PodLogsClient retrieves the logs of the containers from the Kubernetes API.
+// It uses the raw HTTP API instead of the typed clientset, which is a lot of code just for the logs.
+type PodLogsClient struct {
+	host       string
+	httpClient *http.Client
+}
+
+func NewPodLogsClient(config *rest.Config) (*PodLogsClient, error) {
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an HTTP client for the Kubernetes API: %w", err)
+	}
+	return &PodLogsClient{
+		host:       strings.TrimSuffix(config.Host, "/"),
+		httpClient: httpClient,
+	}, nil
+}
+
+// StreamLogs returns the logs of a container - until the container stops if follow is true
+func (c *PodLogsClient) StreamLogs(ctx context.Context, namespace, pod, container string, follow bool) (io.ReadCloser, error) {
+	params := url.Values{}
+	params.Set("container", container)
+	if follow {
+		params.Set("follow", "true")
+	}
+	u := fmt.Sprintf("%s/api/v1/namespaces/%s/pods/%s/log?%s", c.host, url.PathEscape(namespace), url.PathEscape(pod), params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to get the logs of %s/%s: %s: %s", pod, container, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
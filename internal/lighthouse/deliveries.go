@@ -0,0 +1,135 @@
+package lighthouse
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// the results of the deliveries of the Lighthouse events
+const (
+	DeliveryResultSuccess          = "success"
+	DeliveryResultMissingSignature = "missing_signature"
+	DeliveryResultInvalidSignature = "invalid_signature"
+	DeliveryResultMalformedPayload = "malformed_payload"
+	DeliveryResultEmpty            = "empty"
+	DeliveryResultProcessingError  = "processing_error"
+)
+
+// defaultMaxFailures is the number of failed deliveries kept when no limit is set
+const defaultMaxFailures = 100
+
+// DeliveryFailure is an event sent by Lighthouse which could not be handled, with the metadata of its sender
+type DeliveryFailure struct {
+	Time   time.Time
+	Result string
+	Error  string
+	// PayloadType and WebhookKind are the headers set by Lighthouse
+	PayloadType string
+	WebhookKind string
+	// Repository is only known when the payload could be parsed
+	Repository   string
+	RemoteAddr   string
+	UserAgent    string
+	HasSignature bool
+}
+
+// DeliveryStats is a snapshot of the deliveries received since the start of the server
+type DeliveryStats struct {
+	Counts      map[string]int
+	Total       int
+	Failed      int
+	LastSuccess time.Time
+	LastFailure time.Time
+	// Failures are the most recent failures, most recent first
+	Failures []DeliveryFailure
+}
+
+// Deliveries records the results of the deliveries of the Lighthouse events - in memory, so each replica has its own
+type Deliveries struct {
+	// MaxFailures is the number of failures kept - defaults to 100
+	MaxFailures int
+
+	mutex       sync.RWMutex
+	counts      map[string]int
+	lastSuccess time.Time
+	lastFailure time.Time
+	failures    []DeliveryFailure
+}
+
+func (d *Deliveries) recordSuccess(t time.Time) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.increment(DeliveryResultSuccess)
+	d.lastSuccess = t
+}
+
+func (d *Deliveries) recordFailure(failure DeliveryFailure) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.increment(failure.Result)
+	d.lastFailure = failure.Time
+
+	maxFailures := d.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = defaultMaxFailures
+	}
+	d.failures = append(d.failures, failure)
+	if len(d.failures) > maxFailures {
+		d.failures = append([]DeliveryFailure(nil), d.failures[len(d.failures)-maxFailures:]...)
+	}
+}
+
+func (d *Deliveries) increment(result string) {
+	if d.counts == nil {
+		d.counts = map[string]int{}
+	}
+	d.counts[result]++
+}
+
+// Stats returns a snapshot of the deliveries
+func (d *Deliveries) Stats() DeliveryStats {
+	stats := DeliveryStats{
+		Counts: map[string]int{},
+	}
+	if d == nil {
+		return stats
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for result, count := range d.counts {
+		stats.Counts[result] = count
+		stats.Total += count
+		if result != DeliveryResultSuccess {
+			stats.Failed += count
+		}
+	}
+	stats.LastSuccess = d.lastSuccess
+	stats.LastFailure = d.lastFailure
+	stats.Failures = make([]DeliveryFailure, len(d.failures))
+	for i, failure := range d.failures {
+		stats.Failures[len(d.failures)-1-i] = failure
+	}
+	return stats
+}
+
+// Results returns all the results, sorted - so that the metrics are always exposed, even before the first failure
+func (s DeliveryStats) Results() []string {
+	results := []string{
+		DeliveryResultSuccess,
+		DeliveryResultMissingSignature,
+		DeliveryResultInvalidSignature,
+		DeliveryResultMalformedPayload,
+		DeliveryResultEmpty,
+		DeliveryResultProcessingError,
+	}
+	sort.Strings(results)
+	return results
+}
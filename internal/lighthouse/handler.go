@@ -1,69 +1,173 @@
 package lighthouse
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/jenkins-x/go-scm/scm"
 	lhv1alpha1 "github.com/jenkins-x/lighthouse/pkg/apis/lighthouse/v1alpha1"
 	lhutil "github.com/jenkins-x/lighthouse/pkg/util"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type WebhookHandlerFunc func(scm.Webhook) error
+const tracerName = "github.com/jenkins-x-plugins/lighthouse-webui-plugin/internal/lighthouse"
 
-type ActivityHandlerFunc func(*lhv1alpha1.ActivityRecord) error
+// the context carries the span of the webhook, which continues the trace started by Lighthouse
+type WebhookHandlerFunc func(context.Context, scm.Webhook) error
+
+type ActivityHandlerFunc func(context.Context, *lhv1alpha1.ActivityRecord) error
 
 type Handler struct {
 	SecretToken string
-	Logger      *logrus.Logger
+	// Deliveries records the results of the deliveries - optional
+	Deliveries *Deliveries
+	Logger     *logrus.Logger
 
 	webhookHandlers  []WebhookHandlerFunc
 	activityHandlers []ActivityHandlerFunc
+	// parseEvent defaults to lhutil.ParseExternalPluginEvent
+	parseEvent func(*http.Request, string) (scm.Webhook, *lhv1alpha1.ActivityRecord, error)
 }
 
-func (h *Handler) ServeHTTP(_ http.ResponseWriter, r *http.Request) {
+// ServeHTTP handles the events sent by Lighthouse. The status code tells Lighthouse if it should retry:
+// 4xx for the events which will never be accepted - invalid signature or payload - and 5xx if they failed to be processed
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		h.Logger.WithField("method", r.Method).Debug("Invalid http method so returning 200")
+		h.Logger.WithField("method", r.Method).Debug("Invalid http method")
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	ctx, span := otel.Tracer(tracerName).Start(r.Context(), "lighthouse.event",
+		trace.WithAttributes(
+			attribute.String("lighthouse.payload_type", r.Header.Get(lhutil.LighthousePayloadTypeHeader)),
+			attribute.String("lighthouse.webhook_kind", r.Header.Get(lhutil.LighthouseWebhookKindHeader)),
+		),
+	)
+	defer span.End()
+
 	log := h.Logger.
 		WithField("type", r.Header.Get(lhutil.LighthousePayloadTypeHeader)).
 		WithField("kind", r.Header.Get(lhutil.LighthouseWebhookKindHeader)).
 		WithField("UA", r.Header.Get("User-Agent"))
 
-	webhook, activity, err := lhutil.ParseExternalPluginEvent(r, h.SecretToken)
+	failure := DeliveryFailure{
+		Time:         time.Now().UTC(),
+		PayloadType:  r.Header.Get(lhutil.LighthousePayloadTypeHeader),
+		WebhookKind:  r.Header.Get(lhutil.LighthouseWebhookKindHeader),
+		RemoteAddr:   remoteAddr(r),
+		UserAgent:    r.Header.Get("User-Agent"),
+		HasSignature: r.Header.Get(lhutil.LighthouseSignatureHeader) != "",
+	}
+	fail := func(status int, result string, err error) {
+		failure.Result, failure.Error = result, err.Error()
+		h.Deliveries.recordFailure(failure)
+		span.SetAttributes(attribute.String("lighthouse.delivery_result", result))
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), status)
+	}
+
+	if h.SecretToken != "" && !failure.HasSignature {
+		log.Error("Lighthouse event has no signature")
+		fail(http.StatusUnauthorized, DeliveryResultMissingSignature, errors.New("missing signature"))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.
-			WithField("signature", r.Header.Get(lhutil.LighthouseSignatureHeader)).
-			WithError(err).Error("Failed to parse lighthouse event")
+		log.WithError(err).Error("Failed to read lighthouse event")
+		fail(http.StatusBadRequest, DeliveryResultMalformedPayload, err)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Lighthouse doesn't return a typed error for an invalid signature, so we validate it before parsing the event
+	if h.SecretToken != "" && !validSignature(body, r.Header.Get(lhutil.LighthouseSignatureHeader), h.SecretToken) {
+		log.WithField("signature", r.Header.Get(lhutil.LighthouseSignatureHeader)).Error("Lighthouse event has an invalid signature")
+		fail(http.StatusUnauthorized, DeliveryResultInvalidSignature, errors.New("invalid signature"))
+		return
+	}
+
+	parseEvent := h.parseEvent
+	if parseEvent == nil {
+		parseEvent = lhutil.ParseExternalPluginEvent
+	}
+	webhook, activity, err := parseEvent(r, h.SecretToken)
+	if err != nil {
+		log.WithError(err).Error("Failed to parse lighthouse event")
+		fail(http.StatusBadRequest, DeliveryResultMalformedPayload, err)
 		return
 	}
 	if webhook == nil && activity == nil {
 		log.Error("Lighthouse event was empty: no webhook or activity")
+		fail(http.StatusBadRequest, DeliveryResultEmpty, errors.New("no webhook or activity"))
 		return
 	}
 
+	var processingErr error
 	if webhook != nil {
 		log := log.WithField("repo", webhook.Repository().FullName)
+		failure.Repository = webhook.Repository().FullName
+		span.SetAttributes(attribute.String("repository", webhook.Repository().FullName))
 		log.Trace("Handling webhook")
 		for _, handler := range h.webhookHandlers {
-			err = handler(webhook)
+			err = handler(ctx, webhook)
 			if err != nil {
 				log.WithError(err).Error("Failed to process webhook")
+				span.RecordError(err)
+				processingErr = err
 			}
 		}
 	}
 	if activity != nil {
 		log := log.WithField("activity", activity.Name)
+		if failure.Repository == "" {
+			failure.Repository = activity.Owner + "/" + activity.Repo
+		}
 		log.Trace("Handling activity")
 		for _, handler := range h.activityHandlers {
-			err = handler(activity)
+			err = handler(ctx, activity)
 			if err != nil {
 				log.WithError(err).Error("Failed to process activity")
+				span.RecordError(err)
+				processingErr = err
 			}
 		}
 	}
+	if processingErr != nil {
+		// so that Lighthouse retries: the handlers are idempotent
+		fail(http.StatusInternalServerError, DeliveryResultProcessingError, processingErr)
+		return
+	}
+
+	h.Deliveries.recordSuccess(failure.Time)
+	span.SetAttributes(attribute.String("lighthouse.delivery_result", DeliveryResultSuccess))
+}
+
+// validSignature checks the HMAC signature of the payload, computed by Lighthouse with the secret token
+func validSignature(payload []byte, signature, secretToken string) bool {
+	expected := lhutil.CreateHMACSignature(payload, []byte(secretToken))
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// remoteAddr returns the address of the client - or of the first proxy, such as the ingress controller
+func remoteAddr(r *http.Request) string {
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		addr, _, _ := strings.Cut(forwardedFor, ",")
+		return strings.TrimSpace(addr)
+	}
+	return r.RemoteAddr
 }
 
 func (h *Handler) RegisterWebhookHandler(handler WebhookHandlerFunc) {
@@ -0,0 +1,227 @@
+package lighthouse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	lhv1alpha1 "github.com/jenkins-x/lighthouse/pkg/apis/lighthouse/v1alpha1"
+	lhutil "github.com/jenkins-x/lighthouse/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+const testSecretToken = "secret"
+
+func newTestHandler(webhook scm.Webhook, activity *lhv1alpha1.ActivityRecord, parseErr error) *Handler {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return &Handler{
+		SecretToken: testSecretToken,
+		Deliveries:  &Deliveries{},
+		Logger:      logger,
+		parseEvent: func(r *http.Request, secretToken string) (scm.Webhook, *lhv1alpha1.ActivityRecord, error) {
+			// the body must still be readable by Lighthouse
+			if _, err := io.ReadAll(r.Body); err != nil {
+				return nil, nil, err
+			}
+			return webhook, activity, parseErr
+		},
+	}
+}
+
+func newTestRequest(method, body, signature string) *http.Request {
+	req := httptest.NewRequest(method, "/lighthouse/events", strings.NewReader(body))
+	req.Header.Set(lhutil.LighthousePayloadTypeHeader, "webhook")
+	req.Header.Set(lhutil.LighthouseWebhookKindHeader, "push")
+	if signature != "" {
+		req.Header.Set(lhutil.LighthouseSignatureHeader, signature)
+	}
+	return req
+}
+
+func TestHandlerStatusCodes(t *testing.T) {
+	const body = `{"ref":"refs/heads/main"}`
+	var (
+		validSignature = lhutil.CreateHMACSignature([]byte(body), []byte(testSecretToken))
+		pushHook       = &scm.PushHook{Repo: scm.Repository{FullName: "owner/repo"}}
+		activity       = &lhv1alpha1.ActivityRecord{Name: "activity", Owner: "owner", Repo: "repo"}
+	)
+
+	tests := []struct {
+		name            string
+		method          string
+		signature       string
+		webhook         scm.Webhook
+		activity        *lhv1alpha1.ActivityRecord
+		parseErr        error
+		handlerErr      error
+		expectedStatus  int
+		expectedResult  string
+		expectedHandled bool
+	}{
+		{
+			name:           "GET request",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "missing signature",
+			method:         http.MethodPost,
+			webhook:        pushHook,
+			expectedStatus: http.StatusUnauthorized,
+			expectedResult: DeliveryResultMissingSignature,
+		},
+		{
+			name:           "invalid signature",
+			method:         http.MethodPost,
+			signature:      lhutil.CreateHMACSignature([]byte(body), []byte("another secret")),
+			webhook:        pushHook,
+			expectedStatus: http.StatusUnauthorized,
+			expectedResult: DeliveryResultInvalidSignature,
+		},
+		{
+			name:           "malformed payload",
+			method:         http.MethodPost,
+			signature:      validSignature,
+			parseErr:       errors.New("failed to unmarshal the push hook"),
+			expectedStatus: http.StatusBadRequest,
+			expectedResult: DeliveryResultMalformedPayload,
+		},
+		{
+			name:           "empty event",
+			method:         http.MethodPost,
+			signature:      validSignature,
+			expectedStatus: http.StatusBadRequest,
+			expectedResult: DeliveryResultEmpty,
+		},
+		{
+			name:            "processing error",
+			method:          http.MethodPost,
+			signature:       validSignature,
+			webhook:         pushHook,
+			handlerErr:      errors.New("store unavailable"),
+			expectedStatus:  http.StatusInternalServerError,
+			expectedResult:  DeliveryResultProcessingError,
+			expectedHandled: true,
+		},
+		{
+			name:            "webhook",
+			method:          http.MethodPost,
+			signature:       validSignature,
+			webhook:         pushHook,
+			expectedStatus:  http.StatusOK,
+			expectedResult:  DeliveryResultSuccess,
+			expectedHandled: true,
+		},
+		{
+			name:            "activity",
+			method:          http.MethodPost,
+			signature:       validSignature,
+			activity:        activity,
+			expectedStatus:  http.StatusOK,
+			expectedResult:  DeliveryResultSuccess,
+			expectedHandled: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newTestHandler(test.webhook, test.activity, test.parseErr)
+			var handled bool
+			h.RegisterWebhookHandler(func(ctx context.Context, webhook scm.Webhook) error {
+				handled = true
+				return test.handlerErr
+			})
+			h.RegisterActivityHandler(func(ctx context.Context, activity *lhv1alpha1.ActivityRecord) error {
+				handled = true
+				return test.handlerErr
+			})
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newTestRequest(test.method, body, test.signature))
+
+			if rec.Code != test.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", test.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if handled != test.expectedHandled {
+				t.Errorf("expected handled=%v, got %v", test.expectedHandled, handled)
+			}
+
+			stats := h.Deliveries.Stats()
+			if test.expectedResult == "" {
+				if stats.Total != 0 {
+					t.Errorf("expected no delivery to be recorded, got %v", stats.Counts)
+				}
+				return
+			}
+			if stats.Total != 1 || stats.Counts[test.expectedResult] != 1 {
+				t.Errorf("expected 1 delivery with result %s, got %v", test.expectedResult, stats.Counts)
+			}
+			if test.expectedResult == DeliveryResultSuccess {
+				if stats.Failed != 0 || len(stats.Failures) != 0 || stats.LastSuccess.IsZero() {
+					t.Errorf("expected a successful delivery, got %+v", stats)
+				}
+				return
+			}
+			if stats.Failed != 1 || len(stats.Failures) != 1 || stats.LastFailure.IsZero() {
+				t.Fatalf("expected 1 failed delivery, got %+v", stats)
+			}
+			if failure := stats.Failures[0]; failure.Result != test.expectedResult || failure.PayloadType != "webhook" || failure.WebhookKind != "push" {
+				t.Errorf("unexpected failure: %+v", failure)
+			}
+		})
+	}
+}
+
+func TestHandlerWithoutSecretToken(t *testing.T) {
+	h := newTestHandler(&scm.PushHook{Repo: scm.Repository{FullName: "owner/repo"}}, nil, nil)
+	h.SecretToken = ""
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newTestRequest(http.MethodPost, "{}", ""))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d without secret token, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestHandlerDeliveriesCounts(t *testing.T) {
+	const body = "{}"
+	h := newTestHandler(&scm.PushHook{Repo: scm.Repository{FullName: "owner/repo"}}, nil, nil)
+	h.Deliveries.MaxFailures = 2
+
+	validSignature := lhutil.CreateHMACSignature([]byte(body), []byte(testSecretToken))
+	for _, signature := range []string{validSignature, "", "invalid", validSignature, "invalid", validSignature} {
+		h.ServeHTTP(httptest.NewRecorder(), newTestRequest(http.MethodPost, body, signature))
+	}
+
+	stats := h.Deliveries.Stats()
+	if stats.Total != 6 || stats.Failed != 3 {
+		t.Errorf("expected 6 deliveries with 3 failures, got %d with %d failures", stats.Total, stats.Failed)
+	}
+	expectedCounts := map[string]int{
+		DeliveryResultSuccess:          3,
+		DeliveryResultMissingSignature: 1,
+		DeliveryResultInvalidSignature: 2,
+	}
+	for result, count := range expectedCounts {
+		if stats.Counts[result] != count {
+			t.Errorf("expected %d deliveries with result %s, got %d", count, result, stats.Counts[result])
+		}
+	}
+	// only the most recent failures are kept, most recent first
+	if len(stats.Failures) != 2 {
+		t.Fatalf("expected the 2 most recent failures, got %d", len(stats.Failures))
+	}
+	for i, expected := range []string{DeliveryResultInvalidSignature, DeliveryResultInvalidSignature} {
+		if stats.Failures[i].Result != expected {
+			t.Errorf("expected failure %d to be %s, got %s", i, expected, stats.Failures[i].Result)
+		}
+	}
+	if stats.Failures[0].Repository != "" || !stats.Failures[0].HasSignature {
+		t.Errorf("unexpected failure: %+v", stats.Failures[0])
+	}
+}
@@ -1,11 +1,13 @@
 package webui
 
 import (
+	"io"
 	"regexp"
 	"strings"
 	"time"
 
 	lhv1alpha1 "github.com/jenkins-x/lighthouse/pkg/apis/lighthouse/v1alpha1"
+	"k8s.io/cli-runtime/pkg/printers"
 )
 
 type Jobs struct {
@@ -16,6 +18,7 @@ type Jobs struct {
 		Repositories map[string]int
 		Authors      map[string]int
 	}
+	Histogram Histogram
 }
 
 type Job struct {
@@ -35,6 +38,9 @@ type Job struct {
 	Start       time.Time
 	End         time.Time
 	Duration    time.Duration
+	// BaseSHA is the commit of the base branch the job ran on, and PullSHAs the heads of the pull requests merged into it - if any
+	BaseSHA  string
+	PullSHAs []string
 }
 
 func (j Job) PullRequestNumber() string {
@@ -65,15 +71,41 @@ func JobFromLighthouseJob(lhjob *lhv1alpha1.LighthouseJob) Job {
 		j.Duration = j.End.Sub(j.Start)
 	}
 	if lhjob.Spec.Refs != nil {
+		j.BaseSHA = lhjob.Spec.Refs.BaseSHA
 		for _, pr := range lhjob.Spec.Refs.Pulls {
 			if pr.Author != "" && j.Author == "" {
 				j.Author = pr.Author
 			}
+			if pr.SHA != "" {
+				j.PullSHAs = append(j.PullSHAs, pr.SHA)
+			}
 		}
 	}
 	return j
 }
 
+// isCompletedLighthouseJob returns true once the job is done - whatever its result
+func isCompletedLighthouseJob(lhjob *lhv1alpha1.LighthouseJob) bool {
+	switch lhjob.Status.State {
+	case lhv1alpha1.SuccessState, lhv1alpha1.FailureState, lhv1alpha1.AbortedState, lhv1alpha1.ErrorState:
+		return true
+	default:
+		return false
+	}
+}
+
+// WriteLighthouseJobYAML writes the job in YAML - with its apiVersion and kind, which are not set on the objects returned by the clients
+func WriteLighthouseJobYAML(lhjob *lhv1alpha1.LighthouseJob, w io.Writer) error {
+	job := *lhjob
+	if job.APIVersion == "" {
+		job.APIVersion = "lighthouse.jenkins.io/v1alpha1"
+	}
+	if job.Kind == "" {
+		job.Kind = "LighthouseJob"
+	}
+	return new(printers.YAMLPrinter).PrintObj(&job, w)
+}
+
 var traceCtxRegExp = regexp.MustCompile("^(?P<version>[0-9a-f]{2})-(?P<traceID>[a-f0-9]{32})-(?P<spanID>[a-f0-9]{16})-(?P<traceFlags>[a-f0-9]{2})(?:-.*)?$")
 
 func extractTraceIDFromLighthouseJob(lhjob *lhv1alpha1.LighthouseJob) string {
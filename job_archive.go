@@ -0,0 +1,507 @@
+package webui
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	lhv1alpha1 "github.com/jenkins-x/lighthouse/pkg/apis/lighthouse/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	jobArchiveBlobsDir = "blobs"
+	jobArchiveJobsDir  = "jobs"
+	// maxArchivedLogsSize is the max size of the archived logs of a single step - the rest is truncated
+	maxArchivedLogsSize = 10 * 1024 * 1024
+	// pipelinesSyncTimeout is how long we wait for the pipelines informer, before archiving the jobs found on startup
+	pipelinesSyncTimeout = 1 * time.Minute
+)
+
+var digestRegExp = regexp.MustCompile("^[a-f0-9]{64}$")
+
+type JobArchiveConfig struct {
+	// Path is the directory of the archive - usually in the data path of the store
+	Path string
+	// MaxSizeMB is the max size of the archive, in MB - the oldest jobs are removed first. If zero, there is no limit
+	MaxSizeMB int64
+	// MaxAge is the max age of the archived jobs. If zero, there is no limit
+	MaxAge time.Duration
+}
+
+// ArchivedJob is the manifest of an archived job, which references its YAML and logs by their digest
+type ArchivedJob struct {
+	Job          Job
+	ArchivedAt   time.Time
+	YAMLDigest   string
+	PipelineRuns []PipelineRun
+	Logs         []ArchivedLogs
+}
+
+// ArchivedLogs references the archived logs of a step
+type ArchivedLogs struct {
+	TaskRun string
+	Step    string
+	Digest  string
+	// Truncated is true if the logs were larger than the max size of the archived logs
+	Truncated bool
+}
+
+// LogsDigest returns the digest of the archived logs of the given step, or an empty string
+func (a ArchivedJob) LogsDigest(taskRun, step string) string {
+	for _, logs := range a.Logs {
+		if logs.TaskRun == taskRun && logs.Step == step {
+			return logs.Digest
+		}
+	}
+	return ""
+}
+
+// JobArchive keeps a snapshot of the completed jobs - their YAML and the logs of their steps - so that they are still available
+// once the LighthouseJobs and their pods have been garbage-collected from the cluster.
+// The YAML and logs are stored as gzipped content-addressed blobs - in blobs/DIGEST_PREFIX/DIGEST - so that identical logs are only stored once.
+// And the manifest of each job is stored in jobs/JOB_NAME.json
+type JobArchive struct {
+	path      string
+	pipelines *PipelineInformer
+	podLogs   PodLogsStreamer
+	logger    *logrus.Logger
+	limits    archiveLimits
+	// mutex serializes the writes of the blobs and manifests with the garbage collector - but not the streaming of the logs.
+	// pending are the blobs written for the jobs being archived, by digest - the garbage collector must keep them,
+	// even if they are not referenced by any manifest yet
+	mutex      sync.Mutex
+	pending    map[string]int
+	gcStopChan chan struct{}
+}
+
+// archiveLimits are the retention limits of the archive, which can be changed at runtime
+type archiveLimits struct {
+	mutex     sync.RWMutex
+	maxSizeMB int64
+	maxAge    time.Duration
+}
+
+func (l *archiveLimits) set(maxSizeMB int64, maxAge time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.maxSizeMB = maxSizeMB
+	l.maxAge = maxAge
+}
+
+func (l *archiveLimits) get() (maxSizeMB int64, maxAge time.Duration) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.maxSizeMB, l.maxAge
+}
+
+// NewJobArchive creates the archive directory if needed, and starts its garbage collector.
+// The pipelines and podLogs are optional: without them, only the YAML of the jobs is archived
+func NewJobArchive(cfg JobArchiveConfig, pipelines *PipelineInformer, podLogs PodLogsStreamer, logger *logrus.Logger) (*JobArchive, error) {
+	if logger == nil {
+		logger = logrus.New()
+	}
+	for _, dir := range []string{jobArchiveBlobsDir, jobArchiveJobsDir} {
+		if err := os.MkdirAll(filepath.Join(cfg.Path, dir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create the job archive directory: %w", err)
+		}
+	}
+
+	archive := &JobArchive{
+		path:       cfg.Path,
+		pipelines:  pipelines,
+		podLogs:    podLogs,
+		logger:     logger,
+		pending:    map[string]int{},
+		gcStopChan: make(chan struct{}),
+	}
+	archive.limits.set(cfg.MaxSizeMB, cfg.MaxAge)
+
+	ticker := time.NewTicker(10 * time.Minute)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := archive.CollectGarbage(); err != nil {
+					logger.WithError(err).Warning("failed to collect the garbage of the job archive")
+				}
+			case <-archive.gcStopChan:
+				ticker.Stop()
+				logger.Info("Job Archive GarbageCollector exiting...")
+				return
+			}
+		}
+	}()
+
+	return archive, nil
+}
+
+func (a *JobArchive) Close() error {
+	close(a.gcStopChan)
+	return nil
+}
+
+// SetRetentionLimits changes the retention limits - from the next collection
+func (a *JobArchive) SetRetentionLimits(maxSizeMB int64, maxAge time.Duration) {
+	a.limits.set(maxSizeMB, maxAge)
+}
+
+// Archive stores the YAML of the given job, and the logs of the steps of its pipelines - which must still exist in the cluster
+func (a *JobArchive) Archive(ctx context.Context, lhjob *lhv1alpha1.LighthouseJob) error {
+	if !isValidJobName(lhjob.Name) {
+		return fmt.Errorf("invalid job name %q", lhjob.Name)
+	}
+
+	archived := ArchivedJob{
+		Job:        JobFromLighthouseJob(lhjob),
+		ArchivedAt: time.Now().UTC(),
+	}
+	// once the manifest is written - or if we failed - the blobs are protected by the manifest only
+	defer func() { a.releaseBlobs(archived.digests()) }()
+
+	var jobYAML bytes.Buffer
+	if err := WriteLighthouseJobYAML(lhjob, &jobYAML); err != nil {
+		return fmt.Errorf("failed to write the YAML of job %s: %w", lhjob.Name, err)
+	}
+	digest, _, err := a.writeBlob(&jobYAML, int64(jobYAML.Len()))
+	if err != nil {
+		return err
+	}
+	archived.YAMLDigest = digest
+
+	archived.PipelineRuns, err = a.pipelines.PipelineRunsForJob(lhjob.Name)
+	if err != nil {
+		return err
+	}
+	if a.podLogs != nil {
+		for _, pipelineRun := range archived.PipelineRuns {
+			for _, taskRun := range pipelineRun.TaskRuns {
+				if taskRun.PodName == "" {
+					continue
+				}
+				for _, step := range taskRun.Steps {
+					if step.State == PipelineStatePending {
+						continue
+					}
+					logs, err := a.archiveLogs(ctx, pipelineRun.Namespace, taskRun, step)
+					if err != nil {
+						a.logger.WithError(err).WithField("job", lhjob.Name).WithField("pod", taskRun.PodName).WithField("container", step.Container).Warning("failed to archive the logs")
+						continue
+					}
+					archived.Logs = append(archived.Logs, *logs)
+				}
+			}
+		}
+	}
+
+	return a.writeManifest(archived)
+}
+
+// waitForPipelines waits until the pipelines informer is synced - if any - and returns false on timeout
+func (a *JobArchive) waitForPipelines(timeout time.Duration) bool {
+	if a.pipelines == nil {
+		return true
+	}
+	deadline := time.Now().Add(timeout)
+	for !a.pipelines.HasSynced() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Second)
+	}
+	return true
+}
+
+// missingPipelines returns true if the job was archived without the pipelines - and logs - it now has:
+// because it was archived before the pipelines informer was synced
+func (a *JobArchive) missingPipelines(archived *ArchivedJob) bool {
+	if a.pipelines == nil || len(archived.PipelineRuns) > 0 || len(archived.Logs) > 0 {
+		return false
+	}
+	pipelineRuns, err := a.pipelines.PipelineRunsForJob(archived.Job.Name)
+	return err == nil && len(pipelineRuns) > 0
+}
+
+// releaseBlobs stops protecting the given pending blobs from the garbage collector
+func (a *JobArchive) releaseBlobs(digests []string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, digest := range digests {
+		if a.pending[digest] > 1 {
+			a.pending[digest]--
+		} else {
+			delete(a.pending, digest)
+		}
+	}
+}
+
+func (a *JobArchive) archiveLogs(ctx context.Context, namespace string, taskRun TaskRun, step Step) (*ArchivedLogs, error) {
+	logs, err := a.podLogs.StreamLogs(ctx, namespace, taskRun.PodName, step.Container, false)
+	if err != nil {
+		return nil, err
+	}
+	defer logs.Close()
+
+	digest, truncated, err := a.writeBlob(logs, maxArchivedLogsSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ArchivedLogs{
+		TaskRun:   taskRun.Name,
+		Step:      step.Name,
+		Digest:    digest,
+		Truncated: truncated,
+	}, nil
+}
+
+// Get returns the manifest of the given archived job, or nil if it has not been archived
+func (a *JobArchive) Get(jobName string) (*ArchivedJob, error) {
+	if a == nil || !isValidJobName(jobName) {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(a.manifestPath(jobName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	archived := new(ArchivedJob)
+	if err = json.Unmarshal(data, archived); err != nil {
+		return nil, fmt.Errorf("invalid manifest of the archived job %s: %w", jobName, err)
+	}
+	return archived, nil
+}
+
+// OpenBlob returns the (uncompressed) content of the blob with the given digest
+func (a *JobArchive) OpenBlob(digest string) (io.ReadCloser, error) {
+	if !digestRegExp.MatchString(digest) {
+		return nil, fmt.Errorf("invalid digest %q", digest)
+	}
+	f, err := os.Open(a.blobPath(digest))
+	if err != nil {
+		return nil, err
+	}
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gzipReader, f}, nil
+}
+
+// writeBlob stores the content - truncated to maxSize - if it's not already stored, and returns its digest.
+// The content is read into a temporary file first, so that the garbage collector is not blocked while streaming the logs.
+// The blob is then pending until released with releaseBlobs.
+func (a *JobArchive) writeBlob(r io.Reader, maxSize int64) (digest string, truncated bool, err error) {
+	tmpFile, err := os.CreateTemp(filepath.Join(a.path, jobArchiveBlobsDir), ".tmp-")
+	if err != nil {
+		return "", false, err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	var (
+		hash       = sha256.New()
+		gzipWriter = gzip.NewWriter(tmpFile)
+	)
+	n, err := io.CopyN(io.MultiWriter(hash, gzipWriter), r, maxSize)
+	if err != nil && err != io.EOF {
+		return "", false, err
+	}
+	if n == maxSize {
+		if extra, _ := r.Read(make([]byte, 1)); extra > 0 {
+			truncated = true
+		}
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return "", false, err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return "", false, err
+	}
+
+	digest = hex.EncodeToString(hash.Sum(nil))
+	blobPath := a.blobPath(digest)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if _, err = os.Stat(blobPath); err == nil {
+		a.pending[digest]++
+		return digest, truncated, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return "", false, err
+	}
+	if err = os.Rename(tmpFile.Name(), blobPath); err != nil {
+		return "", false, err
+	}
+	a.pending[digest]++
+	return digest, truncated, nil
+}
+
+func (a *JobArchive) writeManifest(archived ArchivedJob) error {
+	data, err := json.Marshal(archived)
+	if err != nil {
+		return err
+	}
+	manifestPath := a.manifestPath(archived.Job.Name)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err = os.WriteFile(manifestPath+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(manifestPath+".tmp", manifestPath)
+}
+
+// CollectGarbage removes the jobs older than the max age, and then the oldest jobs until the archive is smaller than its max size.
+// And finally the blobs which are not referenced anymore
+func (a *JobArchive) CollectGarbage() error {
+	maxSizeMB, maxAge := a.limits.get()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	manifests, err := a.loadManifests()
+	if err != nil {
+		return err
+	}
+	blobSizes, err := a.blobSizes()
+	if err != nil {
+		return err
+	}
+
+	var (
+		kept       []ArchivedJob
+		references = map[string]int{}
+		totalSize  int64
+	)
+	for _, archived := range manifests {
+		if maxAge > 0 && archived.ArchivedAt.Before(time.Now().Add(-maxAge)) {
+			if err = os.Remove(a.manifestPath(archived.Job.Name)); err != nil {
+				return err
+			}
+			continue
+		}
+		kept = append(kept, archived)
+		for _, digest := range archived.digests() {
+			if references[digest] == 0 {
+				totalSize += blobSizes[digest]
+			}
+			references[digest]++
+		}
+	}
+
+	for maxSizeMB > 0 && totalSize > maxSizeMB*1024*1024 && len(kept) > 0 {
+		oldest := kept[0]
+		kept = kept[1:]
+		if err = os.Remove(a.manifestPath(oldest.Job.Name)); err != nil {
+			return err
+		}
+		for _, digest := range oldest.digests() {
+			references[digest]--
+			if references[digest] == 0 {
+				totalSize -= blobSizes[digest]
+			}
+		}
+	}
+
+	for digest := range blobSizes {
+		if references[digest] > 0 || a.pending[digest] > 0 {
+			continue
+		}
+		if err = os.Remove(a.blobPath(digest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		// and its directory, if it's now empty
+		_ = os.Remove(filepath.Dir(a.blobPath(digest)))
+	}
+	return nil
+}
+
+// loadManifests returns all the manifests, the oldest first
+func (a *JobArchive) loadManifests() ([]ArchivedJob, error) {
+	entries, err := os.ReadDir(filepath.Join(a.path, jobArchiveJobsDir))
+	if err != nil {
+		return nil, err
+	}
+	manifests := make([]ArchivedJob, 0, len(entries))
+	for _, entry := range entries {
+		jobName := strings.TrimSuffix(entry.Name(), ".json")
+		if entry.IsDir() || jobName == entry.Name() {
+			continue
+		}
+		archived, err := a.Get(jobName)
+		if err != nil {
+			a.logger.WithError(err).WithField("job", jobName).Warning("ignoring invalid archived job")
+			continue
+		}
+		if archived != nil {
+			manifests = append(manifests, *archived)
+		}
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].ArchivedAt.Before(manifests[j].ArchivedAt)
+	})
+	return manifests, nil
+}
+
+// blobSizes returns the size on disk of all the blobs, indexed by their digest
+func (a *JobArchive) blobSizes() (map[string]int64, error) {
+	sizes := map[string]int64{}
+	err := filepath.WalkDir(filepath.Join(a.path, jobArchiveBlobsDir), func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !digestRegExp.MatchString(entry.Name()) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		sizes[entry.Name()] = info.Size()
+		return nil
+	})
+	return sizes, err
+}
+
+func (a ArchivedJob) digests() []string {
+	digests := []string{a.YAMLDigest}
+	for _, logs := range a.Logs {
+		digests = append(digests, logs.Digest)
+	}
+	return digests
+}
+
+func (a *JobArchive) manifestPath(jobName string) string {
+	return filepath.Join(a.path, jobArchiveJobsDir, jobName+".json")
+}
+
+func (a *JobArchive) blobPath(digest string) string {
+	return filepath.Join(a.path, jobArchiveBlobsDir, digest[:2], digest)
+}
+
+// isValidJobName ensures that the name of a job can be safely used as a file name
+func isValidJobName(name string) bool {
+	return len(validation.IsDNS1123Subdomain(name)) == 0
+}
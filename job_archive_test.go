@@ -0,0 +1,205 @@
+package webui
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	lhv1alpha1 "github.com/jenkins-x/lighthouse/pkg/apis/lighthouse/v1alpha1"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// blockingPodLogsStreamer returns logs which can only be read once unblocked
+type blockingPodLogsStreamer struct {
+	logs      string
+	streaming chan struct{}
+	unblock   chan struct{}
+}
+
+func (s *blockingPodLogsStreamer) StreamLogs(ctx context.Context, namespace, pod, container string, follow bool) (io.ReadCloser, error) {
+	close(s.streaming)
+	<-s.unblock
+	return io.NopCloser(strings.NewReader(s.logs)), nil
+}
+
+func newTestPipelineInformer(t *testing.T, jobName string) *PipelineInformer {
+	t.Helper()
+	status := map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Succeeded", "status": "True"},
+		},
+		"startTime":      "2023-06-01T10:00:00Z",
+		"completionTime": "2023-06-01T10:05:00Z",
+		"podName":        "tr-1-pod",
+		"steps": []interface{}{
+			map[string]interface{}{
+				"name": "build",
+				"terminated": map[string]interface{}{
+					"exitCode":   int64(0),
+					"startedAt":  "2023-06-01T10:00:00Z",
+					"finishedAt": "2023-06-01T10:05:00Z",
+				},
+			},
+		},
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			PipelineRunsResource: "PipelineRunList",
+			TaskRunsResource:     "TaskRunList",
+		},
+		newTektonRun("PipelineRun", "pr-1", nil, jobName, status),
+		newTektonRun("TaskRun", "tr-1", map[string]string{tektonPipelineRunLabel: "pr-1"}, "", status),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	informer := &PipelineInformer{Client: client, Namespace: "jx"}
+	informer.Start(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for !informer.HasSynced() {
+		if time.Now().After(deadline) {
+			t.Fatal("the informers caches were not synced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return informer
+}
+
+func TestJobArchiveDoesNotBlockTheGarbageCollector(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	streamer := &blockingPodLogsStreamer{
+		logs:      "building...\ndone\n",
+		streaming: make(chan struct{}),
+		unblock:   make(chan struct{}),
+	}
+	archive, err := NewJobArchive(JobArchiveConfig{Path: t.TempDir()}, newTestPipelineInformer(t, "job-1"), streamer, logger)
+	if err != nil {
+		t.Fatalf("failed to create the archive: %v", err)
+	}
+	defer archive.Close()
+
+	lhjob := &lhv1alpha1.LighthouseJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "job-1", Namespace: "jx"},
+		Status:     lhv1alpha1.LighthouseJobStatus{State: lhv1alpha1.SuccessState},
+	}
+	archived := make(chan error, 1)
+	go func() {
+		archived <- archive.Archive(context.Background(), lhjob)
+	}()
+
+	select {
+	case <-streamer.streaming:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the logs were not streamed")
+	}
+	collected := make(chan error, 1)
+	go func() {
+		collected <- archive.CollectGarbage()
+	}()
+	select {
+	case err = <-collected:
+		if err != nil {
+			t.Fatalf("failed to collect the garbage: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the garbage collector was blocked by the streaming of the logs")
+	}
+
+	close(streamer.unblock)
+	if err = <-archived; err != nil {
+		t.Fatalf("failed to archive the job: %v", err)
+	}
+
+	job, err := archive.Get("job-1")
+	if err != nil || job == nil {
+		t.Fatalf("expected the job to be archived, got %v (%v)", job, err)
+	}
+	// the YAML blob was written before the garbage collection, and must have been kept
+	yaml, err := archive.OpenBlob(job.YAMLDigest)
+	if err != nil {
+		t.Fatalf("expected the YAML blob to be kept: %v", err)
+	}
+	yaml.Close()
+
+	logs, err := archive.OpenBlob(job.LogsDigest("tr-1", "build"))
+	if err != nil {
+		t.Fatalf("failed to open the archived logs: %v", err)
+	}
+	defer logs.Close()
+	content, err := io.ReadAll(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != streamer.logs {
+		t.Errorf("expected the logs %q, got %q", streamer.logs, content)
+	}
+
+	// once archived, the blobs are protected by the manifest only
+	if len(archive.pending) != 0 {
+		t.Errorf("expected no pending blobs, got %v", archive.pending)
+	}
+	if err = archive.CollectGarbage(); err != nil {
+		t.Fatalf("failed to collect the garbage: %v", err)
+	}
+	if _, err = archive.OpenBlob(job.YAMLDigest); err != nil {
+		t.Errorf("expected the YAML blob of the archived job to be kept: %v", err)
+	}
+}
+
+// countingPodLogsStreamer returns the same logs for all the containers, and counts the calls
+type countingPodLogsStreamer struct {
+	logs  string
+	calls int32
+}
+
+func (s *countingPodLogsStreamer) StreamLogs(ctx context.Context, namespace, pod, container string, follow bool) (io.ReadCloser, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return io.NopCloser(strings.NewReader(s.logs)), nil
+}
+
+func TestJobInformerArchivesTheJobsArchivedBeforeThePipelinesSync(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	streamer := &countingPodLogsStreamer{logs: "building...\ndone\n"}
+	archive, err := NewJobArchive(JobArchiveConfig{Path: t.TempDir()}, newTestPipelineInformer(t, "job-1"), streamer, logger)
+	if err != nil {
+		t.Fatalf("failed to create the archive: %v", err)
+	}
+	defer archive.Close()
+
+	// archived while the pipelines were not synced yet
+	if err = archive.writeManifest(ArchivedJob{Job: Job{Name: "job-1"}, ArchivedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	lhjob := &lhv1alpha1.LighthouseJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "job-1", Namespace: "jx"},
+		Status:     lhv1alpha1.LighthouseJobStatus{State: lhv1alpha1.SuccessState},
+	}
+	informer := &JobInformer{Archive: archive, Logger: logger}
+
+	informer.archiveMissingJob(lhjob)
+	job, err := archive.Get("job-1")
+	if err != nil || job == nil {
+		t.Fatalf("expected the job to be archived, got %v (%v)", job, err)
+	}
+	if len(job.PipelineRuns) != 1 || job.LogsDigest("tr-1", "build") == "" {
+		t.Errorf("expected the job to be archived again with its pipelines and logs, got %+v", job)
+	}
+	if calls := atomic.LoadInt32(&streamer.calls); calls != 1 {
+		t.Errorf("expected the logs to be streamed once, got %d calls", calls)
+	}
+
+	// already archived with its logs
+	informer.archiveMissingJob(lhjob)
+	if calls := atomic.LoadInt32(&streamer.calls); calls != 1 {
+		t.Errorf("expected the job not to be archived again, got %d calls", calls)
+	}
+}
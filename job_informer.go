@@ -9,14 +9,28 @@ import (
 	lhclientset "github.com/jenkins-x/lighthouse/pkg/client/clientset/versioned"
 	lhinformers "github.com/jenkins-x/lighthouse/pkg/client/informers/externalversions"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 )
 
+// archiveWorkers is the max number of jobs archived concurrently
+const archiveWorkers = 4
+
 type JobInformer struct {
 	LHClient       *lhclientset.Clientset
 	Namespace      string
 	ResyncInterval time.Duration
-	Store          *Store
-	Logger         *logrus.Logger
+	Store          Store
+	// Archive is optional - if set, the jobs are archived once completed
+	Archive *JobArchive
+	Logger  *logrus.Logger
+
+	// archiveQueue has the completed jobs to archive - by a fixed number of workers, so that we don't stream the logs of all the jobs at once on startup
+	archiveQueue workqueue.Interface
 }
 
 func (i *JobInformer) Start(ctx context.Context) {
@@ -25,8 +39,91 @@ func (i *JobInformer) Start(ctx context.Context) {
 		i.ResyncInterval,
 		lhinformers.WithNamespace(i.Namespace),
 	)
-	informerFactory.Lighthouse().V1alpha1().LighthouseJobs().Informer().AddEventHandler(i)
+	informer := informerFactory.Lighthouse().V1alpha1().LighthouseJobs().Informer()
+	if i.Archive != nil {
+		i.startArchiveWorkers(ctx)
+	}
+	informer.AddEventHandler(i)
 	informerFactory.Start(ctx.Done())
+
+	go func() {
+		if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			return
+		}
+		i.pruneDeletedJobs(informer.GetStore())
+
+		// the jobs archived before the pipelines were synced don't have their logs
+		if i.Archive != nil && i.Archive.pipelines != nil && cache.WaitForCacheSync(ctx.Done(), i.Archive.pipelines.HasSynced) {
+			for _, obj := range informer.GetStore().List() {
+				if job, ok := obj.(*lhv1alpha1.LighthouseJob); ok && isCompletedLighthouseJob(job) {
+					i.archiveQueue.Add(job)
+				}
+			}
+		}
+	}()
+}
+
+// startArchiveWorkers starts the workers which archive the queued jobs - if they have not been archived yet - until the context is done
+func (i *JobInformer) startArchiveWorkers(ctx context.Context) {
+	i.archiveQueue = workqueue.New()
+	go func() {
+		<-ctx.Done()
+		i.archiveQueue.ShutDown()
+	}()
+	for w := 0; w < archiveWorkers; w++ {
+		go func() {
+			for {
+				item, shutdown := i.archiveQueue.Get()
+				if shutdown {
+					return
+				}
+				if job, ok := item.(*lhv1alpha1.LighthouseJob); ok {
+					i.archiveMissingJob(job)
+				}
+				i.archiveQueue.Done(item)
+			}
+		}()
+	}
+}
+
+// pruneDeletedJobs deletes the stored jobs which are not in the informer cache anymore:
+// the ones deleted while we were not running, if the store is persistent - such as the SQL store
+func (i *JobInformer) pruneDeletedJobs(informerCache cache.Store) {
+	var storedJobs []string
+	err := i.Store.ExportJobs(JobsQuery{}, func(job Job) error {
+		storedJobs = append(storedJobs, job.Name)
+		return nil
+	})
+	if err != nil {
+		if i.Logger != nil {
+			i.Logger.WithError(err).Error("failed to list the stored Jobs to prune the deleted ones")
+		}
+		return
+	}
+
+	existingJobs := map[string]bool{}
+	for _, obj := range informerCache.List() {
+		if job, ok := obj.(*lhv1alpha1.LighthouseJob); ok {
+			existingJobs[job.Name] = true
+		}
+	}
+
+	var pruned int
+	for _, name := range storedJobs {
+		if existingJobs[name] {
+			continue
+		}
+		if err := i.Store.DeleteJob(name); err != nil {
+			if i.Logger != nil {
+				i.Logger.WithError(err).WithField("Job", name).Error("failed to delete Job")
+			}
+			continue
+		}
+		pruned++
+	}
+	if pruned > 0 && i.Logger != nil {
+		i.Logger.WithField("count", pruned).Info("Pruned the Jobs deleted while we were not running")
+	}
 }
 
 func (i *JobInformer) OnAdd(obj interface{}, _ bool) {
@@ -36,6 +133,11 @@ func (i *JobInformer) OnAdd(obj interface{}, _ bool) {
 	}
 
 	i.indexJob(job, "index")
+
+	// the jobs completed while we were not running - or before the archive was enabled
+	if i.Archive != nil && isCompletedLighthouseJob(job) {
+		i.archiveQueue.Add(job)
+	}
 }
 
 func (i *JobInformer) OnUpdate(oldObj, newObj interface{}) {
@@ -45,6 +147,12 @@ func (i *JobInformer) OnUpdate(oldObj, newObj interface{}) {
 	}
 
 	i.indexJob(job, "re-index")
+
+	if i.Archive != nil && isCompletedLighthouseJob(job) {
+		if oldJob, ok := oldObj.(*lhv1alpha1.LighthouseJob); ok && !isCompletedLighthouseJob(oldJob) {
+			i.archiveQueue.Add(job)
+		}
+	}
 }
 func (i *JobInformer) OnDelete(obj interface{}) {
 	job, ok := obj.(*lhv1alpha1.LighthouseJob)
@@ -55,7 +163,9 @@ func (i *JobInformer) OnDelete(obj interface{}) {
 	if i.Logger != nil && i.Logger.IsLevelEnabled(logrus.DebugLevel) {
 		i.Logger.WithField("Job", job.Name).Debug("Deleting Job")
 	}
-	err := i.Store.DeleteJob(job.Name)
+	ctx, span := startJobSpan(job, "delete")
+	err := StoreWithContext(ctx, i.Store).DeleteJob(job.Name)
+	endSpan(span, err)
 	if err != nil && i.Logger != nil {
 		i.Logger.WithError(err).WithField("Job", job.Name).Error("failed to delete Job")
 	}
@@ -65,9 +175,63 @@ func (i *JobInformer) indexJob(job *lhv1alpha1.LighthouseJob, operation string)
 	if i.Logger != nil && i.Logger.IsLevelEnabled(logrus.DebugLevel) {
 		i.Logger.WithField("Job", job.Name).Debugf("%sing Job", strings.Title(operation))
 	}
+	ctx, span := startJobSpan(job, operation)
 	j := JobFromLighthouseJob(job)
-	err := i.Store.AddJob(j)
+	err := StoreWithContext(ctx, i.Store).AddJob(j)
+	endSpan(span, err)
 	if err != nil && i.Logger != nil {
 		i.Logger.WithError(err).WithField("Job", job.Name).Errorf("failed to %s Job", operation)
 	}
 }
+
+// archiveJob archives the job - before it is garbage-collected with its pods
+func (i *JobInformer) archiveJob(job *lhv1alpha1.LighthouseJob) {
+	if i.Logger != nil && i.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		i.Logger.WithField("Job", job.Name).Debug("Archiving Job")
+	}
+	ctx, span := startJobSpan(job, "archive")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	err := i.Archive.Archive(ctx, job)
+	endSpan(span, err)
+	if err != nil && i.Logger != nil {
+		i.Logger.WithError(err).WithField("Job", job.Name).Error("failed to archive Job")
+	}
+}
+
+// archiveMissingJob archives the job if it has not been archived yet - or if it was archived before its pipelines were known
+func (i *JobInformer) archiveMissingJob(job *lhv1alpha1.LighthouseJob) {
+	// the logs can only be archived once we know the pipelines of the job
+	synced := i.Archive.waitForPipelines(pipelinesSyncTimeout)
+	if !synced && i.Logger != nil {
+		i.Logger.WithField("Job", job.Name).Warning("the pipelines are not synced yet - archiving the job without its logs")
+	}
+
+	archived, err := i.Archive.Get(job.Name)
+	if err != nil {
+		if i.Logger != nil {
+			i.Logger.WithError(err).WithField("Job", job.Name).Warning("failed to get the archived Job")
+		}
+		return
+	}
+	if archived == nil || (synced && i.Archive.missingPipelines(archived)) {
+		i.archiveJob(job)
+	}
+}
+
+// startJobSpan starts a span in the trace of the job - from its traceparent annotation set by Lighthouse
+// the jobs without a trace context are not traced, to avoid creating new traces on every resync
+func startJobSpan(job *lhv1alpha1.LighthouseJob, operation string) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": job.Annotations["lighthouse.jenkins-x.io/traceparent"],
+	})
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return otel.Tracer(tracerName).Start(ctx, "lighthouse.job."+operation,
+		trace.WithAttributes(
+			attribute.String("job.name", job.Name),
+			attribute.String("job.context", job.Labels["lighthouse.jenkins-x.io/context"]),
+		),
+	)
+}
@@ -0,0 +1,37 @@
+package webui
+
+import (
+	"testing"
+
+	lhv1alpha1 "github.com/jenkins-x/lighthouse/pkg/apis/lighthouse/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestJobInformerPrunesDeletedJobs(t *testing.T) {
+	store := newTestSQLStore(t, "")
+	for _, name := range []string{"job-1", "job-2", "job-3"} {
+		if err := store.AddJob(Job{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	informerCache := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, name := range []string{"job-2", "job-4"} {
+		err := informerCache.Add(&lhv1alpha1.LighthouseJob{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "jx"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	informer := &JobInformer{Store: store}
+	informer.pruneDeletedJobs(informerCache)
+
+	jobs, err := store.QueryJobs(JobsQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Jobs) != 1 || jobs.Jobs[0].Name != "job-2" {
+		t.Errorf("expected only job-2 to be kept, got %+v", jobs.Jobs)
+	}
+}
@@ -3,101 +3,183 @@ package webui
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/gabs/v2"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultKeeperName is the name of the Keeper source when none is given
+const DefaultKeeperName = "default"
+
 type KeeperSyncer struct {
+	// KeeperName identifies this Keeper as the source of the merge pools and records
+	KeeperName     string
 	KeeperEndpoint string
 	SyncInterval   time.Duration
-	Store          *Store
+	Store          Store
 	Logger         *logrus.Logger
 
 	httpClient *http.Client
+	// interval is the current sync interval, in nanoseconds - it can be changed while a sync is running
+	interval        int64
+	intervalChanged chan struct{}
+	initOnce        sync.Once
+}
+
+// init is called by both Start and SetSyncInterval, so that the interval can be changed before the syncer is started
+func (s *KeeperSyncer) init() {
+	s.initOnce.Do(func() {
+		s.intervalChanged = make(chan struct{}, 1)
+		atomic.StoreInt64(&s.interval, int64(s.SyncInterval))
+	})
 }
 
 func (s *KeeperSyncer) Start(ctx context.Context) {
+	s.init()
 	s.httpClient = http.DefaultClient
 
-	ticker := time.NewTicker(s.SyncInterval)
+	ticker := time.NewTicker(time.Duration(atomic.LoadInt64(&s.interval)))
+
+	if s.KeeperName == "" {
+		s.KeeperName = DefaultKeeperName
+	}
+	log := s.Logger.WithField("keeper", s.KeeperName).WithField("keeperEndpoint", s.KeeperEndpoint)
 
 	go func() {
-		if err := s.Sync(); err != nil {
-			s.Logger.WithError(err).WithField("keeperEndpoint", s.KeeperEndpoint).
-				Error("failed to do the initial sync with Keeper")
+		if err := s.Sync(ctx); err != nil {
+			log.WithError(err).Error("failed to do the initial sync with Keeper")
 		}
 
 		for {
 			select {
 			case <-ticker.C:
-				s.Logger.WithField("keeperEndpoint", s.KeeperEndpoint).Trace("Syncing Keeper merge status/history...")
-				if err := s.Sync(); err != nil {
-					s.Logger.WithError(err).WithField("keeperEndpoint", s.KeeperEndpoint).
-						Error("failed to sync Keeper merge status/history")
+				log.Trace("Syncing Keeper merge status/history...")
+				if err := s.Sync(ctx); err != nil {
+					log.WithError(err).Error("failed to sync Keeper merge status/history")
 				}
+			case <-s.intervalChanged:
+				interval := time.Duration(atomic.LoadInt64(&s.interval))
+				log.WithField("syncInterval", interval).Info("Changing the Keeper sync interval")
+				ticker.Reset(interval)
 			case <-ctx.Done():
-				s.Logger.Info("KeeperSyncer exiting...")
+				log.Info("KeeperSyncer exiting...")
 				return
 			}
 		}
 	}()
 }
 
-func (s *KeeperSyncer) Sync() error {
+// SetSyncInterval changes the interval between 2 syncs.
+// It never blocks: if a sync is running, the new interval is applied once it's done.
+func (s *KeeperSyncer) SetSyncInterval(interval time.Duration) {
+	s.init()
+	atomic.StoreInt64(&s.interval, int64(interval))
+	// a pending notification will already apply the latest interval
+	select {
+	case s.intervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+// Sync retrieves the merge pools and history from Keeper, as a new trace
+func (s *KeeperSyncer) Sync(ctx context.Context) (err error) {
 	const (
 		keeperPoolsPath   = "/"
 		keeperHistoryPath = "/history"
 	)
 
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "keeper.sync",
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.String("keeper", s.KeeperName),
+			attribute.String("keeper.endpoint", s.KeeperEndpoint),
+		),
+	)
+	defer func() { endSpan(span, err) }()
+	store := StoreWithContext(ctx, s.Store)
+
 	{
-		resp, err := s.get(keeperPoolsPath)
+		resp, err := s.get(ctx, keeperPoolsPath)
 		if err != nil {
 			return err
 		}
 		defer resp.Body.Close()
 
-		dec := json.NewDecoder(resp.Body)
-		dec.UseNumber()
-		lhPools, err := gabs.ParseJSONDecoder(dec)
+		pools, err := ParseKeeperPools(s.KeeperName, resp.Body)
 		if err != nil {
 			return err
 		}
-
-		pools := MergePoolsFromLighthousePools(lhPools)
-		s.Store.SetMergeStatus(pools)
+		store.SetMergeStatus(s.KeeperName, pools)
 	}
 
 	{
-		resp, err := s.get(keeperHistoryPath)
+		resp, err := s.get(ctx, keeperHistoryPath)
 		if err != nil {
 			return err
 		}
 		defer resp.Body.Close()
 
-		dec := json.NewDecoder(resp.Body)
-		dec.UseNumber()
-		lhRecords, err := gabs.ParseJSONDecoder(dec)
+		records, err := ParseKeeperHistory(s.KeeperName, resp.Body)
 		if err != nil {
 			return err
 		}
-
-		records := MergeRecordsFromLighthouseRecords(lhRecords)
-		s.Store.SetMergeHistory(records)
+		store.SetMergeHistory(s.KeeperName, records)
 	}
 
 	return nil
 }
 
-func (s *KeeperSyncer) get(path string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodGet, s.KeeperEndpoint+path, nil)
+func (s *KeeperSyncer) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.KeeperEndpoint+path, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", "lighthouse-webui-plugin")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	return s.httpClient.Do(req)
 }
+
+// ParseKeeperPools parses the merge pools JSON document, as exposed by Keeper on its root path
+func ParseKeeperPools(source string, r io.Reader) (pools []MergePool, err error) {
+	lhPools, err := parseKeeperJSON(r)
+	if err != nil {
+		return nil, err
+	}
+	defer recoverInvalidKeeperDocument(&err)
+	return MergePoolsFromLighthousePools(source, lhPools), nil
+}
+
+// ParseKeeperHistory parses the merge history JSON document, as exposed by Keeper on its /history path
+func ParseKeeperHistory(source string, r io.Reader) (records []MergeRecord, err error) {
+	lhRecords, err := parseKeeperJSON(r)
+	if err != nil {
+		return nil, err
+	}
+	defer recoverInvalidKeeperDocument(&err)
+	return MergeRecordsFromLighthouseRecords(source, lhRecords), nil
+}
+
+func parseKeeperJSON(r io.Reader) (*gabs.Container, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return gabs.ParseJSONDecoder(dec)
+}
+
+// recoverInvalidKeeperDocument turns the panics caused by unexpected types in a Keeper document into an error
+func recoverInvalidKeeperDocument(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("invalid Keeper document: %v", r)
+	}
+}
@@ -0,0 +1,28 @@
+package webui
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeeperSyncerSetSyncIntervalDoesNotBlock(t *testing.T) {
+	s := &KeeperSyncer{SyncInterval: time.Minute}
+
+	done := make(chan struct{})
+	go func() {
+		// before Start, and with a pending change which is never consumed
+		s.SetSyncInterval(2 * time.Minute)
+		s.SetSyncInterval(3 * time.Minute)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SetSyncInterval blocked")
+	}
+
+	if interval := time.Duration(atomic.LoadInt64(&s.interval)); interval != 3*time.Minute {
+		t.Errorf("expected the latest interval to be kept, got %s", interval)
+	}
+}
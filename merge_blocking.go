@@ -0,0 +1,129 @@
+package webui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	BlockingReasonKindMergeable = "mergeable"
+	BlockingReasonKindJob       = "job"
+	BlockingReasonKindBlocker   = "blocker"
+	BlockingReasonKindLabel     = "label"
+	BlockingReasonKindUnknown   = "unknown"
+)
+
+// BlockingReason explains why a PR is in the MissingPRs of a merge pool
+type BlockingReason struct {
+	Kind        string
+	Description string
+	URL         string
+}
+
+// blockingLabelPrefixes are the prefixes of the labels used by convention to prevent a PR from being merged
+var blockingLabelPrefixes = []string{
+	"do-not-merge",
+	"needs-",
+}
+
+// BlockingReasonsForPullRequest correlates a missing PR with the latest jobs and events of its branch
+// jobs are expected to be sorted by most recent first, as returned by Store.QueryJobs
+func BlockingReasonsForPullRequest(pool MergePool, pr PullRequest, jobs []Job, events []Event) []BlockingReason {
+	var reasons []BlockingReason
+
+	switch pr.Mergeable {
+	case "MERGEABLE":
+	// the mergeable state is not computed yet by the git provider, so it doesn't block the PR
+	case "", "UNKNOWN":
+	case "CONFLICTING":
+		reasons = append(reasons, BlockingReason{
+			Kind:        BlockingReasonKindMergeable,
+			Description: fmt.Sprintf("Conflicts with the %s branch", pool.Branch),
+		})
+	default:
+		reasons = append(reasons, BlockingReason{
+			Kind:        BlockingReasonKindMergeable,
+			Description: fmt.Sprintf("Mergeable state is %s", strings.ToLower(pr.Mergeable)),
+		})
+	}
+
+	contexts := map[string]bool{}
+	for _, job := range jobs {
+		if job.Context == "" || contexts[job.Context] {
+			continue
+		}
+		contexts[job.Context] = true
+
+		var description string
+		switch job.State {
+		case "failure", "error", "aborted":
+			description = fmt.Sprintf("Context %s is in %s state", job.Context, job.State)
+		case "triggered", "pending", "running":
+			description = fmt.Sprintf("Context %s is still %s", job.Context, job.State)
+		default:
+			continue
+		}
+		reasons = append(reasons, BlockingReason{
+			Kind:        BlockingReasonKindJob,
+			Description: description,
+			URL:         job.ReportURL,
+		})
+	}
+
+	for _, blocker := range pool.Blockers {
+		reasons = append(reasons, BlockingReason{
+			Kind:        BlockingReasonKindBlocker,
+			Description: fmt.Sprintf("Branch blocked by issue #%d: %s", blocker.Number, blocker.Title),
+			URL:         blocker.URL,
+		})
+	}
+
+	labels := latestPullRequestLabels(events)
+	for _, label := range labels {
+		for _, prefix := range blockingLabelPrefixes {
+			if strings.HasPrefix(label, prefix) {
+				reasons = append(reasons, BlockingReason{
+					Kind:        BlockingReasonKindLabel,
+					Description: fmt.Sprintf("Has the %s label", label),
+				})
+				break
+			}
+		}
+	}
+
+	if len(reasons) == 0 {
+		description := "No known reason - most likely a required label is missing"
+		if len(labels) > 0 {
+			description = fmt.Sprintf("%s. Current labels: %s", description, strings.Join(labels, ", "))
+		}
+		reasons = append(reasons, BlockingReason{
+			Kind:        BlockingReasonKindUnknown,
+			Description: description,
+		})
+	}
+
+	return reasons
+}
+
+// latestPullRequestLabels returns the labels of the most recent pull_request event
+// because each pull_request webhook carries the full set of labels of the PR
+func latestPullRequestLabels(events []Event) []string {
+	var latest *Event
+	for i := range events {
+		if events[i].Kind != "pull_request" {
+			continue
+		}
+		if latest == nil || events[i].Time.After(latest.Time) {
+			latest = &events[i]
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+
+	labels := make([]string, len(latest.Labels))
+	copy(labels, latest.Labels)
+	sort.Strings(labels)
+	return labels
+}
@@ -10,6 +10,9 @@ import (
 
 // from lighthouse/pkg/keeper/history.Record
 type MergeRecord struct {
+	// Source is the name of the Keeper the record comes from
+	Source string
+
 	Owner      string
 	Repository string
 	Branch     string
@@ -24,7 +27,20 @@ type MergeRecord struct {
 	KeeperRecord interface{}
 }
 
-func MergeRecordsFromLighthouseRecords(lhRecords *gabs.Container) []MergeRecord {
+// HasSHA returns true if the given commit is the base of the record, or the head of one of its pull requests
+func (r MergeRecord) HasSHA(sha string) bool {
+	if r.BaseSHA == sha {
+		return true
+	}
+	for _, pr := range r.PRs {
+		if pr.SHA == sha {
+			return true
+		}
+	}
+	return false
+}
+
+func MergeRecordsFromLighthouseRecords(source string, lhRecords *gabs.Container) []MergeRecord {
 	if lhRecords == nil {
 		return nil
 	}
@@ -37,6 +53,7 @@ func MergeRecordsFromLighthouseRecords(lhRecords *gabs.Container) []MergeRecord
 			child.Set(repo, "Repo")
 			child.Set(branch, "Branch")
 			record := MergeRecordFromLighthouseRecord(child)
+			record.Source = source
 			records = append(records, record)
 		}
 	}
@@ -52,6 +69,10 @@ func MergeRecordFromLighthouseRecord(lhRecord *gabs.Container) MergeRecord {
 		KeeperRecord: lhRecord.Data(),
 	}
 
+	if baseSHA, ok := lhRecord.Search("baseSHA").Data().(string); ok {
+		record.BaseSHA = baseSHA
+	}
+
 	timeStr := lhRecord.Search("time").Data().(string)
 	if t, err := time.Parse(time.RFC3339Nano, timeStr); err == nil {
 		record.Time = t
@@ -62,6 +83,9 @@ func MergeRecordFromLighthouseRecord(lhRecord *gabs.Container) MergeRecord {
 			Author: target.Search("author").Data().(string),
 			Title:  target.Search("title").Data().(string),
 		}
+		if sha, ok := target.Search("sha").Data().(string); ok {
+			pr.SHA = sha
+		}
 		number := target.Search("number").Data().(json.Number)
 		if n, err := number.Int64(); err == nil {
 			pr.Number = int(n)
@@ -9,6 +9,9 @@ import (
 
 // from lighthouse/pkg/keeper.Pool
 type MergePool struct {
+	// Source is the name of the Keeper the pool comes from
+	Source string
+
 	Owner      string
 	Repository string
 	Branch     string
@@ -31,16 +34,41 @@ type MergePool struct {
 	Blockers []BlockerIssue
 	Error    string
 
+	// these are not part of the keeper pool, but tracked by the store
+	// when the pool is updated, to know for how long the branch has been blocked/in error
+	BlockedSince time.Time
+	ErrorSince   time.Time
+
 	// this is the original keeper object
 	KeeperPool interface{}
 }
 
+func (p MergePool) Key() string {
+	return p.Source + "|" + p.Owner + "/" + p.Repository + ":" + p.Branch
+}
+
+func (p MergePool) BlockedDuration() time.Duration {
+	if p.BlockedSince.IsZero() {
+		return 0
+	}
+	return time.Since(p.BlockedSince).Round(time.Second)
+}
+
+func (p MergePool) ErrorDuration() time.Duration {
+	if p.ErrorSince.IsZero() {
+		return 0
+	}
+	return time.Since(p.ErrorSince).Round(time.Second)
+}
+
 type PullRequest struct {
 	Number    int
 	Author    string
 	Mergeable string
 	Title     string
 	UpdatedAt time.Time
+	// SHA is the head of the pull request - only for the merge records
+	SHA string
 }
 
 type BlockerIssue struct {
@@ -49,7 +77,7 @@ type BlockerIssue struct {
 	URL    string
 }
 
-func MergePoolsFromLighthousePools(lhPools *gabs.Container) []MergePool {
+func MergePoolsFromLighthousePools(source string, lhPools *gabs.Container) []MergePool {
 	if lhPools == nil {
 		return nil
 	}
@@ -57,6 +85,7 @@ func MergePoolsFromLighthousePools(lhPools *gabs.Container) []MergePool {
 	var pools []MergePool
 	for _, child := range lhPools.Children() {
 		pool := MergePoolFromLighthousePool(child)
+		pool.Source = source
 		pools = append(pools, pool)
 	}
 	return pools
@@ -107,6 +136,10 @@ func MergePoolFromLighthousePool(lhPool *gabs.Container) MergePool {
 		}
 		pool.Target = append(pool.Target, pr)
 	}
+	for _, child := range lhPool.Search("Blockers").Children() {
+		blocker := BlockerIssueFromLighthouseBlocker(child)
+		pool.Blockers = append(pool.Blockers, blocker)
+	}
 
 	return pool
 }
@@ -130,3 +163,20 @@ func PullRequestFromLighthousePullRequest(lhPR *gabs.Container) PullRequest {
 
 	return pr
 }
+
+// from lighthouse/pkg/keeper/blockers.Blocker
+func BlockerIssueFromLighthouseBlocker(lhBlocker *gabs.Container) BlockerIssue {
+	blocker := BlockerIssue{}
+	if title, ok := lhBlocker.Search("Title").Data().(string); ok {
+		blocker.Title = title
+	}
+	if url, ok := lhBlocker.Search("URL").Data().(string); ok {
+		blocker.URL = url
+	}
+	if number, ok := lhBlocker.Search("Number").Data().(json.Number); ok {
+		if n, err := number.Int64(); err == nil {
+			blocker.Number = int(n)
+		}
+	}
+	return blocker
+}
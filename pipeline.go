@@ -0,0 +1,233 @@
+package webui
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// the states of the pipelines, tasks and steps
+const (
+	PipelineStatePending   = "pending"
+	PipelineStateRunning   = "running"
+	PipelineStateSucceeded = "succeeded"
+	PipelineStateFailed    = "failed"
+)
+
+// PodLogsStreamer streams the logs of a container - such as a step of a TaskRun
+type PodLogsStreamer interface {
+	StreamLogs(ctx context.Context, namespace, pod, container string, follow bool) (io.ReadCloser, error)
+}
+
+// PipelineRun is a Tekton PipelineRun, run for a Lighthouse job
+type PipelineRun struct {
+	Name      string
+	Namespace string
+	JobName   string
+	State     string
+	Reason    string
+	Message   string
+	Start     time.Time
+	End       time.Time
+	Duration  time.Duration
+	TaskRuns  []TaskRun
+}
+
+// TaskRun is a Tekton TaskRun, run as part of a PipelineRun
+type TaskRun struct {
+	Name string
+	// PipelineTask is the name of the task in the pipeline
+	PipelineTask string
+	PodName      string
+	State        string
+	Reason       string
+	Message      string
+	Start        time.Time
+	End          time.Time
+	Duration     time.Duration
+	Steps        []Step
+}
+
+// Step is a step of a TaskRun, run as a container of its pod
+type Step struct {
+	Name      string
+	Container string
+	State     string
+	Reason    string
+	ExitCode  int32
+	Start     time.Time
+	End       time.Time
+	Duration  time.Duration
+}
+
+// Step returns the step with the given name, or nil
+func (t TaskRun) Step(name string) *Step {
+	for i := range t.Steps {
+		if t.Steps[i].Name == name {
+			return &t.Steps[i]
+		}
+	}
+	return nil
+}
+
+// TaskRun returns the TaskRun with the given name, or nil
+func (p PipelineRun) TaskRun(name string) *TaskRun {
+	for i := range p.TaskRuns {
+		if p.TaskRuns[i].Name == name {
+			return &p.TaskRuns[i]
+		}
+	}
+	return nil
+}
+
+// tektonRun is the subset of the Tekton PipelineRun and TaskRun resources that we use
+// it is shared by both resources, and by the v1beta1 and v1 versions
+type tektonRun struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Status            struct {
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+		StartTime      *metav1.Time `json:"startTime"`
+		CompletionTime *metav1.Time `json:"completionTime"`
+		PodName        string       `json:"podName"`
+		Steps          []struct {
+			Name       string `json:"name"`
+			Container  string `json:"container"`
+			Terminated *struct {
+				ExitCode   int32       `json:"exitCode"`
+				Reason     string      `json:"reason"`
+				StartedAt  metav1.Time `json:"startedAt"`
+				FinishedAt metav1.Time `json:"finishedAt"`
+			} `json:"terminated"`
+			Running *struct {
+				StartedAt metav1.Time `json:"startedAt"`
+			} `json:"running"`
+			Waiting *struct {
+				Reason string `json:"reason"`
+			} `json:"waiting"`
+		} `json:"steps"`
+	} `json:"status"`
+}
+
+func tektonRunFromUnstructured(obj *unstructured.Unstructured) (*tektonRun, error) {
+	run := new(tektonRun)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// state returns the state, reason and message from the Succeeded condition
+func (r *tektonRun) state() (state, reason, message string) {
+	for _, condition := range r.Status.Conditions {
+		if condition.Type != "Succeeded" {
+			continue
+		}
+		switch condition.Status {
+		case "True":
+			state = PipelineStateSucceeded
+		case "False":
+			state = PipelineStateFailed
+		default:
+			state = PipelineStateRunning
+			if condition.Reason == "Pending" || condition.Reason == "PipelineRunPending" {
+				state = PipelineStatePending
+			}
+		}
+		return state, condition.Reason, condition.Message
+	}
+	return PipelineStatePending, "", ""
+}
+
+// timeRange returns the start, end and duration - until now if still running
+func timeRange(start, end *metav1.Time) (time.Time, time.Time, time.Duration) {
+	if start == nil {
+		return time.Time{}, time.Time{}, 0
+	}
+	if end == nil {
+		return start.Time, time.Time{}, time.Since(start.Time).Round(time.Second)
+	}
+	return start.Time, end.Time, end.Sub(start.Time)
+}
+
+func PipelineRunFromUnstructured(obj *unstructured.Unstructured, jobName string) (*PipelineRun, error) {
+	run, err := tektonRunFromUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	p := PipelineRun{
+		Name:      run.Name,
+		Namespace: run.Namespace,
+		JobName:   jobName,
+	}
+	p.State, p.Reason, p.Message = run.state()
+	p.Start, p.End, p.Duration = timeRange(run.Status.StartTime, run.Status.CompletionTime)
+	return &p, nil
+}
+
+func TaskRunFromUnstructured(obj *unstructured.Unstructured) (*TaskRun, error) {
+	run, err := tektonRunFromUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	t := TaskRun{
+		Name:         run.Name,
+		PipelineTask: run.Labels[tektonPipelineTaskLabel],
+		PodName:      run.Status.PodName,
+	}
+	if t.PipelineTask == "" {
+		t.PipelineTask = run.Name
+	}
+	t.State, t.Reason, t.Message = run.state()
+	t.Start, t.End, t.Duration = timeRange(run.Status.StartTime, run.Status.CompletionTime)
+
+	for _, s := range run.Status.Steps {
+		step := Step{
+			Name:      s.Name,
+			Container: s.Container,
+			State:     PipelineStatePending,
+		}
+		if step.Container == "" {
+			step.Container = "step-" + s.Name
+		}
+		switch {
+		case s.Terminated != nil:
+			step.State = PipelineStateSucceeded
+			if s.Terminated.ExitCode != 0 {
+				step.State = PipelineStateFailed
+			}
+			step.Reason = s.Terminated.Reason
+			step.ExitCode = s.Terminated.ExitCode
+			step.Start, step.End, step.Duration = timeRange(&s.Terminated.StartedAt, &s.Terminated.FinishedAt)
+		case s.Running != nil:
+			step.State = PipelineStateRunning
+			step.Start, step.End, step.Duration = timeRange(&s.Running.StartedAt, nil)
+		case s.Waiting != nil:
+			step.Reason = s.Waiting.Reason
+		}
+		t.Steps = append(t.Steps, step)
+	}
+	return &t, nil
+}
+
+// sortTaskRuns sorts the TaskRuns by start time - the ones not started yet last
+func sortTaskRuns(taskRuns []TaskRun) {
+	sort.SliceStable(taskRuns, func(i, j int) bool {
+		if taskRuns[i].Start.IsZero() != taskRuns[j].Start.IsZero() {
+			return !taskRuns[i].Start.IsZero()
+		}
+		if !taskRuns[i].Start.Equal(taskRuns[j].Start) {
+			return taskRuns[i].Start.Before(taskRuns[j].Start)
+		}
+		return taskRuns[i].PipelineTask < taskRuns[j].PipelineTask
+	})
+}
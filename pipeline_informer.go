@@ -0,0 +1,161 @@
+package webui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// tektonPipelineRunLabel is set by Tekton on the TaskRuns of a PipelineRun
+	tektonPipelineRunLabel = "tekton.dev/pipelineRun"
+	// tektonPipelineTaskLabel is set by Tekton on the TaskRuns, with the name of the task in the pipeline
+	tektonPipelineTaskLabel = "tekton.dev/pipelineTask"
+
+	jobIndex         = "job"
+	pipelineRunIndex = "pipelineRun"
+)
+
+var (
+	PipelineRunsResource = schema.GroupVersionResource{Group: "tekton.dev", Version: "v1beta1", Resource: "pipelineruns"}
+	TaskRunsResource     = schema.GroupVersionResource{Group: "tekton.dev", Version: "v1beta1", Resource: "taskruns"}
+)
+
+// PipelineInformer watches the Tekton PipelineRuns owned by the Lighthouse jobs, and their TaskRuns.
+// They are kept in the informers caches - not in the store - because they are only useful while they exist in the cluster.
+type PipelineInformer struct {
+	// Client is a dynamic client, so that we don't depend on a specific version of Tekton
+	Client         dynamic.Interface
+	Namespace      string
+	ResyncInterval time.Duration
+	Logger         *logrus.Logger
+
+	pipelineRuns cache.SharedIndexInformer
+	taskRuns     cache.SharedIndexInformer
+}
+
+func (i *PipelineInformer) Start(ctx context.Context) {
+	i.pipelineRuns = i.newInformer(PipelineRunsResource, cache.Indexers{
+		jobIndex: indexByOwnerLighthouseJob,
+	})
+	i.taskRuns = i.newInformer(TaskRunsResource, cache.Indexers{
+		pipelineRunIndex: indexByPipelineRun,
+	})
+
+	go i.pipelineRuns.Run(ctx.Done())
+	go i.taskRuns.Run(ctx.Done())
+}
+
+// HasSynced returns true once the informers caches are filled
+func (i *PipelineInformer) HasSynced() bool {
+	return i != nil && i.pipelineRuns != nil && i.pipelineRuns.HasSynced() && i.taskRuns.HasSynced()
+}
+
+func (i *PipelineInformer) newInformer(resource schema.GroupVersionResource, indexers cache.Indexers) cache.SharedIndexInformer {
+	client := i.Client.Resource(resource).Namespace(i.Namespace)
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.Watch(context.Background(), options)
+			},
+		},
+		&unstructured.Unstructured{},
+		i.ResyncInterval,
+		indexers,
+	)
+	_ = informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		if i.Logger != nil {
+			i.Logger.WithError(err).WithField("resource", resource.String()).Warning("failed to watch the Tekton resources")
+		}
+	})
+	return informer
+}
+
+// PipelineRunsForJob returns the PipelineRuns of the given Lighthouse job - usually only one - with their TaskRuns
+func (i *PipelineInformer) PipelineRunsForJob(jobName string) ([]PipelineRun, error) {
+	if i == nil || i.pipelineRuns == nil {
+		return nil, nil
+	}
+
+	objs, err := i.pipelineRuns.GetIndexer().ByIndex(jobIndex, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineRuns := make([]PipelineRun, 0, len(objs))
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		pipelineRun, err := PipelineRunFromUnstructured(u, jobName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PipelineRun %s: %w", u.GetName(), err)
+		}
+
+		taskRunObjs, err := i.taskRuns.GetIndexer().ByIndex(pipelineRunIndex, u.GetNamespace()+"/"+u.GetName())
+		if err != nil {
+			return nil, err
+		}
+		for _, taskRunObj := range taskRunObjs {
+			taskRunUnstructured, ok := taskRunObj.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			taskRun, err := TaskRunFromUnstructured(taskRunUnstructured)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TaskRun %s: %w", taskRunUnstructured.GetName(), err)
+			}
+			pipelineRun.TaskRuns = append(pipelineRun.TaskRuns, *taskRun)
+		}
+		sortTaskRuns(pipelineRun.TaskRuns)
+
+		pipelineRuns = append(pipelineRuns, *pipelineRun)
+	}
+
+	sort.Slice(pipelineRuns, func(a, b int) bool {
+		return pipelineRuns[a].Start.After(pipelineRuns[b].Start)
+	})
+	return pipelineRuns, nil
+}
+
+// indexByOwnerLighthouseJob indexes the PipelineRuns by the name of the Lighthouse job which created them
+func indexByOwnerLighthouseJob(obj interface{}) ([]string, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+	var jobs []string
+	for _, owner := range u.GetOwnerReferences() {
+		if owner.Kind == "LighthouseJob" {
+			jobs = append(jobs, owner.Name)
+		}
+	}
+	return jobs, nil
+}
+
+// indexByPipelineRun indexes the TaskRuns by the namespace/name of their PipelineRun
+func indexByPipelineRun(obj interface{}) ([]string, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+	pipelineRun := u.GetLabels()[tektonPipelineRunLabel]
+	if pipelineRun == "" {
+		return nil, nil
+	}
+	return []string{u.GetNamespace() + "/" + pipelineRun}, nil
+}
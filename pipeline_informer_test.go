@@ -0,0 +1,144 @@
+package webui
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newTektonRun(kind, name string, labels map[string]string, owner string, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "tekton.dev/v1beta1",
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "jx",
+		},
+		"status": status,
+	}}
+	u.SetLabels(labels)
+	if owner != "" {
+		u.SetOwnerReferences([]metav1.OwnerReference{
+			{APIVersion: "lighthouse.jenkins.io/v1alpha1", Kind: "LighthouseJob", Name: owner, UID: "uid"},
+		})
+	}
+	return u
+}
+
+func TestIndexByOwnerLighthouseJob(t *testing.T) {
+	pipelineRun := newTektonRun("PipelineRun", "pr-1", nil, "job-1", nil)
+	pipelineRun.SetOwnerReferences(append(pipelineRun.GetOwnerReferences(), metav1.OwnerReference{Kind: "ConfigMap", Name: "not-a-job"}))
+
+	jobs, err := indexByOwnerLighthouseJob(pipelineRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0] != "job-1" {
+		t.Errorf("expected the PipelineRun to be indexed by job-1, got %v", jobs)
+	}
+
+	jobs, err = indexByOwnerLighthouseJob(newTektonRun("PipelineRun", "pr-2", nil, "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("expected a PipelineRun without owner not to be indexed, got %v", jobs)
+	}
+
+	if jobs, _ = indexByOwnerLighthouseJob("not an object"); len(jobs) != 0 {
+		t.Errorf("expected an unknown object not to be indexed, got %v", jobs)
+	}
+}
+
+func TestIndexByPipelineRun(t *testing.T) {
+	pipelineRuns, err := indexByPipelineRun(newTektonRun("TaskRun", "tr-1", map[string]string{tektonPipelineRunLabel: "pr-1"}, "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipelineRuns) != 1 || pipelineRuns[0] != "jx/pr-1" {
+		t.Errorf("expected the TaskRun to be indexed by jx/pr-1, got %v", pipelineRuns)
+	}
+
+	pipelineRuns, err = indexByPipelineRun(newTektonRun("TaskRun", "tr-2", nil, "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipelineRuns) != 0 {
+		t.Errorf("expected a TaskRun without PipelineRun not to be indexed, got %v", pipelineRuns)
+	}
+}
+
+func TestPipelineRunsForJob(t *testing.T) {
+	succeeded := func(start, end string) map[string]interface{} {
+		return map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Succeeded", "status": "True", "reason": "Succeeded"},
+			},
+			"startTime":      start,
+			"completionTime": end,
+		}
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			PipelineRunsResource: "PipelineRunList",
+			TaskRunsResource:     "TaskRunList",
+		},
+		newTektonRun("PipelineRun", "pr-old", nil, "job-1", succeeded("2023-06-01T10:00:00Z", "2023-06-01T10:05:00Z")),
+		newTektonRun("PipelineRun", "pr-new", nil, "job-1", succeeded("2023-06-01T11:00:00Z", "2023-06-01T11:05:00Z")),
+		newTektonRun("PipelineRun", "pr-other", nil, "job-2", succeeded("2023-06-01T11:00:00Z", "2023-06-01T11:05:00Z")),
+		newTektonRun("TaskRun", "tr-test", map[string]string{tektonPipelineRunLabel: "pr-new", tektonPipelineTaskLabel: "test"}, "", succeeded("2023-06-01T11:02:00Z", "2023-06-01T11:05:00Z")),
+		newTektonRun("TaskRun", "tr-build", map[string]string{tektonPipelineRunLabel: "pr-new", tektonPipelineTaskLabel: "build"}, "", succeeded("2023-06-01T11:00:00Z", "2023-06-01T11:02:00Z")),
+		newTektonRun("TaskRun", "tr-other", map[string]string{tektonPipelineRunLabel: "pr-other"}, "", succeeded("2023-06-01T11:00:00Z", "2023-06-01T11:05:00Z")),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	informer := &PipelineInformer{Client: client, Namespace: "jx"}
+	informer.Start(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for !informer.HasSynced() {
+		if time.Now().After(deadline) {
+			t.Fatal("the informers caches were not synced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	pipelineRuns, err := informer.PipelineRunsForJob("job-1")
+	if err != nil {
+		t.Fatalf("failed to get the PipelineRuns: %v", err)
+	}
+	if len(pipelineRuns) != 2 {
+		t.Fatalf("expected 2 PipelineRuns, got %d", len(pipelineRuns))
+	}
+	if pipelineRuns[0].Name != "pr-new" || pipelineRuns[1].Name != "pr-old" {
+		t.Errorf("expected the most recent PipelineRun first, got %s and %s", pipelineRuns[0].Name, pipelineRuns[1].Name)
+	}
+	if pipelineRuns[0].JobName != "job-1" || pipelineRuns[0].State != PipelineStateSucceeded || pipelineRuns[0].Duration != 5*time.Minute {
+		t.Errorf("unexpected PipelineRun: %+v", pipelineRuns[0])
+	}
+	taskRuns := pipelineRuns[0].TaskRuns
+	if len(taskRuns) != 2 || taskRuns[0].Name != "tr-build" || taskRuns[1].Name != "tr-test" {
+		t.Errorf("expected the TaskRuns of the PipelineRun sorted by start time, got %+v", taskRuns)
+	}
+	if len(pipelineRuns[1].TaskRuns) != 0 {
+		t.Errorf("expected no TaskRuns for pr-old, got %+v", pipelineRuns[1].TaskRuns)
+	}
+
+	if pipelineRuns, err = informer.PipelineRunsForJob("unknown-job"); err != nil || len(pipelineRuns) != 0 {
+		t.Errorf("expected no PipelineRuns for an unknown job, got %v (%v)", pipelineRuns, err)
+	}
+}
+
+func TestPipelineRunsForJobWithoutInformer(t *testing.T) {
+	var informer *PipelineInformer
+	pipelineRuns, err := informer.PipelineRunsForJob("job-1")
+	if err != nil || pipelineRuns != nil {
+		t.Errorf("expected no PipelineRuns without informer, got %v (%v)", pipelineRuns, err)
+	}
+}
@@ -0,0 +1,87 @@
+package webui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskRunFromUnstructured(t *testing.T) {
+	obj := newTektonRun("TaskRun", "tr-1", map[string]string{tektonPipelineRunLabel: "pr-1", tektonPipelineTaskLabel: "build"}, "", map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Succeeded", "status": "False", "reason": "Failed", "message": "step test failed"},
+		},
+		"startTime":      "2023-06-01T10:00:00Z",
+		"completionTime": "2023-06-01T10:03:00Z",
+		"podName":        "tr-1-pod",
+		"steps": []interface{}{
+			map[string]interface{}{
+				"name":      "build",
+				"container": "step-build",
+				"terminated": map[string]interface{}{
+					"exitCode":   int64(0),
+					"reason":     "Completed",
+					"startedAt":  "2023-06-01T10:00:00Z",
+					"finishedAt": "2023-06-01T10:02:00Z",
+				},
+			},
+			map[string]interface{}{
+				"name": "test",
+				"terminated": map[string]interface{}{
+					"exitCode":   int64(1),
+					"reason":     "Error",
+					"startedAt":  "2023-06-01T10:02:00Z",
+					"finishedAt": "2023-06-01T10:03:00Z",
+				},
+			},
+			map[string]interface{}{
+				"name": "publish",
+				"waiting": map[string]interface{}{
+					"reason": "PodInitializing",
+				},
+			},
+		},
+	})
+
+	taskRun, err := TaskRunFromUnstructured(obj)
+	if err != nil {
+		t.Fatalf("failed to convert the TaskRun: %v", err)
+	}
+	if taskRun.Name != "tr-1" || taskRun.PipelineTask != "build" || taskRun.PodName != "tr-1-pod" {
+		t.Errorf("unexpected TaskRun metadata: %+v", taskRun)
+	}
+	if taskRun.State != PipelineStateFailed || taskRun.Reason != "Failed" || taskRun.Message != "step test failed" {
+		t.Errorf("unexpected TaskRun state: %s %s %s", taskRun.State, taskRun.Reason, taskRun.Message)
+	}
+	if taskRun.Duration != 3*time.Minute {
+		t.Errorf("expected a duration of 3m, got %s", taskRun.Duration)
+	}
+
+	expectedSteps := []Step{
+		{Name: "build", Container: "step-build", State: PipelineStateSucceeded, Reason: "Completed", Duration: 2 * time.Minute},
+		{Name: "test", Container: "step-test", State: PipelineStateFailed, Reason: "Error", ExitCode: 1, Duration: time.Minute},
+		{Name: "publish", Container: "step-publish", State: PipelineStatePending, Reason: "PodInitializing"},
+	}
+	if len(taskRun.Steps) != len(expectedSteps) {
+		t.Fatalf("expected %d steps, got %d", len(expectedSteps), len(taskRun.Steps))
+	}
+	for i, expected := range expectedSteps {
+		step := taskRun.Steps[i]
+		if step.Name != expected.Name || step.Container != expected.Container || step.State != expected.State ||
+			step.Reason != expected.Reason || step.ExitCode != expected.ExitCode || step.Duration != expected.Duration {
+			t.Errorf("unexpected step %d: %+v - expected %+v", i, step, expected)
+		}
+	}
+}
+
+func TestTaskRunFromUnstructuredWithoutStatus(t *testing.T) {
+	taskRun, err := TaskRunFromUnstructured(newTektonRun("TaskRun", "tr-1", nil, "", map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("failed to convert the TaskRun: %v", err)
+	}
+	if taskRun.PipelineTask != "tr-1" {
+		t.Errorf("expected the name of the TaskRun as the pipeline task, got %s", taskRun.PipelineTask)
+	}
+	if taskRun.State != PipelineStatePending || !taskRun.Start.IsZero() || len(taskRun.Steps) != 0 {
+		t.Errorf("expected a pending TaskRun, got %+v", taskRun)
+	}
+}
@@ -0,0 +1,288 @@
+package webui
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/search/query"
+)
+
+// the types of the fields which can be used in a query string
+const (
+	QueryFieldTypeKeyword = "keyword"
+	QueryFieldTypeDate    = "date"
+	QueryFieldTypeNumber  = "number"
+)
+
+// QueryField is a field of the jobs or events, which can be used in a query string - such as State in State:failure
+type QueryField struct {
+	Name string
+	// Type is either QueryFieldTypeKeyword, QueryFieldTypeDate or QueryFieldTypeNumber
+	Type string
+}
+
+// JobQueryFields and EventQueryFields are the fields which can be used in the query strings of the jobs and events, sorted by name
+var (
+	JobQueryFields   = queryFieldsOf(Job{})
+	EventQueryFields = queryFieldsOf(Event{})
+)
+
+// queryFieldsOf returns the indexed fields of the given struct
+func queryFieldsOf(v interface{}) []QueryField {
+	var (
+		fields       []QueryField
+		t            = reflect.TypeOf(v)
+		timeType     = reflect.TypeOf(time.Time{})
+		durationType = reflect.TypeOf(time.Duration(0))
+	)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		field := QueryField{Name: f.Name}
+		switch {
+		case f.Type == timeType:
+			field.Type = QueryFieldTypeDate
+		case f.Type == durationType:
+			field.Type = QueryFieldTypeNumber
+		case f.Type.Kind() == reflect.String:
+			field.Type = QueryFieldTypeKeyword
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.String:
+			field.Type = QueryFieldTypeKeyword
+		default:
+			continue
+		}
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return fields
+}
+
+// FieldValue is a value of a field, with the number of jobs or events with this value
+type FieldValue struct {
+	Value string
+	Count int
+}
+
+// sortFieldValues sorts the values by decreasing count, and keeps the first ones
+func sortFieldValues(values []FieldValue, limit int) []FieldValue {
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+	return values
+}
+
+// QueryError is returned for an invalid query string, so that it can be reported to the user - instead of an internal error
+type QueryError struct {
+	Query   string
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query %q: %s", e.Query, e.Message)
+}
+
+// QueryTerm is a term of a query string - such as +State:failure, -Type:batch, Start:>"2021-06-01" or a value without field
+type QueryTerm struct {
+	// Occur is either '+' for a term which must match, '-' for a term which must not match, or 0
+	Occur byte
+	// Field is empty for a term which matches any field
+	Field string
+	// Operator is either >, >=, < or <= for a comparison, or empty
+	Operator string
+	// Value is unquoted, and keeps the optional boost (^) or fuzziness (~) suffix of the term
+	Value  string
+	Quoted bool
+}
+
+// ParseQueryString splits a query string into its terms, and checks that their fields are known, and their values valid for their type
+func ParseQueryString(queryString string, fields []QueryField) ([]QueryTerm, error) {
+	fieldTypes := make(map[string]string, len(fields))
+	for _, field := range fields {
+		fieldTypes[field.Name] = field.Type
+	}
+
+	rawTerms, err := splitQueryTerms(queryString)
+	if err != nil {
+		return nil, &QueryError{Query: queryString, Message: err.Error()}
+	}
+
+	terms := make([]QueryTerm, 0, len(rawTerms))
+	for _, rawTerm := range rawTerms {
+		term, err := parseQueryTerm(rawTerm, fieldTypes)
+		if err != nil {
+			if fieldErr, ok := err.(unknownFieldError); ok {
+				return nil, &QueryError{Query: queryString, Message: fieldErr.message(fields)}
+			}
+			return nil, &QueryError{Query: queryString, Message: fmt.Sprintf("%s in %q", err, rawTerm)}
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// splitQueryTerms splits a query string on spaces, except inside quotes
+func splitQueryTerms(queryString string) ([]string, error) {
+	var (
+		terms    []string
+		term     strings.Builder
+		inQuotes bool
+		escaped  bool
+	)
+	for _, r := range queryString {
+		switch {
+		case escaped:
+			escaped = false
+			term.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			term.WriteRune(r)
+		case r == '"':
+			inQuotes = !inQuotes
+			term.WriteRune(r)
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("missing closing quote")
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms, nil
+}
+
+type unknownFieldError string
+
+func (e unknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", string(e))
+}
+
+// message suggests the field with the same name but a different case - the field names are case-sensitive - or lists all the fields
+func (e unknownFieldError) message(fields []QueryField) string {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if strings.EqualFold(field.Name, string(e)) {
+			return fmt.Sprintf("%s - did you mean %s?", e.Error(), field.Name)
+		}
+		names = append(names, field.Name)
+	}
+	return fmt.Sprintf("%s - the fields are %s", e.Error(), strings.Join(names, ", "))
+}
+
+func parseQueryTerm(rawTerm string, fieldTypes map[string]string) (QueryTerm, error) {
+	var term QueryTerm
+	value := rawTerm
+	if value[0] == '+' || value[0] == '-' {
+		term.Occur, value = value[0], value[1:]
+	}
+	if field, fieldValue, found := cutUnescaped(value, ':'); found {
+		if field == "" {
+			return term, fmt.Errorf("missing field before :")
+		}
+		if _, known := fieldTypes[field]; !known {
+			return term, unknownFieldError(field)
+		}
+		term.Field, value = field, fieldValue
+	}
+	for _, operator := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(value, operator) {
+			term.Operator, value = operator, strings.TrimPrefix(value, operator)
+			break
+		}
+	}
+	if value == "" {
+		return term, fmt.Errorf("missing value")
+	}
+	if value[0] == '"' {
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return term, fmt.Errorf("invalid quoted value")
+		}
+		term.Value, term.Quoted = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`), true
+	} else {
+		term.Value = value
+	}
+
+	switch fieldType := fieldTypes[term.Field]; {
+	case term.Operator != "" && term.Field == "":
+		return term, fmt.Errorf("missing field before %s", term.Operator)
+	case fieldType == QueryFieldTypeKeyword && term.Operator != "":
+		return term, fmt.Errorf("%s can only be used with the date and number fields", term.Operator)
+	case fieldType == QueryFieldTypeDate && term.Operator == "":
+		return term, fmt.Errorf("the date fields can only be compared with >, >=, < or <= - or restricted with the from and to parameters")
+	case fieldType == QueryFieldTypeDate && !term.Quoted:
+		return term, fmt.Errorf("the dates must be quoted, such as %s:%s\"2021-06-01\"", term.Field, term.Operator)
+	case fieldType == QueryFieldTypeDate:
+		if _, err := parseQueryDate(term.Value); err != nil {
+			return term, fmt.Errorf("invalid date %q: the format is 2006-01-02 or 2006-01-02T15:04:05Z07:00", term.Value)
+		}
+	case fieldType == QueryFieldTypeNumber:
+		if _, err := strconv.ParseFloat(term.Value, 64); err != nil {
+			return term, fmt.Errorf("invalid number %q", term.Value)
+		}
+	}
+	return term, nil
+}
+
+// cutUnescaped is like strings.Cut, but ignores the escaped and quoted separators
+func cutUnescaped(s string, sep byte) (before, after string, found bool) {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+func parseQueryDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// Validate checks the query string of the jobs query
+func (q JobsQuery) Validate() error {
+	return validateQueryString(q.Query, JobQueryFields)
+}
+
+// Validate checks the query string of the events query
+func (q EventsQuery) Validate() error {
+	return validateQueryString(q.Query, EventQueryFields)
+}
+
+func validateQueryString(queryString string, fields []QueryField) error {
+	if _, err := ParseQueryString(queryString, fields); err != nil {
+		return err
+	}
+	// the full syntax - boosts, fuzziness, regular expressions, ... - is checked by Bleve
+	if _, err := query.NewQueryStringQuery(queryString).Parse(); err != nil {
+		return &QueryError{Query: queryString, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package webui
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQueryString(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expected      []QueryTerm
+		expectedError string
+	}{
+		{
+			name:     "empty",
+			query:    "",
+			expected: []QueryTerm{},
+		},
+		{
+			name:     "spaces only",
+			query:    "  \t ",
+			expected: []QueryTerm{},
+		},
+		{
+			name:     "value without field",
+			query:    "failure",
+			expected: []QueryTerm{{Value: "failure"}},
+		},
+		{
+			name:  "keyword fields with occurrences",
+			query: "State:failure +Type:postsubmit\t-Context:lint",
+			expected: []QueryTerm{
+				{Field: "State", Value: "failure"},
+				{Occur: '+', Field: "Type", Value: "postsubmit"},
+				{Occur: '-', Field: "Context", Value: "lint"},
+			},
+		},
+		{
+			name:     "quoted value with spaces",
+			query:    `Description:"tests failed"`,
+			expected: []QueryTerm{{Field: "Description", Value: "tests failed", Quoted: true}},
+		},
+		{
+			name:     "quoted value with escaped quotes",
+			query:    `Description:"the \"unit\" tests"`,
+			expected: []QueryTerm{{Field: "Description", Value: `the "unit" tests`, Quoted: true}},
+		},
+		{
+			name:     "escaped separator in the value",
+			query:    `Name:my\:job`,
+			expected: []QueryTerm{{Field: "Name", Value: `my\:job`}},
+		},
+		{
+			name:     "wildcard and fuzziness",
+			query:    "Branch:PR-* Author:jon~",
+			expected: []QueryTerm{{Field: "Branch", Value: "PR-*"}, {Field: "Author", Value: "jon~"}},
+		},
+		{
+			name:  "quoted dates",
+			query: `Start:>="2021-06-01" End:<"2021-06-02T10:00:00Z"`,
+			expected: []QueryTerm{
+				{Field: "Start", Operator: ">=", Value: "2021-06-01", Quoted: true},
+				{Field: "End", Operator: "<", Value: "2021-06-02T10:00:00Z", Quoted: true},
+			},
+		},
+		{
+			name:     "number",
+			query:    "Duration:>60",
+			expected: []QueryTerm{{Field: "Duration", Operator: ">", Value: "60"}},
+		},
+		{
+			name:          "unknown field",
+			query:         "Foo:bar",
+			expectedError: `unknown field "Foo" - the fields are Author, BaseSHA, Branch`,
+		},
+		{
+			name:          "wrong case field",
+			query:         "state:failure",
+			expectedError: `unknown field "state" - did you mean State?`,
+		},
+		{
+			name:          "missing field",
+			query:         ":failure",
+			expectedError: "missing field before :",
+		},
+		{
+			name:          "missing value",
+			query:         "State:",
+			expectedError: "missing value",
+		},
+		{
+			name:          "occurrence without value",
+			query:         "State:failure +",
+			expectedError: "missing value",
+		},
+		{
+			name:          "unquoted date",
+			query:         "Start:>2021-06-01",
+			expectedError: `the dates must be quoted, such as Start:>"2021-06-01"`,
+		},
+		{
+			name:          "date without operator",
+			query:         `Start:"2021-06-01"`,
+			expectedError: "the date fields can only be compared with >, >=, < or <=",
+		},
+		{
+			name:          "invalid date",
+			query:         `Start:>"01/06/2021"`,
+			expectedError: `invalid date "01/06/2021"`,
+		},
+		{
+			name:          "invalid number",
+			query:         "Duration:>a minute",
+			expectedError: `invalid number "a"`,
+		},
+		{
+			name:          "operator on a keyword field",
+			query:         "State:>failure",
+			expectedError: "> can only be used with the date and number fields",
+		},
+		{
+			name:          "operator without field",
+			query:         ">60",
+			expectedError: "missing field before >",
+		},
+		{
+			name:          "unbalanced quote",
+			query:         `Description:"tests failed`,
+			expectedError: "missing closing quote",
+		},
+		{
+			name:          "unbalanced quote in a later term",
+			query:         `State:failure "tests`,
+			expectedError: "missing closing quote",
+		},
+		{
+			name:          "unterminated quoted value",
+			query:         `Description:"tests"failed"`,
+			expectedError: "missing closing quote",
+		},
+		{
+			name:          "quote in the middle of the value",
+			query:         `Description:"tests"failed`,
+			expectedError: "invalid quoted value",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			terms, err := ParseQueryString(test.query, JobQueryFields)
+			if test.expectedError != "" {
+				var queryErr *QueryError
+				if !errors.As(err, &queryErr) {
+					t.Fatalf("expected a query error containing %q, got %v", test.expectedError, err)
+				}
+				if !strings.Contains(queryErr.Message, test.expectedError) {
+					t.Errorf("expected a query error containing %q, got %q", test.expectedError, queryErr.Message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(terms, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, terms)
+			}
+		})
+	}
+}
+
+func TestValidateQueryString(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		expectedErr bool
+	}{
+		{name: "valid jobs query", query: `+State:failure -Type:batch Start:>"2021-06-01"`},
+		{name: "unknown jobs field", query: "Kind:push", expectedErr: true},
+		{name: "invalid Bleve syntax", query: "State:failure^x", expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := JobsQuery{Query: test.query}.Validate()
+			if test.expectedErr && err == nil {
+				t.Fatal("expected an error")
+			}
+			if !test.expectedErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	if err := (EventsQuery{Query: "Kind:push"}).Validate(); err != nil {
+		t.Errorf("unexpected error for a valid events query: %v", err)
+	}
+}
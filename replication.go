@@ -0,0 +1,450 @@
+package webui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReplicatedHeader is set on the requests forwarded to the peers, so that they are not forwarded again
+const ReplicatedHeader = "X-Lighthouse-Webui-Replicated"
+
+// SavedSearchesReplicationPath is the path of the saved searches of all the users, served to the peers
+const SavedSearchesReplicationPath = "/replication/searches.ndjson"
+
+const (
+	replicationTimeout  = 10 * time.Second
+	replicationAttempts = 3
+	bootstrapTimeout    = 2 * time.Minute
+)
+
+// PeerResolver returns the base URLs of the other replicas - excluding the current one
+type PeerResolver interface {
+	Peers(ctx context.Context) ([]string, error)
+}
+
+// DNSPeerResolver finds the other replicas by resolving the DNS name of a (headless) Kubernetes service
+type DNSPeerResolver struct {
+	// Host is the DNS name which resolves to the IPs of all the replicas
+	Host string
+	Port int
+	// SelfIP is the IP of the current replica, which is excluded from the peers
+	SelfIP string
+	// Resolver defaults to net.DefaultResolver
+	Resolver *net.Resolver
+}
+
+func (r *DNSPeerResolver) Peers(ctx context.Context) ([]string, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupHost(ctx, r.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the peers from %s: %w", r.Host, err)
+	}
+	sort.Strings(ips)
+
+	var peers []string
+	for _, ip := range ips {
+		if ip == r.SelfIP {
+			continue
+		}
+		peers = append(peers, "http://"+net.JoinHostPort(ip, strconv.Itoa(r.Port)))
+	}
+	return peers, nil
+}
+
+// Replicator keeps the stores of multiple replicas consistent:
+// the webhooks and Keeper state received by a replica are forwarded to all the other replicas,
+// and a new replica loads the events, the merge state and the saved searches from one of the others.
+// The jobs don't need to be replicated, because each replica has its own informer.
+type Replicator struct {
+	Resolver   PeerResolver
+	Store      Store
+	HTTPClient *http.Client
+	Logger     *logrus.Logger
+
+	ready int32
+}
+
+// Ready returns true once the events have been loaded from the peers
+func (r *Replicator) Ready() bool {
+	return atomic.LoadInt32(&r.ready) == 1
+}
+
+// Handler wraps an ingestion handler - for the webhooks, the Keeper state or the saved searches -
+// to forward the requests it successfully handled to all the peers
+func (r *Replicator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.Header.Get(ReplicatedHeader) != "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read the request body: %s", err), http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+		// the saved searches redirect to their page once saved
+		if recorder.status >= http.StatusBadRequest {
+			return
+		}
+
+		go r.forward(req.URL.RequestURI(), req.Header.Clone(), body)
+	})
+}
+
+func (r *Replicator) forward(requestURI string, header http.Header, body []byte) {
+	log := r.Logger.WithField("requestURI", requestURI)
+
+	ctx, cancel := context.WithTimeout(context.Background(), replicationTimeout)
+	peers, err := r.Resolver.Peers(ctx)
+	cancel()
+	if err != nil {
+		log.WithError(err).Error("failed to find the peers to forward the request to")
+		return
+	}
+
+	header.Set(ReplicatedHeader, "true")
+	for _, peer := range peers {
+		var err error
+		for attempt := 1; attempt <= replicationAttempts; attempt++ {
+			if err = r.forwardTo(peer+requestURI, header, body); err == nil {
+				break
+			}
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		if err != nil {
+			log.WithField("peer", peer).WithError(err).Error("failed to forward the request to the peer")
+			continue
+		}
+		log.WithField("peer", peer).Trace("Forwarded request to the peer")
+	}
+}
+
+func (r *Replicator) forwardTo(url string, header http.Header, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), replicationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+
+	resp, err := r.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("got status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Bootstrap loads the events, the merge status, the merge history and the saved searches from the first peer which answers.
+// The replica is ready once it's done - even if it failed, so that a replica can always start.
+func (r *Replicator) Bootstrap(ctx context.Context) error {
+	defer atomic.StoreInt32(&r.ready, 1)
+
+	ctx, cancel := context.WithTimeout(ctx, bootstrapTimeout)
+	defer cancel()
+
+	peers, err := r.Resolver.Peers(ctx)
+	if err != nil {
+		return err
+	}
+	if len(peers) == 0 {
+		r.Logger.Info("No peers to load the events from")
+		return nil
+	}
+
+	for _, peer := range peers {
+		log := r.Logger.WithField("peer", peer)
+		if err := r.bootstrapFrom(ctx, peer); err != nil {
+			log.WithError(err).Warning("failed to load the events from the peer")
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to load the events from any of the %d peers", len(peers))
+}
+
+// bootstrapFrom loads all the events, merge pools, merge records and saved searches of a peer
+func (r *Replicator) bootstrapFrom(ctx context.Context, peer string) error {
+	events, err := r.bootstrapEvents(ctx, peer)
+	if err != nil {
+		return err
+	}
+	pools, err := r.bootstrapMergeStatus(ctx, peer)
+	if err != nil {
+		return err
+	}
+	records, err := r.bootstrapMergeHistory(ctx, peer)
+	if err != nil {
+		return err
+	}
+	searches, err := r.bootstrapSavedSearches(ctx, peer)
+	if err != nil {
+		return err
+	}
+	r.Logger.WithField("peer", peer).
+		WithField("events", events).
+		WithField("pools", pools).
+		WithField("records", records).
+		WithField("searches", searches).
+		Info("Loaded the events, the merge state and the saved searches from the peer")
+	return nil
+}
+
+// bootstrapEvents loads all the events of a peer, using its NDJSON export
+func (r *Replicator) bootstrapEvents(ctx context.Context, peer string) (int, error) {
+	var count int
+	err := r.getNDJSON(ctx, peer+"/events.ndjson", func(line []byte) error {
+		// the export has an empty string for the zero time
+		var exported struct {
+			Event
+			Time string
+		}
+		if err := json.Unmarshal(line, &exported); err != nil {
+			return fmt.Errorf("failed to decode event %d: %w", count+1, err)
+		}
+		event := exported.Event
+		var err error
+		if event.Time, err = parseExportedTime(exported.Time); err != nil {
+			return fmt.Errorf("invalid time for event %s: %w", event.GUID, err)
+		}
+		if err := r.Store.AddEvent(event); err != nil {
+			return fmt.Errorf("failed to store event %s: %w", event.GUID, err)
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// bootstrapMergeStatus loads the merge pools of a peer - for the sources (Keepers) we don't have yet,
+// which could have been pushed since the replica started
+func (r *Replicator) bootstrapMergeStatus(ctx context.Context, peer string) (int, error) {
+	resp, err := r.get(ctx, peer+"/merge/status.json")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var pools []MergePool
+	if err = json.NewDecoder(resp.Body).Decode(&pools); err != nil {
+		return 0, fmt.Errorf("failed to decode the merge status: %w", err)
+	}
+
+	var (
+		sources       []string
+		poolsBySource = map[string][]MergePool{}
+	)
+	for _, pool := range pools {
+		if _, found := poolsBySource[pool.Source]; !found {
+			sources = append(sources, pool.Source)
+		}
+		poolsBySource[pool.Source] = append(poolsBySource[pool.Source], pool)
+	}
+	var count int
+	for _, source := range sources {
+		if len(r.Store.QueryMergeStatus(MergeStatusQuery{Source: source})) > 0 {
+			continue
+		}
+		r.Store.SetMergeStatus(source, poolsBySource[source])
+		count += len(poolsBySource[source])
+	}
+	return count, nil
+}
+
+// bootstrapMergeHistory loads the merge records of a peer - for the sources (Keepers) we don't have yet.
+// It uses the JSON endpoint, which has the full records - with their Keeper record, and the records without PRs.
+func (r *Replicator) bootstrapMergeHistory(ctx context.Context, peer string) (int, error) {
+	resp, err := r.get(ctx, peer+"/merge/history.json")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var records []MergeRecord
+	if err = json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return 0, fmt.Errorf("failed to decode the merge history: %w", err)
+	}
+
+	var (
+		sources         []string
+		recordsBySource = map[string][]MergeRecord{}
+	)
+	for _, record := range records {
+		if _, found := recordsBySource[record.Source]; !found {
+			sources = append(sources, record.Source)
+		}
+		recordsBySource[record.Source] = append(recordsBySource[record.Source], record)
+	}
+	var count int
+	for _, source := range sources {
+		if len(r.Store.QueryMergeHistory(MergeHistoryQuery{Source: source})) > 0 {
+			continue
+		}
+		r.Store.SetMergeHistory(source, recordsBySource[source])
+		count += len(recordsBySource[source])
+	}
+	return count, nil
+}
+
+// bootstrapSavedSearches loads the searches saved by all the users on a peer - except the ones we already have,
+// which could have been saved since the replica started
+func (r *Replicator) bootstrapSavedSearches(ctx context.Context, peer string) (int, error) {
+	existing := map[string]bool{}
+	localSearches, err := r.Store.ExportSavedSearches()
+	if err != nil {
+		return 0, err
+	}
+	for _, search := range localSearches {
+		existing[search.User+"/"+search.Name] = true
+	}
+
+	var count int
+	err = r.getNDJSON(ctx, peer+SavedSearchesReplicationPath, func(line []byte) error {
+		var search SavedSearch
+		if err := json.Unmarshal(line, &search); err != nil {
+			return fmt.Errorf("failed to decode saved search %d: %w", count+1, err)
+		}
+		if existing[search.User+"/"+search.Name] {
+			return nil
+		}
+		if err := r.Store.SaveSearch(search); err != nil {
+			return fmt.Errorf("failed to store saved search %s: %w", search.Name, err)
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// SavedSearchesHandler serves the searches saved by all the users in NDJSON, to bootstrap the new replicas.
+// As they have the IDs of the users, they are only served to the peers.
+func (r *Replicator) SavedSearchesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !r.isPeer(req) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		searches, err := r.Store.ExportSavedSearches()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for _, search := range searches {
+			if err := enc.Encode(search); err != nil {
+				r.Logger.WithError(err).Error("failed to encode the saved searches for a peer")
+				return
+			}
+		}
+	})
+}
+
+// isPeer returns true if the request comes from the IP of one of the peers
+func (r *Replicator) isPeer(req *http.Request) bool {
+	remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	peers, err := r.Resolver.Peers(req.Context())
+	if err != nil {
+		r.Logger.WithError(err).Warning("failed to find the peers to check the request")
+		return false
+	}
+	for _, peer := range peers {
+		u, err := url.Parse(peer)
+		if err == nil && u.Hostname() == remoteIP {
+			return true
+		}
+	}
+	return false
+}
+
+// get sends a GET request to a peer, and returns its response if it is successful
+func (r *Replicator) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("got status code %d for %s", resp.StatusCode, url)
+	}
+	return resp, nil
+}
+
+// getNDJSON calls fn for each line of the NDJSON document returned by a peer
+func (r *Replicator) getNDJSON(ctx context.Context, url string, fn func(line []byte) error) error {
+	resp, err := r.get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseExportedTime parses the times of the exports - which have an empty string for the zero time
+func parseExportedTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func (r *Replicator) httpClient() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// statusRecorder records the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,430 @@
+package webui
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakePeerResolver struct {
+	peers []string
+	err   error
+}
+
+func (r *fakePeerResolver) Peers(ctx context.Context) ([]string, error) {
+	return r.peers, r.err
+}
+
+type forwardedRequest struct {
+	method     string
+	requestURI string
+	header     http.Header
+	body       string
+}
+
+// newFakePeer starts a peer which records the requests it receives,
+// and answers with an error for the first failures requests
+func newFakePeer(t *testing.T, failures int32) (*httptest.Server, <-chan forwardedRequest) {
+	t.Helper()
+	var (
+		requests = make(chan forwardedRequest, 10)
+		calls    int32
+	)
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests <- forwardedRequest{
+			method:     r.Method,
+			requestURI: r.URL.RequestURI(),
+			header:     r.Header.Clone(),
+			body:       string(body),
+		}
+	}))
+	t.Cleanup(peer.Close)
+	return peer, requests
+}
+
+func newTestReplicator(peers ...string) *Replicator {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return &Replicator{
+		Resolver: &fakePeerResolver{peers: peers},
+		Logger:   logger,
+	}
+}
+
+func statusHandler(status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(status)
+	})
+}
+
+func expectForwarded(t *testing.T, requests <-chan forwardedRequest, timeout time.Duration) forwardedRequest {
+	t.Helper()
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(timeout):
+		t.Fatal("the request was not forwarded to the peer")
+		return forwardedRequest{}
+	}
+}
+
+func expectNotForwarded(t *testing.T, requests <-chan forwardedRequest) {
+	t.Helper()
+	select {
+	case req := <-requests:
+		t.Fatalf("unexpected request forwarded to the peer: %s %s", req.method, req.requestURI)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestReplicatorHandlerForwardsSuccessfulPosts(t *testing.T) {
+	peer1, requests1 := newFakePeer(t, 0)
+	peer2, requests2 := newFakePeer(t, 0)
+	r := newTestReplicator(peer1.URL, peer2.URL)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/lighthouse/events?source=test", strings.NewReader(`{"key":"value"}`))
+	req.Header.Set("X-Lighthouse-Payload-Type", "push")
+	r.Handler(statusHandler(http.StatusOK)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	for _, requests := range []<-chan forwardedRequest{requests1, requests2} {
+		forwarded := expectForwarded(t, requests, 5*time.Second)
+		if forwarded.method != http.MethodPost {
+			t.Errorf("expected method %s, got %s", http.MethodPost, forwarded.method)
+		}
+		if forwarded.requestURI != "/lighthouse/events?source=test" {
+			t.Errorf("expected request URI /lighthouse/events?source=test, got %s", forwarded.requestURI)
+		}
+		if forwarded.body != `{"key":"value"}` {
+			t.Errorf("expected the original body, got %q", forwarded.body)
+		}
+		if forwarded.header.Get(ReplicatedHeader) == "" {
+			t.Errorf("expected the %s header to be set", ReplicatedHeader)
+		}
+		if forwarded.header.Get("X-Lighthouse-Payload-Type") != "push" {
+			t.Errorf("expected the original headers to be forwarded, got %v", forwarded.header)
+		}
+	}
+}
+
+func TestReplicatorHandlerForwardsRedirects(t *testing.T) {
+	peer, requests := newFakePeer(t, 0)
+	r := newTestReplicator(peer.URL)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/searches", strings.NewReader("name=test"))
+	r.Handler(statusHandler(http.StatusSeeOther)).ServeHTTP(rec, req)
+
+	expectForwarded(t, requests, 5*time.Second)
+}
+
+func TestReplicatorHandlerDoesNotForward(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		status     int
+		replicated bool
+	}{
+		{
+			name:   "failed request",
+			method: http.MethodPost,
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "invalid request",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "GET request",
+			method: http.MethodGet,
+			status: http.StatusOK,
+		},
+		{
+			name:       "replicated request",
+			method:     http.MethodPost,
+			status:     http.StatusOK,
+			replicated: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			peer, requests := newFakePeer(t, 0)
+			r := newTestReplicator(peer.URL)
+
+			var handled bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				handled = true
+				w.WriteHeader(test.status)
+			})
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, "/lighthouse/events", strings.NewReader("{}"))
+			if test.replicated {
+				req.Header.Set(ReplicatedHeader, "true")
+			}
+			r.Handler(next).ServeHTTP(rec, req)
+
+			if !handled {
+				t.Fatal("expected the request to be handled")
+			}
+			if rec.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, rec.Code)
+			}
+			expectNotForwarded(t, requests)
+		})
+	}
+}
+
+func TestReplicatorHandlerRetries(t *testing.T) {
+	peer, requests := newFakePeer(t, 1)
+	r := newTestReplicator(peer.URL)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/merge/status", strings.NewReader("[]"))
+	r.Handler(statusHandler(http.StatusOK)).ServeHTTP(rec, req)
+
+	forwarded := expectForwarded(t, requests, 5*time.Second)
+	if forwarded.body != "[]" {
+		t.Errorf("expected the original body on the retry, got %q", forwarded.body)
+	}
+}
+
+// newBootstrapPeer starts a peer which serves the exports used to bootstrap a replica
+func newBootstrapPeer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events.ndjson", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"GUID":"guid-1","Time":"2023-06-01T10:00:00Z","Owner":"owner","Repository":"repo","Branch":"main","Kind":"push","Sender":"alice","Labels":[],"SHA":"","BeforeSHA":"","CommitSHAs":[]}
+{"GUID":"guid-2","Time":"","Owner":"owner","Repository":"repo","Branch":"PR-1","Kind":"pull_request","Action":"opened","Sender":"bob","Labels":["bug"],"SHA":"","BeforeSHA":"","CommitSHAs":[]}
+`)
+	})
+	mux.HandleFunc("/merge/status.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `[{"Source":"keeper","Owner":"owner","Repository":"repo","Branch":"main","Action":"MERGE","SuccessPRs":[{"Number":1,"Author":"bob"}]}]`)
+	})
+	mux.HandleFunc("/merge/history.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `[{"Source":"keeper","Owner":"owner","Repository":"repo","Branch":"main","Time":"2023-06-01T11:00:00Z","Action":"MERGE_BATCH","BaseSHA":"abc","PRs":[{"Number":1,"Title":"first","Author":"bob","SHA":"def"},{"Number":2,"Title":"second","Author":"carol","SHA":"ghi"}],"KeeperRecord":{"action":"MERGE_BATCH","baseSHA":"abc"}},
+{"Source":"keeper","Owner":"owner","Repository":"repo","Branch":"main","Time":"2023-06-01T10:00:00Z","Action":"TRIGGER_BATCH","BaseSHA":"uvw","PRs":null,"KeeperRecord":{"action":"TRIGGER_BATCH","baseSHA":"uvw"}},
+{"Source":"keeper","Owner":"owner","Repository":"repo","Branch":"main","Time":"2023-06-01T09:00:00Z","Action":"MERGE","BaseSHA":"xyz","PRs":[{"Number":3,"Title":"third","Author":"alice","SHA":"jkl"}],"KeeperRecord":null}]`)
+	})
+	mux.HandleFunc(SavedSearchesReplicationPath, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"User":"alice","Name":"failures","Page":"jobs","Path":"owner","Query":"State:failure","From":"last 7d","Notify":true}
+{"User":"bob","Name":"pushes","Page":"events","Query":"Kind:push"}
+`)
+	})
+	peer := httptest.NewServer(mux)
+	t.Cleanup(peer.Close)
+	return peer
+}
+
+func newTestStore(t *testing.T) *BleveStore {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store, err := NewBleveStore(StoreConfig{}, logger)
+	if err != nil {
+		t.Fatalf("failed to create the store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestReplicatorBootstrap(t *testing.T) {
+	failingPeer := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(failingPeer.Close)
+	peer := newBootstrapPeer(t)
+
+	r := newTestReplicator(failingPeer.URL, peer.URL)
+	r.Store = newTestStore(t)
+	if r.Ready() {
+		t.Fatal("expected the replicator not to be ready before the bootstrap")
+	}
+	if err := r.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("failed to bootstrap: %v", err)
+	}
+	if !r.Ready() {
+		t.Error("expected the replicator to be ready after the bootstrap")
+	}
+
+	events, err := r.Store.QueryEvents(EventsQuery{})
+	if err != nil {
+		t.Fatalf("failed to query the events: %v", err)
+	}
+	if len(events.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events.Events))
+	}
+	for _, event := range events.Events {
+		switch event.GUID {
+		case "guid-1":
+			if !event.Time.Equal(time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)) {
+				t.Errorf("unexpected time for event guid-1: %s", event.Time)
+			}
+		case "guid-2":
+			if event.Sender != "bob" || len(event.Labels) != 1 {
+				t.Errorf("unexpected event guid-2: %+v", event)
+			}
+		default:
+			t.Errorf("unexpected event %s", event.GUID)
+		}
+	}
+
+	pools := r.Store.QueryMergeStatus(MergeStatusQuery{Source: "keeper"})
+	if len(pools) != 1 || len(pools[0].SuccessPRs) != 1 {
+		t.Errorf("expected 1 merge pool with 1 PR, got %+v", pools)
+	}
+
+	records := r.Store.QueryMergeHistory(MergeHistoryQuery{Source: "keeper"})
+	if len(records) != 3 {
+		t.Fatalf("expected 3 merge records, got %d", len(records))
+	}
+	for _, record := range records {
+		switch record.BaseSHA {
+		case "abc":
+			if len(record.PRs) != 2 || record.PRs[0].Number != 1 || record.PRs[1].Number != 2 {
+				t.Errorf("expected the PRs of the batch, got %+v", record.PRs)
+			}
+			if record.KeeperRecord == nil {
+				t.Error("expected the Keeper record of the batch")
+			}
+		case "uvw":
+			if len(record.PRs) != 0 || record.Action != "TRIGGER_BATCH" || record.KeeperRecord == nil {
+				t.Errorf("expected the record without PRs to be kept with its Keeper record, got %+v", record)
+			}
+		case "xyz":
+			if len(record.PRs) != 1 || record.PRs[0].SHA != "jkl" {
+				t.Errorf("unexpected PRs for the merge: %+v", record.PRs)
+			}
+		default:
+			t.Errorf("unexpected merge record %+v", record)
+		}
+	}
+
+	searches, err := r.Store.ExportSavedSearches()
+	if err != nil {
+		t.Fatalf("failed to export the saved searches: %v", err)
+	}
+	if len(searches) != 2 {
+		t.Fatalf("expected 2 saved searches, got %+v", searches)
+	}
+	if s := searches[0]; s.User != "alice" || s.Name != "failures" || s.Query != "State:failure" || s.From != "last 7d" || !s.Notify {
+		t.Errorf("unexpected saved search of alice: %+v", s)
+	}
+	if s := searches[1]; s.User != "bob" || s.Name != "pushes" || s.Page != SavedSearchPageEvents {
+		t.Errorf("unexpected saved search of bob: %+v", s)
+	}
+}
+
+func TestReplicatorBootstrapKeepsLocalMergeState(t *testing.T) {
+	peer := newBootstrapPeer(t)
+
+	r := newTestReplicator(peer.URL)
+	r.Store = newTestStore(t)
+	r.Store.SetMergeStatus("keeper", []MergePool{{Source: "keeper", Owner: "owner", Repository: "repo", Branch: "local"}})
+	r.Store.SetMergeHistory("keeper", []MergeRecord{{Source: "keeper", Owner: "owner", Repository: "repo", Branch: "local", Action: "MERGE"}})
+	if err := r.Store.SaveSearch(SavedSearch{User: "alice", Name: "failures", Page: SavedSearchPageJobs, Query: "State:error"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("failed to bootstrap: %v", err)
+	}
+
+	pools := r.Store.QueryMergeStatus(MergeStatusQuery{Source: "keeper"})
+	if len(pools) != 1 || pools[0].Branch != "local" {
+		t.Errorf("expected the local merge pool to be kept, got %+v", pools)
+	}
+	records := r.Store.QueryMergeHistory(MergeHistoryQuery{Source: "keeper"})
+	if len(records) != 1 || records[0].Branch != "local" {
+		t.Errorf("expected the local merge record to be kept, got %+v", records)
+	}
+	searches, err := r.Store.QuerySavedSearches("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searches) != 1 || searches[0].Query != "State:error" {
+		t.Errorf("expected the local saved search to be kept, got %+v", searches)
+	}
+}
+
+func TestReplicatorSavedSearchesHandler(t *testing.T) {
+	r := newTestReplicator("http://10.0.0.2:8080", "http://10.0.0.3:8080")
+	r.Store = newTestStore(t)
+	for _, search := range []SavedSearch{
+		{User: "bob", Name: "pushes", Page: SavedSearchPageEvents, Query: "Kind:push"},
+		{User: "alice", Name: "failures", Page: SavedSearchPageJobs, Query: "State:failure"},
+	} {
+		if err := r.Store.SaveSearch(search); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{remoteAddr: "10.0.0.3:51234", expectedStatus: http.StatusOK},
+		{remoteAddr: "10.0.0.4:51234", expectedStatus: http.StatusForbidden},
+		{remoteAddr: "not an address", expectedStatus: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, SavedSearchesReplicationPath, nil)
+		req.RemoteAddr = test.remoteAddr
+		w := httptest.NewRecorder()
+		r.SavedSearchesHandler().ServeHTTP(w, req)
+		if w.Code != test.expectedStatus {
+			t.Errorf("expected the status code %d for a request from %s, got %d", test.expectedStatus, test.remoteAddr, w.Code)
+			continue
+		}
+		if test.expectedStatus != http.StatusOK {
+			if strings.Contains(w.Body.String(), "alice") {
+				t.Errorf("expected the saved searches not to be served to %s", test.remoteAddr)
+			}
+			continue
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 2 || !strings.Contains(lines[0], `"User":"alice"`) || !strings.Contains(lines[1], `"User":"bob"`) {
+			t.Errorf("expected the saved searches sorted by user, got %s", w.Body.String())
+		}
+	}
+}
+
+func TestReplicatorBootstrapWithoutPeers(t *testing.T) {
+	r := newTestReplicator()
+	r.Store = newTestStore(t)
+	if err := r.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("expected no error without peers, got %v", err)
+	}
+	if !r.Ready() {
+		t.Error("expected the replicator to be ready")
+	}
+}
+
+func TestReplicatorBootstrapFailure(t *testing.T) {
+	failingPeer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(failingPeer.Close)
+
+	r := newTestReplicator(failingPeer.URL)
+	r.Store = newTestStore(t)
+	if err := r.Bootstrap(context.Background()); err == nil {
+		t.Fatal("expected an error when no peer answers")
+	}
+	if !r.Ready() {
+		t.Error("expected the replicator to be ready even if the bootstrap failed")
+	}
+}
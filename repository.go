@@ -0,0 +1,203 @@
+package webui
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HealthSuccess = "success"
+	HealthPending = "pending"
+	HealthFailure = "failure"
+	HealthUnknown = "unknown"
+)
+
+// RepositorySummary is used to list all the repositories known by the store
+type RepositorySummary struct {
+	Owner      string
+	Repository string
+	// Health is the health of the postsubmit jobs of all the branches
+	Health       string
+	LastActivity time.Time
+}
+
+// RepositoryOverview is everything we know about a repository
+type RepositoryOverview struct {
+	Owner        string
+	Repository   string
+	Health       string
+	RecentEvents []Event
+	Branches     []BranchHealth
+	OpenPRs      []RepositoryPullRequest
+	Pools        []MergePool
+	RecentMerges []MergeRecord
+}
+
+// BranchHealth is the latest postsubmit job of each context for a branch
+type BranchHealth struct {
+	Branch string
+	Health string
+	Jobs   []Job
+}
+
+// RepositoryPullRequest is a PR which is not closed yet, with the latest job of each context
+type RepositoryPullRequest struct {
+	Number    int
+	Author    string
+	Health    string
+	UpdatedAt time.Time
+	Jobs      []Job
+}
+
+// latestJobsByContext returns the most recent job of each context, sorted by context
+// jobs are expected to be sorted by most recent first, as returned by Store.QueryJobs
+func latestJobsByContext(jobs []Job) []Job {
+	var (
+		latestJobs []Job
+		contexts   = map[string]bool{}
+	)
+	for _, job := range jobs {
+		if contexts[job.Context] {
+			continue
+		}
+		contexts[job.Context] = true
+		latestJobs = append(latestJobs, job)
+	}
+	sort.SliceStable(latestJobs, func(i, j int) bool {
+		return latestJobs[i].Context < latestJobs[j].Context
+	})
+	return latestJobs
+}
+
+// jobsHealth returns the worst state of the given jobs
+func jobsHealth(jobs []Job) string {
+	health := HealthUnknown
+	for _, job := range jobs {
+		switch job.State {
+		case "failure", "error", "aborted":
+			return HealthFailure
+		case "triggered", "pending", "running":
+			health = HealthPending
+		case "success":
+			if health == HealthUnknown {
+				health = HealthSuccess
+			}
+		}
+	}
+	return health
+}
+
+// worstHealth returns the worst of the given healths
+func worstHealth(healths ...string) string {
+	health := HealthUnknown
+	for _, h := range healths {
+		switch h {
+		case HealthFailure:
+			return HealthFailure
+		case HealthPending:
+			health = HealthPending
+		case HealthSuccess:
+			if health == HealthUnknown {
+				health = HealthSuccess
+			}
+		}
+	}
+	return health
+}
+
+// branchesHealth groups the postsubmit jobs by branch
+func branchesHealth(jobs []Job) []BranchHealth {
+	var (
+		branches     []string
+		jobsByBranch = map[string][]Job{}
+	)
+	for _, job := range jobs {
+		if job.Type != "postsubmit" {
+			continue
+		}
+		if _, found := jobsByBranch[job.Branch]; !found {
+			branches = append(branches, job.Branch)
+		}
+		jobsByBranch[job.Branch] = append(jobsByBranch[job.Branch], job)
+	}
+	sort.Strings(branches)
+
+	var healths []BranchHealth
+	for _, branch := range branches {
+		latestJobs := latestJobsByContext(jobsByBranch[branch])
+		healths = append(healths, BranchHealth{
+			Branch: branch,
+			Health: jobsHealth(latestJobs),
+			Jobs:   latestJobs,
+		})
+	}
+	return healths
+}
+
+// openPullRequests groups the jobs by PR, ignoring the PRs which have been closed or merged
+func openPullRequests(jobs []Job, events []Event, records []MergeRecord) []RepositoryPullRequest {
+	closed := map[int]bool{}
+	for _, record := range records {
+		if record.Action != "MERGE" && record.Action != "MERGE_BATCH" {
+			continue
+		}
+		for _, pr := range record.PRs {
+			closed[pr.Number] = true
+		}
+	}
+	// events are sorted by most recent first: the first pull_request event is the current state of the PR
+	seen := map[int]bool{}
+	for _, event := range events {
+		if event.Kind != "pull_request" {
+			continue
+		}
+		number, err := strconv.Atoi(event.PullRequestNumber())
+		if err != nil || seen[number] {
+			continue
+		}
+		seen[number] = true
+		if event.Action == "closed" {
+			closed[number] = true
+		}
+	}
+
+	var (
+		numbers  []int
+		prsByNum = map[int]*RepositoryPullRequest{}
+		jobsByPR = map[int][]Job{}
+	)
+	for _, job := range jobs {
+		if !strings.HasPrefix(job.Branch, "PR-") {
+			continue
+		}
+		number, err := strconv.Atoi(job.PullRequestNumber())
+		if err != nil || closed[number] {
+			continue
+		}
+		pr, found := prsByNum[number]
+		if !found {
+			pr = &RepositoryPullRequest{
+				Number: number,
+				Author: job.Author,
+			}
+			prsByNum[number] = pr
+			numbers = append(numbers, number)
+		}
+		if job.Start.After(pr.UpdatedAt) {
+			pr.UpdatedAt = job.Start
+		}
+		jobsByPR[number] = append(jobsByPR[number], job)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(numbers)))
+
+	var prs []RepositoryPullRequest
+	for _, number := range numbers {
+		pr := prsByNum[number]
+		pr.Jobs = latestJobsByContext(jobsByPR[number])
+		pr.Health = jobsHealth(pr.Jobs)
+		prs = append(prs, *pr)
+	}
+	return prs
+}
@@ -0,0 +1,217 @@
+package webui
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// the pages which can be saved as searches
+const (
+	SavedSearchPageEvents = "events"
+	SavedSearchPageJobs   = "jobs"
+)
+
+// savedSearchNameRegExp validates the owner and repository of the path of a search - the rest of the path is the branch
+var savedSearchNameRegExp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// SavedSearch is a named search of the events or jobs page - such as State:failure Type:postsubmit on the jobs of an organization
+type SavedSearch struct {
+	// User is the user who saved the search - or empty for a global search, defined in the config
+	User string
+	Name string
+	// Page is either SavedSearchPageEvents or SavedSearchPageJobs
+	Page string
+	// Path is the optional owner, repository and branch of the page - such as my-org/my-repo or my-org/my-repo/feature/xyz
+	Path  string
+	Query string
+	// From and To are the raw values of the time range, such as "last 7d"
+	From string
+	To   string
+	// Notify enables the count of the new results since the search was last viewed
+	Notify     bool
+	LastViewed time.Time
+	Created    time.Time
+}
+
+// ParseSavedSearch returns a new search with the given name, from the URL of an events or jobs page - such as /jobs/my-org?q=State:failure
+func ParseSavedSearch(name, rawURL string) (*SavedSearch, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	page, path, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	s := SavedSearch{
+		Name:  strings.TrimSpace(name),
+		Page:  page,
+		Path:  path,
+		Query: u.Query().Get("q"),
+		From:  u.Query().Get("from"),
+		To:    u.Query().Get("to"),
+	}
+	if err = s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate ensures that the search can be safely stored, and rendered as a relative URL
+func (s SavedSearch) Validate() error {
+	if s.Name == "" || len(s.Name) > 100 || strings.ContainsAny(s.Name, "/?#") {
+		return fmt.Errorf("invalid search name %q: it must be between 1 and 100 characters, without / ? or #", s.Name)
+	}
+	switch s.Page {
+	case SavedSearchPageEvents, SavedSearchPageJobs:
+	default:
+		return fmt.Errorf("invalid search page %q: only the %s and %s pages can be saved", s.Page, SavedSearchPageEvents, SavedSearchPageJobs)
+	}
+	if _, _, _, _, _, err := s.queryParams(time.Time{}); err != nil {
+		return err
+	}
+	if s.Page == SavedSearchPageJobs {
+		if err := (JobsQuery{Query: s.Query}).Validate(); err != nil {
+			return err
+		}
+	} else if err := (EventsQuery{Query: s.Query}).Validate(); err != nil {
+		return err
+	}
+	if s.Path != "" && !validSavedSearchPath(s.Path) {
+		return fmt.Errorf("invalid search path %q: it must be owner[/repository[/branch]]", s.Path)
+	}
+	return nil
+}
+
+// validSavedSearchPath returns true if the owner and repository are valid names,
+// and if the branch - which can contain slashes - has no empty or relative segment, and no control character
+func validSavedSearchPath(path string) bool {
+	segments := strings.SplitN(path, "/", 3)
+	for i, segment := range segments {
+		if i < 2 {
+			if !savedSearchNameRegExp.MatchString(segment) || segment == "." || segment == ".." {
+				return false
+			}
+			continue
+		}
+		for _, branchSegment := range strings.Split(segment, "/") {
+			if branchSegment == "" || branchSegment == "." || branchSegment == ".." || strings.IndexFunc(branchSegment, unicode.IsControl) >= 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// URL returns the relative URL of the page of the search
+func (s SavedSearch) URL() string {
+	return s.urlWithExtension("")
+}
+
+// FeedURL returns the relative URL of the Atom feed of the search
+func (s SavedSearch) FeedURL() string {
+	return s.urlWithExtension(".atom")
+}
+
+func (s SavedSearch) urlWithExtension(extension string) string {
+	path := "/" + s.Page
+	if s.Path != "" {
+		segments := strings.Split(s.Path, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		path += "/" + strings.Join(segments, "/")
+	}
+	path += extension
+	params := url.Values{}
+	for name, value := range map[string]string{"q": s.Query, "from": s.From, "to": s.To} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	if len(params) == 0 {
+		return path
+	}
+	return path + "?" + params.Encode()
+}
+
+// queryParams returns the owner, repository, branch and time range of the search - with the time range starting at since, if later
+func (s SavedSearch) queryParams(since time.Time) (owner, repository, branch string, from, to time.Time, err error) {
+	segments := append(strings.SplitN(s.Path, "/", 3), "", "")
+	owner, repository, branch = segments[0], segments[1], segments[2]
+
+	now := time.Now()
+	if from, err = ParseTime(s.From, now); err != nil {
+		return owner, repository, branch, from, to, fmt.Errorf("invalid from time range of search %s: %w", s.Name, err)
+	}
+	if to, err = ParseTime(s.To, now); err != nil {
+		return owner, repository, branch, from, to, fmt.Errorf("invalid to time range of search %s: %w", s.Name, err)
+	}
+	if since.After(from) {
+		from = since
+	}
+	return owner, repository, branch, from, to, nil
+}
+
+// JobsQuery returns the query of the search on the jobs page - restricted to the jobs started since the given time, if not zero
+func (s SavedSearch) JobsQuery(since time.Time) (JobsQuery, error) {
+	owner, repository, branch, from, to, err := s.queryParams(since)
+	return JobsQuery{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     branch,
+		Query:      s.Query,
+		From:       from,
+		To:         to,
+	}, err
+}
+
+// EventsQuery returns the query of the search on the events page - restricted to the events received since the given time, if not zero
+func (s SavedSearch) EventsQuery(since time.Time) (EventsQuery, error) {
+	owner, repository, branch, from, to, err := s.queryParams(since)
+	return EventsQuery{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     branch,
+		Query:      s.Query,
+		From:       from,
+		To:         to,
+	}, err
+}
+
+// sortSavedSearches sorts the searches by name
+func sortSavedSearches(searches []SavedSearch) {
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].Name < searches[j].Name
+	})
+}
+
+// GlobalSavedSearches are the searches defined in the config - for all the users - which can be changed at runtime
+type GlobalSavedSearches struct {
+	value atomic.Value
+}
+
+func NewGlobalSavedSearches(searches []SavedSearch) *GlobalSavedSearches {
+	g := new(GlobalSavedSearches)
+	g.Set(searches)
+	return g
+}
+
+// Set replaces the global searches
+func (g *GlobalSavedSearches) Set(searches []SavedSearch) {
+	searches = append([]SavedSearch(nil), searches...)
+	sortSavedSearches(searches)
+	g.value.Store(searches)
+}
+
+// Get returns the global searches, sorted by name
+func (g *GlobalSavedSearches) Get() []SavedSearch {
+	if g == nil {
+		return nil
+	}
+	searches, _ := g.value.Load().([]SavedSearch)
+	return searches
+}
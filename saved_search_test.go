@@ -0,0 +1,100 @@
+package webui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSavedSearch(t *testing.T) {
+	tests := []struct {
+		name               string
+		url                string
+		expectedErr        bool
+		expectedOwner      string
+		expectedRepository string
+		expectedBranch     string
+		expectedURL        string
+	}{
+		{
+			name:        "all the jobs",
+			url:         "/jobs?q=State:failure",
+			expectedURL: "/jobs?q=State%3Afailure",
+		},
+		{
+			name:          "owner",
+			url:           "/events/my-org?from=last+7d",
+			expectedOwner: "my-org",
+			expectedURL:   "/events/my-org?from=last+7d",
+		},
+		{
+			name:               "branch",
+			url:                "/jobs/my-org/my-repo/main",
+			expectedOwner:      "my-org",
+			expectedRepository: "my-repo",
+			expectedBranch:     "main",
+			expectedURL:        "/jobs/my-org/my-repo/main",
+		},
+		{
+			name:               "branch with slashes",
+			url:                "/jobs/my-org/my-repo/feature/new-ui",
+			expectedOwner:      "my-org",
+			expectedRepository: "my-repo",
+			expectedBranch:     "feature/new-ui",
+			expectedURL:        "/jobs/my-org/my-repo/feature/new-ui",
+		},
+		{
+			name:               "branch with special characters",
+			url:                "/jobs/my-org/my-repo/fix/%23123%3Fa%20b",
+			expectedOwner:      "my-org",
+			expectedRepository: "my-repo",
+			expectedBranch:     "fix/#123?a b",
+			expectedURL:        "/jobs/my-org/my-repo/fix/%23123%3Fa%20b",
+		},
+		{name: "unknown page", url: "/merge/history/my-org", expectedErr: true},
+		{name: "invalid owner", url: "/jobs/my%20org", expectedErr: true},
+		{name: "relative owner", url: "/jobs/../admin", expectedErr: true},
+		{name: "invalid repository", url: "/jobs/my-org/my%3Frepo/main", expectedErr: true},
+		{name: "empty repository", url: "/jobs/my-org//main", expectedErr: true},
+		{name: "relative branch segment", url: "/jobs/my-org/my-repo/feature/../main", expectedErr: true},
+		{name: "empty branch segment", url: "/jobs/my-org/my-repo/feature//x", expectedErr: true},
+		{name: "control character in the branch", url: "/jobs/my-org/my-repo/fix%0A", expectedErr: true},
+		{name: "invalid query", url: "/jobs/my-org?q=state:failure", expectedErr: true},
+		{name: "invalid time range", url: "/events?from=yesterday", expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			search, err := ParseSavedSearch("my search", test.url)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", search)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			q, err := search.JobsQuery(time.Time{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.Owner != test.expectedOwner || q.Repository != test.expectedRepository || q.Branch != test.expectedBranch {
+				t.Errorf("expected %s/%s/%s, got %s/%s/%s", test.expectedOwner, test.expectedRepository, test.expectedBranch, q.Owner, q.Repository, q.Branch)
+			}
+			if actual := search.URL(); actual != test.expectedURL {
+				t.Errorf("expected the URL %s, got %s", test.expectedURL, actual)
+			}
+		})
+	}
+}
+
+func TestSavedSearchValidateName(t *testing.T) {
+	for _, name := range []string{"", "a/b", "what?", "#1", strings.Repeat("a", 101)} {
+		search := SavedSearch{Name: name, Page: SavedSearchPageJobs}
+		if err := search.Validate(); err == nil {
+			t.Errorf("expected an error for the name %q", name)
+		}
+	}
+}
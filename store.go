@@ -1,353 +1,611 @@
 package webui
 
 import (
-	"errors"
 	"fmt"
-	"path/filepath"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/blevesearch/bleve"
-	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
-	"github.com/blevesearch/bleve/search"
-	"github.com/blevesearch/bleve/search/query"
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	// eventsIndexMappingVersion is the version of the events index mapping
-	// if you change something in the mapping, increment this version
-	// this is used to ensure the index will be recreated if we change the mapping
-	eventsIndexMappingVersion = 1
+	// StoreBackendBleve stores the events and jobs in Bleve indexes, and the merge status/history in memory
+	StoreBackendBleve = "bleve"
+	// StoreBackendSQLite stores everything in an embedded SQLite database, which can be queried with SQL
+	StoreBackendSQLite = "sqlite"
 )
 
-type Store struct {
-	config            StoreConfig
-	gcStopChan        chan struct{}
-	events            bleve.Index
-	jobs              bleve.Index
-	mergeStatus       []MergePool
-	mergeStatusMutex  sync.RWMutex
-	mergeHistory      []MergeRecord
-	mergeHistoryMutex sync.RWMutex
+// Store is where we keep the events, jobs, merge status and merge history
+type Store interface {
+	AddJob(j Job) error
+	DeleteJob(name string) error
+	QueryJobs(q JobsQuery) (*Jobs, error)
+	// CountJobs returns the number of jobs matching the query - without the limit of QueryJobs
+	CountJobs(q JobsQuery) (int, error)
+	// ExportJobs calls fn for each job matching the query, most recent first, without any limit on the number of jobs
+	ExportJobs(q JobsQuery, fn func(Job) error) error
+	// QueryJobFieldValues returns the most frequent values of a keyword field of the jobs, starting with the given prefix
+	QueryJobFieldValues(field, prefix string, limit int) ([]FieldValue, error)
+
+	AddEvent(e Event) error
+	QueryEvents(q EventsQuery) (*Events, error)
+	// CountEvents returns the number of events matching the query - without the limit of QueryEvents
+	CountEvents(q EventsQuery) (int, error)
+	// ExportEvents calls fn for each event matching the query, most recent first, without any limit on the number of events
+	ExportEvents(q EventsQuery, fn func(Event) error) error
+	// QueryEventFieldValues returns the most frequent values of a keyword field of the events, starting with the given prefix
+	QueryEventFieldValues(field, prefix string, limit int) ([]FieldValue, error)
+
+	// SetMergeStatus replaces the merge pools of the given source (Keeper)
+	// the pools from the other sources are left untouched
+	SetMergeStatus(source string, pools []MergePool)
+	QueryMergeStatus(q MergeStatusQuery) []MergePool
+
+	// SetMergeHistory replaces the merge records of the given source (Keeper)
+	// the records from the other sources are left untouched
+	SetMergeHistory(source string, records []MergeRecord)
+	QueryMergeHistory(q MergeHistoryQuery) []MergeRecord
+
+	// SaveSearch adds or replaces the search with the same user and name
+	SaveSearch(s SavedSearch) error
+	DeleteSavedSearch(user, name string) error
+	// QuerySavedSearches returns the searches saved by the given user, sorted by name
+	QuerySavedSearches(user string) ([]SavedSearch, error)
+	// ExportSavedSearches returns the searches saved by all the users, sorted by user and name
+	ExportSavedSearches() ([]SavedSearch, error)
+
+	// SetGarbageCollectionLimits changes the limits enforced by the garbage collector - from the next collection
+	SetGarbageCollectionLimits(maxEvents int, eventsMaxAge time.Duration)
+
+	Close() error
 }
 
-type StoreConfig struct {
-	DataPath     string
-	MaxEvents    int
-	EventsMaxAge time.Duration
+func NewStore(cfg StoreConfig, logger *logrus.Logger) (Store, error) {
+	switch cfg.Backend {
+	case "", StoreBackendBleve:
+		return NewBleveStore(cfg, logger)
+	case StoreBackendSQLite:
+		return NewSQLStore(cfg, logger)
+	default:
+		return nil, fmt.Errorf("unknown store backend %q - valid values are %s and %s", cfg.Backend, StoreBackendBleve, StoreBackendSQLite)
+	}
 }
 
-func NewStore(cfg StoreConfig, logger *logrus.Logger) (*Store, error) {
-	var (
-		store = new(Store)
-		err   error
-	)
-
-	keywordFieldMapping := bleve.NewTextFieldMapping()
-	keywordFieldMapping.Analyzer = keyword.Name
-
-	jobsMapping := bleve.NewIndexMapping()
-	jobsMapping.DefaultAnalyzer = keyword.Name
-	jobsMapping.DefaultMapping.AddFieldMappingsAt("Start", bleve.NewDateTimeFieldMapping())
-	jobsMapping.DefaultMapping.AddFieldMappingsAt("End", bleve.NewDateTimeFieldMapping())
-
-	eventsMapping := bleve.NewIndexMapping()
-	eventsMapping.DefaultAnalyzer = keyword.Name
-	eventsMapping.DefaultMapping.AddFieldMappingsAt("Time", bleve.NewDateTimeFieldMapping())
-
-	store.jobs, err = bleve.NewMemOnly(jobsMapping)
-	if err != nil {
-		return nil, fmt.Errorf("failed to created a Bleve in-memory Index: %w", err)
+// mergePoolsWithPreviousState sets the source of the new pools,
+// and keeps the time since when the pools are blocked or in error from the previous pools
+func mergePoolsWithPreviousState(source string, previous, pools []MergePool) []MergePool {
+	previousPools := make(map[string]MergePool, len(previous))
+	for _, pool := range previous {
+		previousPools[pool.Key()] = pool
 	}
 
-	if cfg.DataPath == "" {
-		store.events, err = bleve.NewMemOnly(eventsMapping)
-		if err != nil {
-			return nil, fmt.Errorf("failed to created a Bleve in-memory Index: %w", err)
-		}
-	} else {
-		eventsDataPath := filepath.Join(cfg.DataPath, fmt.Sprintf("events-v%d", eventsIndexMappingVersion))
-		store.events, err = bleve.Open(eventsDataPath)
-		if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
-			store.events, err = bleve.New(eventsDataPath, eventsMapping)
-		} else if err != nil {
-			if logger != nil {
-				logger.WithError(err).WithField("index-path", eventsDataPath).Warning("failed to open existing Bleve index - a new (empty) index will be created...")
+	now := time.Now()
+	newPools := make([]MergePool, 0, len(pools))
+	for _, pool := range pools {
+		pool.Source = source
+		previousPool, found := previousPools[pool.Key()]
+		if len(pool.Blockers) > 0 {
+			pool.BlockedSince = now
+			if found && len(previousPool.Blockers) > 0 && !previousPool.BlockedSince.IsZero() {
+				pool.BlockedSince = previousPool.BlockedSince
 			}
-			store.events, err = bleve.New(eventsDataPath, eventsMapping)
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to created a Bleve Index at %s: %w", eventsDataPath, err)
 		}
-	}
-
-	store.config = cfg
-	store.gcStopChan = make(chan struct{})
-
-	ticker := time.NewTicker(1 * time.Minute)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				store.CollectGarbage()
-			case <-store.gcStopChan:
-				logger.Info("Store GarbageCollector exiting...")
-				return
+		if pool.Error != "" {
+			pool.ErrorSince = now
+			if found && previousPool.Error == pool.Error && !previousPool.ErrorSince.IsZero() {
+				pool.ErrorSince = previousPool.ErrorSince
 			}
 		}
-	}()
-
-	return store, nil
+		newPools = append(newPools, pool)
+	}
+	return newPools
 }
 
-func (s *Store) Close() error {
-	close(s.gcStopChan)
-	return s.events.Close()
+func (q MergeStatusQuery) Matches(pool MergePool) bool {
+	if q.Source != "" && q.Source != pool.Source {
+		return false
+	}
+	if q.Owner != "" && q.Owner != pool.Owner {
+		return false
+	}
+	if q.Repository != "" && q.Repository != pool.Repository {
+		return false
+	}
+	if q.Branch != "" && q.Branch != pool.Branch {
+		return false
+	}
+	if q.Blocked && len(pool.Blockers) == 0 && pool.Error == "" {
+		return false
+	}
+	return true
 }
 
-func (s *Store) SetMergeStatus(pools []MergePool) {
-	s.mergeStatusMutex.Lock()
-	defer s.mergeStatusMutex.Unlock()
-	s.mergeStatus = make([]MergePool, len(pools))
-	copy(s.mergeStatus, pools)
+func (q MergeHistoryQuery) Matches(record MergeRecord) bool {
+	if q.Source != "" && q.Source != record.Source {
+		return false
+	}
+	if q.Owner != "" && q.Owner != record.Owner {
+		return false
+	}
+	if q.Repository != "" && q.Repository != record.Repository {
+		return false
+	}
+	if q.Branch != "" && q.Branch != record.Branch {
+		return false
+	}
+	if !q.From.IsZero() && record.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !record.Time.Before(q.To) {
+		return false
+	}
+	if q.SHA != "" && !record.HasSHA(q.SHA) {
+		return false
+	}
+	return true
 }
 
-func (s *Store) QueryMergeStatus(q MergeStatusQuery) []MergePool {
-	s.mergeStatusMutex.RLock()
-	defer s.mergeStatusMutex.RUnlock()
-
-	var pools []MergePool
-	for _, pool := range s.mergeStatus {
-		if q.Owner != "" && q.Owner != pool.Owner {
-			continue
-		}
-		if q.Repository != "" && q.Repository != pool.Repository {
-			continue
+func QueryBlockingReasons(s Store, q BlockingReasonsQuery) ([]BlockingReason, error) {
+	var (
+		pool  MergePool
+		pr    PullRequest
+		found bool
+	)
+	for _, p := range s.QueryMergeStatus(MergeStatusQuery{Owner: q.Owner, Repository: q.Repository}) {
+		for _, missingPR := range p.MissingPRs {
+			if missingPR.Number == q.Number {
+				pool, pr, found = p, missingPR, true
+				break
+			}
 		}
-		if q.Branch != "" && q.Branch != pool.Branch {
-			continue
+		if found {
+			break
 		}
-		pools = append(pools, pool)
 	}
-	return pools
-}
+	if !found {
+		return nil, nil
+	}
 
-func (s *Store) SetMergeHistory(records []MergeRecord) {
-	s.mergeHistoryMutex.Lock()
-	defer s.mergeHistoryMutex.Unlock()
-	s.mergeHistory = make([]MergeRecord, len(records))
-	copy(s.mergeHistory, records)
-}
+	branch := fmt.Sprintf("PR-%d", q.Number)
+	jobs, err := s.QueryJobs(JobsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		Branch:     branch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.QueryEvents(EventsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		Branch:     branch,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-func (s *Store) QueryMergeHistory(q MergeHistoryQuery) []MergeRecord {
-	s.mergeHistoryMutex.RLock()
-	defer s.mergeHistoryMutex.RUnlock()
+	return BlockingReasonsForPullRequest(pool, pr, jobs.Jobs, events.Events), nil
+}
 
-	var records []MergeRecord
-	for _, record := range s.mergeHistory {
-		if q.Owner != "" && q.Owner != record.Owner {
+// QueryPoolsBlockingReasons returns the blocking reasons of the missing PRs of the given pools, by repository (owner/repository) and PR number.
+// The jobs and events are exported once per repository, instead of queried once per PR - and without the limit of the queries,
+// so that the oldest PRs of a busy repository still get their jobs and events
+func QueryPoolsBlockingReasons(s Store, pools []MergePool) (map[string]map[int][]BlockingReason, error) {
+	var (
+		reasons         = map[string]map[int][]BlockingReason{}
+		missingBranches = map[string]map[string]bool{}
+		jobsByBranch    = map[string]map[string][]Job{}
+		eventsByBranch  = map[string]map[string][]Event{}
+		// repositories has the first pool of each repository, for its owner and name
+		repositories []MergePool
+	)
+	for _, pool := range pools {
+		if len(pool.MissingPRs) == 0 {
 			continue
 		}
-		if q.Repository != "" && q.Repository != record.Repository {
-			continue
+		repository := pool.Owner + "/" + pool.Repository
+		if _, found := missingBranches[repository]; !found {
+			missingBranches[repository] = map[string]bool{}
+			repositories = append(repositories, pool)
 		}
-		if q.Branch != "" && q.Branch != record.Branch {
-			continue
+		for _, pr := range pool.MissingPRs {
+			missingBranches[repository][fmt.Sprintf("PR-%d", pr.Number)] = true
 		}
-		records = append(records, record)
 	}
-	return records
-}
 
-func (s *Store) AddJob(j Job) error {
-	return s.jobs.Index(j.Name, j)
-}
+	for _, pool := range repositories {
+		var (
+			repository = pool.Owner + "/" + pool.Repository
+			branches   = missingBranches[repository]
+		)
+		jobsByBranch[repository] = map[string][]Job{}
+		err := s.ExportJobs(JobsQuery{Owner: pool.Owner, Repository: pool.Repository}, func(job Job) error {
+			if branches[job.Branch] {
+				jobsByBranch[repository][job.Branch] = append(jobsByBranch[repository][job.Branch], job)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
 
-func (s *Store) DeleteJob(name string) error {
-	return s.jobs.Delete(name)
-}
+		eventsByBranch[repository] = map[string][]Event{}
+		err = s.ExportEvents(EventsQuery{Owner: pool.Owner, Repository: pool.Repository}, func(event Event) error {
+			if branches[event.Branch] {
+				eventsByBranch[repository][event.Branch] = append(eventsByBranch[repository][event.Branch], event)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		reasons[repository] = map[int][]BlockingReason{}
+	}
 
-func (s *Store) AddEvent(e Event) error {
-	return s.events.Index(e.GUID, e)
+	for _, pool := range pools {
+		repository := pool.Owner + "/" + pool.Repository
+		for _, pr := range pool.MissingPRs {
+			branch := fmt.Sprintf("PR-%d", pr.Number)
+			reasons[repository][pr.Number] = BlockingReasonsForPullRequest(pool, pr, jobsByBranch[repository][branch], eventsByBranch[repository][branch])
+		}
+	}
+	return reasons, nil
 }
 
-func (s *Store) QueryJobs(q JobsQuery) (*Jobs, error) {
-	request := bleve.NewSearchRequest(q.ToBleveQuery())
-	request.SortBy([]string{"-Start"})
-	request.Size = 10000
-	request.Fields = []string{"*"}
-	request.AddFacet("State", bleve.NewFacetRequest("State", 4))
-	request.AddFacet("Repository", bleve.NewFacetRequest("Repository", 3))
-	request.AddFacet("Type", bleve.NewFacetRequest("Type", 3))
-	request.AddFacet("Author", bleve.NewFacetRequest("Author", 3))
-	result, err := s.jobs.Search(request)
+func QueryUserActivity(s Store, q UserActivityQuery) (*UserActivity, error) {
+	activity := UserActivity{
+		Login: q.Login,
+	}
+
+	events, err := s.QueryEvents(EventsQuery{
+		Sender: q.Login,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to search for %v: %w", q, err)
+		return nil, err
 	}
+	activity.Events = events.Events
 
-	jobs := bleveResultToJobs(result)
-	return &jobs, nil
-}
+	jobs, err := s.QueryJobs(JobsQuery{
+		Author: q.Login,
+	})
+	if err != nil {
+		return nil, err
+	}
+	activity.Jobs = jobs.Jobs
 
-func (s *Store) QueryEvents(q EventsQuery) (*Events, error) {
-	request := bleve.NewSearchRequest(q.ToBleveQuery())
-	request.SortBy([]string{"-Time"})
-	request.Size = 10000
-	request.Fields = []string{"*"}
-	request.AddFacet("Kind", bleve.NewFacetRequest("Kind", 4))
-	request.AddFacet("Repository", bleve.NewFacetRequest("Repository", 3))
-	request.AddFacet("Sender", bleve.NewFacetRequest("Sender", 3))
-	result, err := s.events.Search(request)
+	activity.PoolPRs = userPoolPullRequests(q.Login, s.QueryMergeStatus(MergeStatusQuery{}))
+
+	activity.MergedPRs = userMergedPullRequests(q.Login, s.QueryMergeHistory(MergeHistoryQuery{}))
+	eventsByBranch, err := exportPullRequestsEvents(s, activity.MergedPRs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search for %v: %w", q, err)
+		return nil, err
+	}
+	for i, pr := range activity.MergedPRs {
+		activity.MergedPRs[i].ApprovedAt = approvalTime(eventsByBranch[pr.Owner+"/"+pr.Repository][fmt.Sprintf("PR-%d", pr.Number)])
 	}
 
-	events := bleveResultToEvents(result)
-	return &events, nil
+	activity.Stats = computeUserStats(activity.Jobs, activity.MergedPRs)
+	return &activity, nil
 }
 
-func (s *Store) CollectGarbage() error {
-	var deleteMatchingEvents = func(req *bleve.SearchRequest) error {
-		result, err := s.events.Search(req)
+// exportPullRequestsEvents returns the events of the given PRs, by repository (owner/repository) and branch (PR-N).
+// The events are exported once per repository, instead of queried once per PR
+func exportPullRequestsEvents(s Store, prs []UserMergedPullRequest) (map[string]map[string][]Event, error) {
+	var (
+		eventsByBranch = map[string]map[string][]Event{}
+		branches       = map[string]map[string]bool{}
+		// repositories has the first PR of each repository, for its owner and name
+		repositories []UserMergedPullRequest
+	)
+	for _, pr := range prs {
+		repository := pr.Owner + "/" + pr.Repository
+		if _, found := branches[repository]; !found {
+			branches[repository] = map[string]bool{}
+			repositories = append(repositories, pr)
+		}
+		branches[repository][fmt.Sprintf("PR-%d", pr.Number)] = true
+	}
+
+	for _, pr := range repositories {
+		repository := pr.Owner + "/" + pr.Repository
+		eventsByBranch[repository] = map[string][]Event{}
+		err := s.ExportEvents(EventsQuery{Owner: pr.Owner, Repository: pr.Repository}, func(event Event) error {
+			if branches[repository][event.Branch] {
+				eventsByBranch[repository][event.Branch] = append(eventsByBranch[repository][event.Branch], event)
+			}
+			return nil
+		})
 		if err != nil {
-			return err
+			return nil, err
 		}
-		for _, doc := range result.Hits {
-			if err = s.events.Delete(doc.ID); err != nil {
-				return err
+	}
+	return eventsByBranch, nil
+}
+
+// QueryCommit returns the events, jobs and merge records of a commit
+func QueryCommit(s Store, q CommitQuery) (*CommitActivity, error) {
+	activity := CommitActivity{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		SHA:        q.SHA,
+	}
+
+	events, err := s.QueryEvents(EventsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		SHA:        q.SHA,
+	})
+	if err != nil {
+		return nil, err
+	}
+	activity.Events = events.Events
+
+	jobs, err := s.QueryJobs(JobsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		SHA:        q.SHA,
+	})
+	if err != nil {
+		return nil, err
+	}
+	activity.Jobs = jobs.Jobs
+
+	activity.Merges = s.QueryMergeHistory(MergeHistoryQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		SHA:        q.SHA,
+	})
+	activity.ProducedBy = producedByMerges(q.SHA, activity.Events, s.QueryMergeHistory(MergeHistoryQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+	}))
+
+	return &activity, nil
+}
+
+// QueryRepositories lists all the repositories for which we have events, jobs or merge pools/records
+func QueryRepositories(s Store, q RepositoriesQuery) ([]RepositorySummary, error) {
+	var (
+		keys      []string
+		summaries = map[string]*RepositorySummary{}
+		jobsByKey = map[string][]Job{}
+	)
+	var summaryFor = func(owner, repository string, activity time.Time) *RepositorySummary {
+		key := owner + "/" + repository
+		summary, found := summaries[key]
+		if !found {
+			summary = &RepositorySummary{
+				Owner:      owner,
+				Repository: repository,
 			}
+			summaries[key] = summary
+			keys = append(keys, key)
 		}
-		return nil
-	}
-	if s.config.MaxEvents > 0 {
-		request := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
-		request.SortBy([]string{"-Time"})
-		request.Size = 1000
-		request.From = s.config.MaxEvents
-		if err := deleteMatchingEvents(request); err != nil {
-			return err
+		if activity.After(summary.LastActivity) {
+			summary.LastActivity = activity
 		}
+		return summary
+	}
+
+	jobs, err := s.QueryJobs(JobsQuery{Owner: q.Owner})
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs.Jobs {
+		summaryFor(job.Owner, job.Repository, job.Start)
+		jobsByKey[job.Owner+"/"+job.Repository] = append(jobsByKey[job.Owner+"/"+job.Repository], job)
 	}
-	if s.config.EventsMaxAge > 0 {
-		request := bleve.NewSearchRequest(bleve.NewDateRangeQuery(time.Time{}, time.Now().Add(-s.config.EventsMaxAge)))
-		request.Size = 1000
-		if err := deleteMatchingEvents(request); err != nil {
-			return err
+	events, err := s.QueryEvents(EventsQuery{Owner: q.Owner})
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events.Events {
+		if event.Owner == "" || event.Repository == "" {
+			continue
 		}
+		summaryFor(event.Owner, event.Repository, event.Time)
+	}
+	for _, pool := range s.QueryMergeStatus(MergeStatusQuery{Owner: q.Owner}) {
+		summaryFor(pool.Owner, pool.Repository, pool.UpdatedAt)
+	}
+	for _, record := range s.QueryMergeHistory(MergeHistoryQuery{Owner: q.Owner}) {
+		summaryFor(record.Owner, record.Repository, record.Time)
 	}
-	return nil
-}
 
-type JobsQuery struct {
-	EventGUID  string
-	Owner      string
-	Repository string
-	Branch     string
-	Query      string
+	sort.Strings(keys)
+	repositories := make([]RepositorySummary, 0, len(keys))
+	for _, key := range keys {
+		summary := summaries[key]
+		var healths []string
+		for _, branch := range branchesHealth(jobsByKey[key]) {
+			healths = append(healths, branch.Health)
+		}
+		summary.Health = worstHealth(healths...)
+		repositories = append(repositories, *summary)
+	}
+	return repositories, nil
 }
 
-func (q JobsQuery) ToBleveQuery() query.Query {
-	var queryString strings.Builder
-	if len(q.Query) > 0 {
-		queryString.WriteString("+")
-		queryString.WriteString(q.Query)
+// QueryRepositoryOverview returns a summary of the recent activity of a repository
+func QueryRepositoryOverview(s Store, q RepositoryOverviewQuery) (*RepositoryOverview, error) {
+	overview := RepositoryOverview{
+		Owner:      q.Owner,
+		Repository: q.Repository,
 	}
-	if len(q.EventGUID) > 0 {
-		if queryString.Len() > 0 {
-			queryString.WriteString(" ")
-		}
-		queryString.WriteString("+EventGUID:")
-		queryString.WriteString(q.EventGUID)
+
+	events, err := s.QueryEvents(EventsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+	})
+	if err != nil {
+		return nil, err
 	}
-	if len(q.Owner) > 0 {
-		if queryString.Len() > 0 {
-			queryString.WriteString(" ")
-		}
-		queryString.WriteString("+Owner:")
-		queryString.WriteString(q.Owner)
+	overview.RecentEvents = events.Events
+	if q.MaxItems > 0 && len(overview.RecentEvents) > q.MaxItems {
+		overview.RecentEvents = overview.RecentEvents[:q.MaxItems]
 	}
-	if len(q.Repository) > 0 {
-		if queryString.Len() > 0 {
-			queryString.WriteString(" ")
-		}
-		queryString.WriteString("+Repository:")
-		queryString.WriteString(q.Repository)
+
+	jobs, err := s.QueryJobs(JobsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+	})
+	if err != nil {
+		return nil, err
+	}
+	overview.Branches = branchesHealth(jobs.Jobs)
+	var healths []string
+	for _, branch := range overview.Branches {
+		healths = append(healths, branch.Health)
 	}
-	if len(q.Branch) > 0 {
-		if queryString.Len() > 0 {
-			queryString.WriteString(" ")
+	overview.Health = worstHealth(healths...)
+
+	overview.Pools = s.QueryMergeStatus(MergeStatusQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+	})
+
+	records := s.QueryMergeHistory(MergeHistoryQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+	})
+	overview.OpenPRs = openPullRequests(jobs.Jobs, events.Events, records)
+
+	for _, record := range records {
+		if record.Action == "MERGE" || record.Action == "MERGE_BATCH" {
+			overview.RecentMerges = append(overview.RecentMerges, record)
 		}
-		queryString.WriteString("+Branch:")
-		queryString.WriteString(q.Branch)
 	}
-	if queryString.Len() == 0 {
-		return bleve.NewMatchAllQuery()
+	sort.SliceStable(overview.RecentMerges, func(i, j int) bool {
+		return overview.RecentMerges[i].Time.After(overview.RecentMerges[j].Time)
+	})
+	if q.MaxItems > 0 && len(overview.RecentMerges) > q.MaxItems {
+		overview.RecentMerges = overview.RecentMerges[:q.MaxItems]
 	}
-	return bleve.NewQueryStringQuery(queryString.String())
-}
 
-func bleveResultToJobs(result *bleve.SearchResult) Jobs {
-	var jobs Jobs
+	return &overview, nil
+}
 
-	for _, doc := range result.Hits {
-		job := bleveDocToJob(doc)
-		jobs.Jobs = append(jobs.Jobs, job)
+// QueryBranchHealth returns the health of the latest postsubmit jobs of a branch
+func QueryBranchHealth(s Store, q BranchHealthQuery) (*BranchHealth, error) {
+	jobs, err := s.QueryJobs(JobsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		Branch:     q.Branch,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	for _, facet := range result.Facets {
-		counts := map[string]int{}
-		for _, term := range facet.Terms {
-			counts[term.Term] = term.Count
-		}
-		for _, numericRange := range facet.NumericRanges {
-			counts[numericRange.Name] = numericRange.Count
+	var postsubmits []Job
+	for _, job := range jobs.Jobs {
+		if job.Type != "postsubmit" {
+			continue
 		}
-		counts["Other"] = facet.Other
-		switch facet.Field {
-		case "State":
-			jobs.Counts.States = counts
-		case "Repository":
-			jobs.Counts.Repositories = counts
-		case "Type":
-			jobs.Counts.Types = counts
-		case "Author":
-			jobs.Counts.Authors = counts
+		if q.Context != "" && q.Context != job.Context {
+			continue
 		}
+		postsubmits = append(postsubmits, job)
 	}
 
-	return jobs
+	latestJobs := latestJobsByContext(postsubmits)
+	return &BranchHealth{
+		Branch: q.Branch,
+		Health: jobsHealth(latestJobs),
+		Jobs:   latestJobs,
+	}, nil
 }
 
-func bleveDocToJob(doc *search.DocumentMatch) Job {
-	var (
-		startDate, endDate time.Time
-	)
-	if start, ok := doc.Fields["Start"].(string); ok {
-		startDate, _ = time.Parse(time.RFC3339, start)
-	}
-	if end, ok := doc.Fields["End"].(string); ok {
-		endDate, _ = time.Parse(time.RFC3339, end)
-	}
-	return Job{
-		Name:        doc.Fields["Name"].(string),
-		Type:        doc.Fields["Type"].(string),
-		EventGUID:   doc.Fields["EventGUID"].(string),
-		Owner:       doc.Fields["Owner"].(string),
-		Repository:  doc.Fields["Repository"].(string),
-		Branch:      doc.Fields["Branch"].(string),
-		Build:       doc.Fields["Build"].(string),
-		Context:     doc.Fields["Context"].(string),
-		Author:      doc.Fields["Author"].(string),
-		State:       doc.Fields["State"].(string),
-		Description: doc.Fields["Description"].(string),
-		ReportURL:   doc.Fields["ReportURL"].(string),
-		TraceID:     doc.Fields["TraceID"].(string),
-		Start:       startDate,
-		End:         endDate,
-		Duration:    time.Duration(doc.Fields["Duration"].(float64)),
+// QueryBranchHistory returns the timeline of the postsubmit jobs of a branch, and the merges which broke its failing contexts
+func QueryBranchHistory(s Store, q BranchHistoryQuery) (*BranchHistory, error) {
+	jobs, err := s.QueryJobs(JobsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		Branch:     q.Branch,
+		From:       q.From,
+		To:         q.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the push events are used to find the merges which produced the commits of the jobs
+	events, err := s.QueryEvents(EventsQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		Branch:     q.Branch,
+		From:       q.From,
+		To:         q.To,
+	})
+	if err != nil {
+		return nil, err
 	}
+
+	records := s.QueryMergeHistory(MergeHistoryQuery{
+		Owner:      q.Owner,
+		Repository: q.Repository,
+		Branch:     q.Branch,
+		From:       q.From,
+		To:         q.To,
+	})
+
+	return newBranchHistory(q.Owner, q.Repository, q.Branch, jobs.Jobs, events.Events, records), nil
+}
+
+// gcLimits are the limits enforced by the garbage collector of the stores, which can be changed at runtime
+type gcLimits struct {
+	mutex        sync.RWMutex
+	maxEvents    int
+	eventsMaxAge time.Duration
+}
+
+func (l *gcLimits) set(maxEvents int, eventsMaxAge time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.maxEvents = maxEvents
+	l.eventsMaxAge = eventsMaxAge
+}
+
+func (l *gcLimits) get() (maxEvents int, eventsMaxAge time.Duration) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.maxEvents, l.eventsMaxAge
+}
+
+type StoreConfig struct {
+	// Backend is either StoreBackendBleve (the default) or StoreBackendSQLite
+	Backend      string
+	DataPath     string
+	MaxEvents    int
+	EventsMaxAge time.Duration
+}
+
+// PersistsEvents returns true if the events survive a restart - which is the case for all the backends with a data path
+func (c StoreConfig) PersistsEvents() bool {
+	return c.DataPath != ""
+}
+
+// PersistsJobsAndMergeHistory returns true if the jobs and the merge history survive a restart:
+// the Bleve backend keeps them in memory, so only the SQLite backend with a data path persists them
+func (c StoreConfig) PersistsJobsAndMergeHistory() bool {
+	return c.DataPath != "" && c.Backend == StoreBackendSQLite
+}
+
+type JobsQuery struct {
+	Name       string
+	EventGUID  string
+	Owner      string
+	Repository string
+	Branch     string
+	Author     string
+	// SHA restricts the jobs to the ones which ran on this commit - either as the base or as a pull request
+	SHA   string
+	Query string
+	// From and To restrict the jobs to the ones started in this time range - the zero value means no bound
+	From time.Time
+	To   time.Time
+	// Histogram is true to compute the histogram of the jobs over time - which is only displayed on the HTML page
+	Histogram bool
 }
 
 type EventsQuery struct {
@@ -355,106 +613,77 @@ type EventsQuery struct {
 	Owner      string
 	Repository string
 	Branch     string
-	Query      string
+	Sender     string
+	// SHA restricts the events to the ones with this commit - as the head or a pushed commit
+	SHA   string
+	Query string
+	// From and To restrict the events to the ones received in this time range - the zero value means no bound
+	From time.Time
+	To   time.Time
+	// Histogram is true to compute the histogram of the events over time - which is only displayed on the HTML page
+	Histogram bool
 }
 
-func (q EventsQuery) ToBleveQuery() query.Query {
-	var queryString strings.Builder
-	if len(q.Query) > 0 {
-		queryString.WriteString("+")
-		queryString.WriteString(q.Query)
-	}
-	if len(q.GUID) > 0 {
-		if queryString.Len() > 0 {
-			queryString.WriteString(" ")
-		}
-		queryString.WriteString("+GUID:")
-		queryString.WriteString(q.GUID)
-	}
-	if len(q.Owner) > 0 {
-		if queryString.Len() > 0 {
-			queryString.WriteString(" ")
-		}
-		queryString.WriteString("+Owner:")
-		queryString.WriteString(q.Owner)
-	}
-	if len(q.Repository) > 0 {
-		if queryString.Len() > 0 {
-			queryString.WriteString(" ")
-		}
-		queryString.WriteString("+Repository:")
-		queryString.WriteString(q.Repository)
-	}
-	if len(q.Branch) > 0 {
-		if queryString.Len() > 0 {
-			queryString.WriteString(" ")
-		}
-		queryString.WriteString("+Branch:")
-		queryString.WriteString(q.Branch)
-	}
-	if queryString.Len() == 0 {
-		return bleve.NewMatchAllQuery()
-	}
-	return bleve.NewQueryStringQuery(queryString.String())
+type MergeStatusQuery struct {
+	Source     string
+	Owner      string
+	Repository string
+	Branch     string
+	// Blocked restricts the results to the pools with blocker issues or an error
+	Blocked bool
 }
 
-func bleveResultToEvents(result *bleve.SearchResult) Events {
-	var events Events
-
-	for _, doc := range result.Hits {
-		event := bleveDocToEvent(doc)
-		events.Events = append(events.Events, event)
-	}
+type MergeHistoryQuery struct {
+	Source     string
+	Owner      string
+	Repository string
+	Branch     string
+	// SHA restricts the records to the ones with this commit - as the base or the head of a merged pull request
+	SHA string
+	// From and To restrict the records to this time range - the zero value means no bound
+	From time.Time
+	To   time.Time
+}
 
-	for _, facet := range result.Facets {
-		counts := map[string]int{}
-		for _, term := range facet.Terms {
-			counts[term.Term] = term.Count
-		}
-		for _, numericRange := range facet.NumericRanges {
-			counts[numericRange.Name] = numericRange.Count
-		}
-		counts["Other"] = facet.Other
-		switch facet.Field {
-		case "Kind":
-			events.Counts.Kinds = counts
-		case "Repository":
-			events.Counts.Repositories = counts
-		case "Sender":
-			events.Counts.Senders = counts
-		}
-	}
+type BlockingReasonsQuery struct {
+	Owner      string
+	Repository string
+	Number     int
+}
 
-	return events
+type UserActivityQuery struct {
+	Login string
 }
 
-func bleveDocToEvent(doc *search.DocumentMatch) Event {
-	var eventTime time.Time
-	if evTime, ok := doc.Fields["Time"].(string); ok {
-		eventTime, _ = time.Parse(time.RFC3339, evTime)
-	}
-	return Event{
-		GUID:       doc.Fields["GUID"].(string),
-		Owner:      doc.Fields["Owner"].(string),
-		Repository: doc.Fields["Repository"].(string),
-		Branch:     doc.Fields["Branch"].(string),
-		Kind:       doc.Fields["Kind"].(string),
-		Action:     doc.Fields["Action"].(string),
-		Details:    doc.Fields["Details"].(string),
-		URL:        doc.Fields["URL"].(string),
-		Sender:     doc.Fields["Sender"].(string),
-		Time:       eventTime,
-	}
+type CommitQuery struct {
+	Owner      string
+	Repository string
+	SHA        string
 }
 
-type MergeStatusQuery struct {
+type BranchHealthQuery struct {
 	Owner      string
 	Repository string
 	Branch     string
+	Context    string
 }
 
-type MergeHistoryQuery struct {
+type BranchHistoryQuery struct {
 	Owner      string
 	Repository string
 	Branch     string
+	// From and To restrict the jobs, events and merges to this time range - the zero value means no bound
+	From time.Time
+	To   time.Time
+}
+
+type RepositoriesQuery struct {
+	Owner string
+}
+
+type RepositoryOverviewQuery struct {
+	Owner      string
+	Repository string
+	// MaxItems is the max number of recent events and merges - if non-zero
+	MaxItems int
 }
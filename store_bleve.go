@@ -0,0 +1,866 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	bleveindex "github.com/blevesearch/bleve/index"
+	"github.com/blevesearch/bleve/mapping"
+	"github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// eventsIndexMappingVersion is the version of the events index mapping
+	// if you change something in the mapping, increment this version
+	// this is used to ensure the index will be recreated if we change the mapping
+	// the events of the previous version are then reindexed in the new index
+	eventsIndexMappingVersion = 1
+
+	// reindexBatchSize is the number of events indexed at once when migrating an index
+	reindexBatchSize = 1000
+
+	// histogramFacetName is the name of the date range facet used to build the histogram of the events/jobs
+	histogramFacetName = "Histogram"
+
+	// savedSearchesInternalKey is the key of the saved searches, stored in the internal (not indexed) storage of the events index
+	// so that they are persisted with the events
+	savedSearchesInternalKey = "saved-searches"
+)
+
+// BleveStore stores the events and jobs in Bleve indexes, and the merge status/history in memory
+type BleveStore struct {
+	gcLimits          gcLimits
+	gcStopChan        chan struct{}
+	events            bleve.Index
+	jobs              bleve.Index
+	mergeStatus       []MergePool
+	mergeStatusMutex  sync.RWMutex
+	mergeHistory      []MergeRecord
+	mergeHistoryMutex sync.RWMutex
+	// savedSearchesMutex protects the read-modify-write of the saved searches
+	savedSearchesMutex sync.Mutex
+}
+
+func NewBleveStore(cfg StoreConfig, logger *logrus.Logger) (*BleveStore, error) {
+	var (
+		store = new(BleveStore)
+		err   error
+	)
+
+	keywordFieldMapping := bleve.NewTextFieldMapping()
+	keywordFieldMapping.Analyzer = keyword.Name
+
+	jobsMapping := bleve.NewIndexMapping()
+	jobsMapping.DefaultAnalyzer = keyword.Name
+	jobsMapping.DefaultMapping.AddFieldMappingsAt("Start", bleve.NewDateTimeFieldMapping())
+	jobsMapping.DefaultMapping.AddFieldMappingsAt("End", bleve.NewDateTimeFieldMapping())
+
+	eventsMapping := newEventsIndexMapping()
+
+	store.jobs, err = bleve.NewMemOnly(jobsMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to created a Bleve in-memory Index: %w", err)
+	}
+
+	if cfg.DataPath == "" {
+		store.events, err = bleve.NewMemOnly(eventsMapping)
+		if err != nil {
+			return nil, fmt.Errorf("failed to created a Bleve in-memory Index: %w", err)
+		}
+	} else {
+		store.events, err = openEventsIndex(cfg.DataPath, eventsIndexMappingVersion, eventsMapping, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store.gcLimits.set(cfg.MaxEvents, cfg.EventsMaxAge)
+	store.gcStopChan = make(chan struct{})
+
+	ticker := time.NewTicker(1 * time.Minute)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				store.CollectGarbage()
+			case <-store.gcStopChan:
+				logger.Info("Store GarbageCollector exiting...")
+				return
+			}
+		}
+	}()
+
+	return store, nil
+}
+
+// newEventsIndexMapping returns the mapping of the events index - for the current eventsIndexMappingVersion
+func newEventsIndexMapping() *mapping.IndexMappingImpl {
+	eventsMapping := bleve.NewIndexMapping()
+	eventsMapping.DefaultAnalyzer = keyword.Name
+	eventsMapping.DefaultMapping.AddFieldMappingsAt("Time", bleve.NewDateTimeFieldMapping())
+	return eventsMapping
+}
+
+// openEventsIndex opens the persisted events index for the given mapping version - which is eventsIndexMappingVersion, except in tests.
+// If it doesn't exist yet, it is created and the events of the most recent previous version - if any - are reindexed into it.
+// If it can't be opened, it is moved aside - instead of being deleted - and a new (empty) index is created.
+func openEventsIndex(dataPath string, mappingVersion int, eventsMapping mapping.IndexMapping, logger *logrus.Logger) (bleve.Index, error) {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+
+	eventsDataPath := eventsIndexPath(dataPath, mappingVersion)
+	index, err := bleve.Open(eventsDataPath)
+	if err == nil {
+		return index, nil
+	}
+
+	indexExists := !errors.Is(err, bleve.ErrorIndexPathDoesNotExist)
+	if indexExists {
+		brokenDataPath := fmt.Sprintf("%s.broken-%s", eventsDataPath, time.Now().UTC().Format("20060102150405"))
+		logger.WithError(err).WithField("index-path", eventsDataPath).WithField("moved-to", brokenDataPath).Warning("failed to open existing Bleve index - it will be moved aside, and a new (empty) index will be created...")
+		if err = os.Rename(eventsDataPath, brokenDataPath); err != nil {
+			return nil, fmt.Errorf("failed to move the Bleve Index at %s to %s: %w", eventsDataPath, brokenDataPath, err)
+		}
+	}
+
+	index, err = bleve.New(eventsDataPath, eventsMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to created a Bleve Index at %s: %w", eventsDataPath, err)
+	}
+	if indexExists {
+		return index, nil
+	}
+
+	for version := mappingVersion - 1; version > 0; version-- {
+		previousDataPath := eventsIndexPath(dataPath, version)
+		if _, err := os.Stat(previousDataPath); err != nil {
+			continue
+		}
+
+		log := logger.WithField("index-path", previousDataPath).WithField("version", version)
+		count, err := reindexEvents(previousDataPath, index)
+		if err != nil {
+			// we keep the previous index, so that the events can still be migrated manually
+			log.WithError(err).Error("failed to reindex the events of the previous Bleve index")
+			break
+		}
+		log.WithField("events", count).Info("Reindexed the events of the previous Bleve index")
+		if err = os.RemoveAll(previousDataPath); err != nil {
+			log.WithError(err).Warning("failed to delete the previous Bleve index")
+		}
+		break
+	}
+	return index, nil
+}
+
+func eventsIndexPath(dataPath string, version int) string {
+	return filepath.Join(dataPath, fmt.Sprintf("events-v%d", version))
+}
+
+// reindexEvents copies all the events of the index at the given path into the given index
+func reindexEvents(dataPath string, index bleve.Index) (int, error) {
+	previousIndex, err := bleve.Open(dataPath)
+	if err != nil {
+		return 0, err
+	}
+	defer previousIndex.Close()
+
+	var (
+		count int
+		batch = index.NewBatch()
+	)
+	err = searchAll(previousIndex, bleve.NewMatchAllQuery(), "-Time", func(doc *search.DocumentMatch) error {
+		event := bleveDocToEvent(doc)
+		if err := batch.Index(event.GUID, event); err != nil {
+			return err
+		}
+		count++
+		if batch.Size() >= reindexBatchSize {
+			if err := index.Batch(batch); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if err = index.Batch(batch); err != nil {
+		return count, err
+	}
+
+	// and the saved searches, which are not indexed
+	savedSearches, err := previousIndex.GetInternal([]byte(savedSearchesInternalKey))
+	if err != nil || len(savedSearches) == 0 {
+		return count, err
+	}
+	return count, index.SetInternal([]byte(savedSearchesInternalKey), savedSearches)
+}
+
+func (s *BleveStore) Close() error {
+	close(s.gcStopChan)
+	return s.events.Close()
+}
+
+func (s *BleveStore) SetMergeStatus(source string, pools []MergePool) {
+	s.mergeStatusMutex.Lock()
+	defer s.mergeStatusMutex.Unlock()
+
+	var (
+		mergeStatus   = make([]MergePool, 0, len(s.mergeStatus)+len(pools))
+		previousPools []MergePool
+	)
+	for _, pool := range s.mergeStatus {
+		if pool.Source != source {
+			mergeStatus = append(mergeStatus, pool)
+			continue
+		}
+		previousPools = append(previousPools, pool)
+	}
+	s.mergeStatus = append(mergeStatus, mergePoolsWithPreviousState(source, previousPools, pools)...)
+}
+
+func (s *BleveStore) QueryMergeStatus(q MergeStatusQuery) []MergePool {
+	s.mergeStatusMutex.RLock()
+	defer s.mergeStatusMutex.RUnlock()
+
+	var pools []MergePool
+	for _, pool := range s.mergeStatus {
+		if q.Matches(pool) {
+			pools = append(pools, pool)
+		}
+	}
+	return pools
+}
+
+func (s *BleveStore) SaveSearch(search SavedSearch) error {
+	s.savedSearchesMutex.Lock()
+	defer s.savedSearchesMutex.Unlock()
+
+	searches, err := s.loadSavedSearches()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range searches {
+		if searches[i].User == search.User && searches[i].Name == search.Name {
+			searches[i] = search
+			replaced = true
+		}
+	}
+	if !replaced {
+		searches = append(searches, search)
+	}
+	return s.storeSavedSearches(searches)
+}
+
+func (s *BleveStore) DeleteSavedSearch(user, name string) error {
+	s.savedSearchesMutex.Lock()
+	defer s.savedSearchesMutex.Unlock()
+
+	searches, err := s.loadSavedSearches()
+	if err != nil {
+		return err
+	}
+	kept := searches[:0]
+	for _, search := range searches {
+		if search.User != user || search.Name != name {
+			kept = append(kept, search)
+		}
+	}
+	return s.storeSavedSearches(kept)
+}
+
+func (s *BleveStore) QuerySavedSearches(user string) ([]SavedSearch, error) {
+	s.savedSearchesMutex.Lock()
+	searches, err := s.loadSavedSearches()
+	s.savedSearchesMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var userSearches []SavedSearch
+	for _, search := range searches {
+		if search.User == user {
+			userSearches = append(userSearches, search)
+		}
+	}
+	sortSavedSearches(userSearches)
+	return userSearches, nil
+}
+
+func (s *BleveStore) ExportSavedSearches() ([]SavedSearch, error) {
+	s.savedSearchesMutex.Lock()
+	searches, err := s.loadSavedSearches()
+	s.savedSearchesMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(searches, func(i, j int) bool {
+		if searches[i].User != searches[j].User {
+			return searches[i].User < searches[j].User
+		}
+		return searches[i].Name < searches[j].Name
+	})
+	return searches, nil
+}
+
+// loadSavedSearches returns the searches of all the users
+func (s *BleveStore) loadSavedSearches() ([]SavedSearch, error) {
+	data, err := s.events.GetInternal([]byte(savedSearchesInternalKey))
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	var searches []SavedSearch
+	if err = json.Unmarshal(data, &searches); err != nil {
+		return nil, fmt.Errorf("invalid saved searches: %w", err)
+	}
+	return searches, nil
+}
+
+func (s *BleveStore) storeSavedSearches(searches []SavedSearch) error {
+	data, err := json.Marshal(searches)
+	if err != nil {
+		return err
+	}
+	return s.events.SetInternal([]byte(savedSearchesInternalKey), data)
+}
+
+func (s *BleveStore) SetMergeHistory(source string, records []MergeRecord) {
+	s.mergeHistoryMutex.Lock()
+	defer s.mergeHistoryMutex.Unlock()
+
+	mergeHistory := make([]MergeRecord, 0, len(s.mergeHistory)+len(records))
+	for _, record := range s.mergeHistory {
+		if record.Source != source {
+			mergeHistory = append(mergeHistory, record)
+		}
+	}
+	for _, record := range records {
+		record.Source = source
+		mergeHistory = append(mergeHistory, record)
+	}
+	s.mergeHistory = mergeHistory
+}
+
+func (s *BleveStore) QueryMergeHistory(q MergeHistoryQuery) []MergeRecord {
+	s.mergeHistoryMutex.RLock()
+	defer s.mergeHistoryMutex.RUnlock()
+
+	var records []MergeRecord
+	for _, record := range s.mergeHistory {
+		if q.Matches(record) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func (s *BleveStore) AddJob(j Job) error {
+	return s.jobs.Index(j.Name, j)
+}
+
+func (s *BleveStore) DeleteJob(name string) error {
+	return s.jobs.Delete(name)
+}
+
+func (s *BleveStore) AddEvent(e Event) error {
+	return s.events.Index(e.GUID, e)
+}
+
+func (s *BleveStore) QueryJobs(q JobsQuery) (*Jobs, error) {
+	var histogram Histogram
+	if q.Histogram {
+		var err error
+		if histogram, err = s.histogram(s.jobs, q.ToBleveQuery(), "Start", q.From, q.To); err != nil {
+			return nil, err
+		}
+	}
+
+	request := bleve.NewSearchRequest(q.ToBleveQuery())
+	request.SortBy([]string{"-Start"})
+	request.Size = 10000
+	request.Fields = []string{"*"}
+	request.AddFacet("State", bleve.NewFacetRequest("State", 4))
+	request.AddFacet("Repository", bleve.NewFacetRequest("Repository", 3))
+	request.AddFacet("Type", bleve.NewFacetRequest("Type", 3))
+	request.AddFacet("Author", bleve.NewFacetRequest("Author", 3))
+	addHistogramFacet(request, "Start", histogram)
+	result, err := s.jobs.Search(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for %v: %w", q, err)
+	}
+
+	jobs := bleveResultToJobs(result)
+	jobs.Histogram = histogramWithCounts(histogram, result.Facets[histogramFacetName])
+	return &jobs, nil
+}
+
+func (s *BleveStore) CountJobs(q JobsQuery) (int, error) {
+	return countDocuments(s.jobs, q.ToBleveQuery())
+}
+
+func (s *BleveStore) CountEvents(q EventsQuery) (int, error) {
+	return countDocuments(s.events, q.ToBleveQuery())
+}
+
+// countDocuments returns the total number of documents matching the query, without loading any of them
+func countDocuments(index bleve.Index, q query.Query) (int, error) {
+	request := bleve.NewSearchRequest(q)
+	request.Size = 0
+	result, err := index.Search(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count the documents matching %v: %w", q, err)
+	}
+	return int(result.Total), nil
+}
+
+func (s *BleveStore) QueryEvents(q EventsQuery) (*Events, error) {
+	var histogram Histogram
+	if q.Histogram {
+		var err error
+		if histogram, err = s.histogram(s.events, q.ToBleveQuery(), "Time", q.From, q.To); err != nil {
+			return nil, err
+		}
+	}
+
+	request := bleve.NewSearchRequest(q.ToBleveQuery())
+	request.SortBy([]string{"-Time"})
+	request.Size = 10000
+	request.Fields = []string{"*"}
+	request.AddFacet("Kind", bleve.NewFacetRequest("Kind", 4))
+	request.AddFacet("Repository", bleve.NewFacetRequest("Repository", 3))
+	request.AddFacet("Sender", bleve.NewFacetRequest("Sender", 3))
+	addHistogramFacet(request, "Time", histogram)
+	result, err := s.events.Search(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for %v: %w", q, err)
+	}
+
+	events := bleveResultToEvents(result)
+	events.Histogram = histogramWithCounts(histogram, result.Facets[histogramFacetName])
+	return &events, nil
+}
+
+// histogram returns the empty buckets of the date histogram for the given query:
+// if there is no lower bound, we use the oldest document matching the query, and if there is no upper bound, we use now
+func (s *BleveStore) histogram(index bleve.Index, q query.Query, field string, from, to time.Time) (Histogram, error) {
+	if from.IsZero() {
+		request := bleve.NewSearchRequest(q)
+		request.SortBy([]string{field})
+		request.Size = 1
+		request.Fields = []string{field}
+		result, err := index.Search(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for the oldest document matching %v: %w", q, err)
+		}
+		if len(result.Hits) == 0 {
+			return nil, nil
+		}
+		if oldest, ok := result.Hits[0].Fields[field].(string); ok {
+			from, _ = time.Parse(time.RFC3339, oldest)
+		}
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	return histogramBuckets(from, to), nil
+}
+
+func addHistogramFacet(request *bleve.SearchRequest, field string, histogram Histogram) {
+	if len(histogram) == 0 {
+		return
+	}
+	facet := bleve.NewFacetRequest(field, len(histogram))
+	for i, bucket := range histogram {
+		facet.AddDateTimeRange(strconv.Itoa(i), bucket.Start, bucket.End)
+	}
+	request.AddFacet(histogramFacetName, facet)
+}
+
+// histogramWithCounts fills the buckets of the histogram with the counts from the facet - which only has the non-empty buckets
+func histogramWithCounts(histogram Histogram, facet *search.FacetResult) Histogram {
+	if facet == nil {
+		return histogram
+	}
+	for _, dateRange := range facet.DateRanges {
+		if i, err := strconv.Atoi(dateRange.Name); err == nil && i < len(histogram) {
+			histogram[i].Count = dateRange.Count
+		}
+	}
+	return histogram
+}
+
+// ExportJobs retrieves the jobs by batches, so there is no limit on the number of jobs
+func (s *BleveStore) ExportJobs(q JobsQuery, fn func(Job) error) error {
+	return searchAll(s.jobs, q.ToBleveQuery(), "-Start", func(doc *search.DocumentMatch) error {
+		return fn(bleveDocToJob(doc))
+	})
+}
+
+// ExportEvents retrieves the events by batches, so there is no limit on the number of events
+func (s *BleveStore) ExportEvents(q EventsQuery, fn func(Event) error) error {
+	return searchAll(s.events, q.ToBleveQuery(), "-Time", func(doc *search.DocumentMatch) error {
+		return fn(bleveDocToEvent(doc))
+	})
+}
+
+func (s *BleveStore) QueryJobFieldValues(field, prefix string, limit int) ([]FieldValue, error) {
+	return fieldValues(s.jobs, field, prefix, limit)
+}
+
+func (s *BleveStore) QueryEventFieldValues(field, prefix string, limit int) ([]FieldValue, error) {
+	return fieldValues(s.events, field, prefix, limit)
+}
+
+// fieldValues returns the most frequent terms of the field starting with the prefix - which are the values, with the keyword analyzer
+func fieldValues(index bleve.Index, field, prefix string, limit int) ([]FieldValue, error) {
+	var (
+		dict bleveindex.FieldDict
+		err  error
+	)
+	if prefix == "" {
+		dict, err = index.FieldDict(field)
+	} else {
+		dict, err = index.FieldDictPrefix(field, []byte(prefix))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the values of %s: %w", field, err)
+	}
+	defer dict.Close()
+
+	var values []FieldValue
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the values of %s: %w", field, err)
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Count > 0 && entry.Term != "" {
+			values = append(values, FieldValue{Value: entry.Term, Count: int(entry.Count)})
+		}
+	}
+	return sortFieldValues(values, limit), nil
+}
+
+// searchAll pages through all the documents matching the query, using the sort keys of the last hit
+// as the starting point of the next batch - which is a lot cheaper than increasing the "From" offset
+func searchAll(index bleve.Index, q query.Query, sortBy string, fn func(*search.DocumentMatch) error) error {
+	var searchAfter []string
+	for {
+		request := bleve.NewSearchRequest(q)
+		request.SortBy([]string{sortBy, "_id"})
+		request.Size = 1000
+		request.Fields = []string{"*"}
+		request.SearchAfter = searchAfter
+		result, err := index.Search(request)
+		if err != nil {
+			return fmt.Errorf("failed to search for %v: %w", q, err)
+		}
+		for _, doc := range result.Hits {
+			if err = fn(doc); err != nil {
+				return err
+			}
+		}
+		if len(result.Hits) < request.Size {
+			return nil
+		}
+		searchAfter = result.Hits[len(result.Hits)-1].Sort
+	}
+}
+
+func (s *BleveStore) SetGarbageCollectionLimits(maxEvents int, eventsMaxAge time.Duration) {
+	s.gcLimits.set(maxEvents, eventsMaxAge)
+}
+
+func (s *BleveStore) CollectGarbage() error {
+	maxEvents, eventsMaxAge := s.gcLimits.get()
+	var deleteMatchingEvents = func(req *bleve.SearchRequest) error {
+		result, err := s.events.Search(req)
+		if err != nil {
+			return err
+		}
+		for _, doc := range result.Hits {
+			if err = s.events.Delete(doc.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if maxEvents > 0 {
+		request := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
+		request.SortBy([]string{"-Time"})
+		request.Size = 1000
+		request.From = maxEvents
+		if err := deleteMatchingEvents(request); err != nil {
+			return err
+		}
+	}
+	if eventsMaxAge > 0 {
+		request := bleve.NewSearchRequest(bleve.NewDateRangeQuery(time.Time{}, time.Now().Add(-eventsMaxAge)))
+		request.Size = 1000
+		if err := deleteMatchingEvents(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q JobsQuery) ToBleveQuery() query.Query {
+	return withTimeRange(q.fieldsQuery(), "Start", q.From, q.To)
+}
+
+// fieldsQuery matches the exact values of the fields of the query - which can contain any character - and the query string entered by the user
+func (q JobsQuery) fieldsQuery() query.Query {
+	return withQueryString(q.Query, termQueries(
+		"Name", q.Name,
+		"EventGUID", q.EventGUID,
+		"Owner", q.Owner,
+		"Repository", q.Repository,
+		"Branch", q.Branch,
+		"Author", q.Author,
+	), shaQuery(q.SHA, "BaseSHA", "PullSHAs"))
+}
+
+func bleveResultToJobs(result *bleve.SearchResult) Jobs {
+	var jobs Jobs
+
+	for _, doc := range result.Hits {
+		job := bleveDocToJob(doc)
+		jobs.Jobs = append(jobs.Jobs, job)
+	}
+
+	for _, facet := range result.Facets {
+		counts := map[string]int{}
+		for _, term := range facet.Terms {
+			counts[term.Term] = term.Count
+		}
+		for _, numericRange := range facet.NumericRanges {
+			counts[numericRange.Name] = numericRange.Count
+		}
+		counts["Other"] = facet.Other
+		switch facet.Field {
+		case "State":
+			jobs.Counts.States = counts
+		case "Repository":
+			jobs.Counts.Repositories = counts
+		case "Type":
+			jobs.Counts.Types = counts
+		case "Author":
+			jobs.Counts.Authors = counts
+		}
+	}
+
+	return jobs
+}
+
+func bleveDocToJob(doc *search.DocumentMatch) Job {
+	var (
+		startDate, endDate time.Time
+	)
+	if start, ok := doc.Fields["Start"].(string); ok {
+		startDate, _ = time.Parse(time.RFC3339, start)
+	}
+	if end, ok := doc.Fields["End"].(string); ok {
+		endDate, _ = time.Parse(time.RFC3339, end)
+	}
+	return Job{
+		Name:        doc.Fields["Name"].(string),
+		Type:        doc.Fields["Type"].(string),
+		EventGUID:   doc.Fields["EventGUID"].(string),
+		Owner:       doc.Fields["Owner"].(string),
+		Repository:  doc.Fields["Repository"].(string),
+		Branch:      doc.Fields["Branch"].(string),
+		Build:       doc.Fields["Build"].(string),
+		Context:     doc.Fields["Context"].(string),
+		Author:      doc.Fields["Author"].(string),
+		State:       doc.Fields["State"].(string),
+		Description: doc.Fields["Description"].(string),
+		ReportURL:   doc.Fields["ReportURL"].(string),
+		TraceID:     doc.Fields["TraceID"].(string),
+		Start:       startDate,
+		End:         endDate,
+		Duration:    time.Duration(doc.Fields["Duration"].(float64)),
+		BaseSHA:     bleveDocString(doc, "BaseSHA"),
+		PullSHAs:    bleveDocStrings(doc, "PullSHAs"),
+	}
+}
+
+func (q EventsQuery) ToBleveQuery() query.Query {
+	return withTimeRange(q.fieldsQuery(), "Time", q.From, q.To)
+}
+
+// fieldsQuery matches the exact values of the fields of the query - which can contain any character - and the query string entered by the user
+func (q EventsQuery) fieldsQuery() query.Query {
+	return withQueryString(q.Query, termQueries(
+		"GUID", q.GUID,
+		"Owner", q.Owner,
+		"Repository", q.Repository,
+		"Branch", q.Branch,
+		"Sender", q.Sender,
+	), shaQuery(q.SHA, "SHA", "CommitSHAs"))
+}
+
+// shaQuery matches the documents with the given commit in any of the fields - or returns nil if there is no commit
+func shaQuery(sha string, fields ...string) query.Query {
+	if sha == "" {
+		return nil
+	}
+	queries := make([]query.Query, 0, len(fields))
+	for _, field := range fields {
+		termQuery := bleve.NewTermQuery(sha)
+		termQuery.SetField(field)
+		queries = append(queries, termQuery)
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+// termQueries returns the term queries for the given field and value pairs, ignoring the empty values.
+// With the keyword analyzer, the values are indexed as a single term, so a term query is an exact match.
+func termQueries(fieldsAndValues ...string) []query.Query {
+	var queries []query.Query
+	for i := 0; i+1 < len(fieldsAndValues); i += 2 {
+		field, value := fieldsAndValues[i], fieldsAndValues[i+1]
+		if value == "" {
+			continue
+		}
+		termQuery := bleve.NewTermQuery(value)
+		termQuery.SetField(field)
+		queries = append(queries, termQuery)
+	}
+	return queries
+}
+
+// withQueryString combines the query string entered by the user with the other queries - which must all match, and can be nil.
+// The first term of the user query is required - unless it is already prefixed with + or -
+func withQueryString(userQuery string, queries []query.Query, otherQueries ...query.Query) query.Query {
+	for _, q := range otherQueries {
+		if q != nil {
+			queries = append(queries, q)
+		}
+	}
+	userQuery = strings.TrimSpace(userQuery)
+	if userQuery != "" {
+		if userQuery[0] != '+' && userQuery[0] != '-' {
+			userQuery = "+" + userQuery
+		}
+		queries = append([]query.Query{bleve.NewQueryStringQuery(userQuery)}, queries...)
+	}
+	switch len(queries) {
+	case 0:
+		return bleve.NewMatchAllQuery()
+	case 1:
+		return queries[0]
+	default:
+		return bleve.NewConjunctionQuery(queries...)
+	}
+}
+
+// withTimeRange restricts the given query to the documents with the date field between from (inclusive) and to (exclusive)
+func withTimeRange(q query.Query, field string, from, to time.Time) query.Query {
+	if from.IsZero() && to.IsZero() {
+		return q
+	}
+	timeRange := bleve.NewDateRangeQuery(from, to)
+	timeRange.SetField(field)
+	if _, matchAll := q.(*query.MatchAllQuery); matchAll {
+		return timeRange
+	}
+	return bleve.NewConjunctionQuery(q, timeRange)
+}
+
+func bleveResultToEvents(result *bleve.SearchResult) Events {
+	var events Events
+
+	for _, doc := range result.Hits {
+		event := bleveDocToEvent(doc)
+		events.Events = append(events.Events, event)
+	}
+
+	for _, facet := range result.Facets {
+		counts := map[string]int{}
+		for _, term := range facet.Terms {
+			counts[term.Term] = term.Count
+		}
+		for _, numericRange := range facet.NumericRanges {
+			counts[numericRange.Name] = numericRange.Count
+		}
+		counts["Other"] = facet.Other
+		switch facet.Field {
+		case "Kind":
+			events.Counts.Kinds = counts
+		case "Repository":
+			events.Counts.Repositories = counts
+		case "Sender":
+			events.Counts.Senders = counts
+		}
+	}
+
+	return events
+}
+
+func bleveDocToEvent(doc *search.DocumentMatch) Event {
+	var eventTime time.Time
+	if evTime, ok := doc.Fields["Time"].(string); ok {
+		eventTime, _ = time.Parse(time.RFC3339, evTime)
+	}
+	return Event{
+		GUID:       bleveDocString(doc, "GUID"),
+		Owner:      bleveDocString(doc, "Owner"),
+		Repository: bleveDocString(doc, "Repository"),
+		Branch:     bleveDocString(doc, "Branch"),
+		Kind:       bleveDocString(doc, "Kind"),
+		Action:     bleveDocString(doc, "Action"),
+		Details:    bleveDocString(doc, "Details"),
+		URL:        bleveDocString(doc, "URL"),
+		Sender:     bleveDocString(doc, "Sender"),
+		Labels:     bleveDocStrings(doc, "Labels"),
+		Time:       eventTime,
+		SHA:        bleveDocString(doc, "SHA"),
+		BeforeSHA:  bleveDocString(doc, "BeforeSHA"),
+		CommitSHAs: bleveDocStrings(doc, "CommitSHAs"),
+	}
+}
+
+// bleveDocString returns the value of a string field, or an empty string if it is missing
+// which happens for the documents indexed with a previous version of the mapping
+func bleveDocString(doc *search.DocumentMatch, field string) string {
+	value, _ := doc.Fields[field].(string)
+	return value
+}
+
+// bleveDocStrings returns the values of a multi-valued field:
+// bleve returns a single string when there is only 1 value, and nothing at all when there is none
+func bleveDocStrings(doc *search.DocumentMatch, field string) []string {
+	switch value := doc.Fields[field].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if str, ok := v.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,309 @@
+package webui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestOpenEventsIndexMigratesThePreviousVersion(t *testing.T) {
+	var (
+		dataPath = t.TempDir()
+		logger   = logrus.New()
+		now      = time.Now().UTC().Truncate(time.Second)
+	)
+	logger.SetOutput(io.Discard)
+
+	previousIndex, err := openEventsIndex(dataPath, 1, newEventsIndexMapping(), logger)
+	if err != nil {
+		t.Fatalf("failed to create the v1 index: %v", err)
+	}
+	previousStore := &BleveStore{events: previousIndex}
+	for i, guid := range []string{"guid-1", "guid-2", "guid-3"} {
+		err = previousStore.AddEvent(Event{
+			GUID:       guid,
+			Owner:      "owner",
+			Repository: "repo",
+			Branch:     "main",
+			Kind:       "push",
+			Sender:     "alice",
+			Labels:     []string{"bug"},
+			Time:       now.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("failed to index event %s: %v", guid, err)
+		}
+	}
+	if err = previousStore.SaveSearch(SavedSearch{User: "alice", Name: "my search", Page: SavedSearchPageEvents, Query: "Kind:push"}); err != nil {
+		t.Fatalf("failed to save the search: %v", err)
+	}
+	if err = previousIndex.Close(); err != nil {
+		t.Fatalf("failed to close the v1 index: %v", err)
+	}
+
+	index, err := openEventsIndex(dataPath, 2, newEventsIndexMapping(), logger)
+	if err != nil {
+		t.Fatalf("failed to open the v2 index: %v", err)
+	}
+	defer index.Close()
+
+	if _, err = os.Stat(eventsIndexPath(dataPath, 1)); !os.IsNotExist(err) {
+		t.Errorf("expected the v1 index to be removed, got %v", err)
+	}
+	if _, err = os.Stat(eventsIndexPath(dataPath, 2)); err != nil {
+		t.Errorf("expected the v2 index to exist: %v", err)
+	}
+
+	store := &BleveStore{events: index}
+	events, err := store.QueryEvents(EventsQuery{})
+	if err != nil {
+		t.Fatalf("failed to query the events: %v", err)
+	}
+	if len(events.Events) != 3 {
+		t.Fatalf("expected 3 migrated events, got %d", len(events.Events))
+	}
+	for i, event := range events.Events {
+		expectedTime := now.Add(time.Duration(2-i) * time.Minute)
+		if !event.Time.Equal(expectedTime) {
+			t.Errorf("expected event %s at %s, got %s", event.GUID, expectedTime, event.Time)
+		}
+		if event.Sender != "alice" || event.Kind != "push" || len(event.Labels) != 1 || event.Labels[0] != "bug" {
+			t.Errorf("the fields of event %s were not migrated: %+v", event.GUID, event)
+		}
+	}
+
+	searches, err := store.QuerySavedSearches("alice")
+	if err != nil {
+		t.Fatalf("failed to query the saved searches: %v", err)
+	}
+	if len(searches) != 1 || searches[0].Name != "my search" {
+		t.Errorf("expected the saved search to be migrated, got %+v", searches)
+	}
+}
+
+func TestOpenEventsIndexMovesABrokenIndexAside(t *testing.T) {
+	dataPath := t.TempDir()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	if err := os.MkdirAll(eventsIndexPath(dataPath, 1), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(eventsIndexPath(dataPath, 1)+"/index_meta.json", []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := openEventsIndex(dataPath, 1, newEventsIndexMapping(), logger)
+	if err != nil {
+		t.Fatalf("failed to open the events index: %v", err)
+	}
+	defer index.Close()
+
+	count, err := index.DocCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected a new empty index, got %d documents", count)
+	}
+	entries, err := os.ReadDir(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected the new index and the broken one, got %d entries", len(entries))
+	}
+}
+
+func TestBleveStoreCountEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("indexing more than 10000 events is slow")
+	}
+	store := newTestStore(t)
+	now := time.Now().UTC()
+
+	// more than the max number of results of QueryEvents - indexed in a single batch, to be fast
+	batch := store.events.NewBatch()
+	for i := 0; i < 10050; i++ {
+		event := Event{GUID: fmt.Sprintf("guid-%d", i), Kind: "push", Time: now.Add(-time.Duration(i) * time.Second)}
+		if err := batch.Index(event.GUID, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.events.Batch(batch); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddEvent(Event{GUID: "other", Kind: "pull_request", Time: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := store.CountEvents(EventsQuery{Query: "Kind:push"})
+	if err != nil {
+		t.Fatalf("failed to count the events: %v", err)
+	}
+	if count != 10050 {
+		t.Errorf("expected 10050 events, got %d", count)
+	}
+
+	count, err = store.CountEvents(EventsQuery{Query: "Kind:push", From: now.Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("failed to count the events: %v", err)
+	}
+	if count != 61 {
+		t.Errorf("expected 61 events in the last minute, got %d", count)
+	}
+}
+
+// specialValues contain the characters which have a meaning in the Bleve query string syntax
+var specialValues = []string{
+	"with space",
+	"with:colon",
+	"with+plus",
+	"-leading-minus",
+	`with"quote`,
+	`with\backslash`,
+	`all of: +them -"\`,
+}
+
+func TestBleveStoreJobsQueryExactMatches(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now().UTC()
+
+	var jobs []Job
+	for i, value := range specialValues {
+		for _, state := range []string{"success", "failure"} {
+			jobs = append(jobs, Job{
+				Name:       fmt.Sprintf("%s %s", value, state),
+				EventGUID:  value,
+				Owner:      value,
+				Repository: value,
+				Branch:     value,
+				Author:     value,
+				State:      state,
+				Start:      now.Add(-time.Duration(i) * time.Minute),
+			})
+		}
+	}
+	// a job with a similar value, which must never match
+	jobs = append(jobs, Job{Name: "similar", EventGUID: "with", Owner: "with", Repository: "with", Branch: "with", Author: "with", State: "success", Start: now})
+	for _, job := range jobs {
+		if err := store.AddJob(job); err != nil {
+			t.Fatalf("failed to add job %s: %v", job.Name, err)
+		}
+	}
+
+	for _, value := range specialValues {
+		queries := map[string]JobsQuery{
+			"Owner":      {Owner: value},
+			"Repository": {Repository: value},
+			"Branch":     {Branch: value},
+			"EventGUID":  {EventGUID: value},
+			"Author":     {Author: value},
+			"Name":       {Name: value + " success"},
+		}
+		for field, q := range queries {
+			expected := 2
+			if field == "Name" {
+				expected = 1
+			}
+			assertJobs(t, store, fmt.Sprintf("%s=%q", field, value), q, expected, value)
+
+			// combined with a query string entered by the user
+			q.Query = "State:success"
+			assertJobs(t, store, fmt.Sprintf("%s=%q and q=%s", field, value, q.Query), q, 1, value)
+		}
+
+		// values which are only similar must not match
+		assertJobs(t, store, fmt.Sprintf("Owner=%q with a suffix", value), JobsQuery{Owner: value + "x"}, 0, "")
+		assertJobs(t, store, fmt.Sprintf("Name=%q without its state", value), JobsQuery{Name: value}, 0, "")
+	}
+}
+
+func assertJobs(t *testing.T, store *BleveStore, description string, q JobsQuery, expected int, owner string) {
+	t.Helper()
+	jobs, err := store.QueryJobs(q)
+	if err != nil {
+		t.Errorf("%s: failed to query the jobs: %v", description, err)
+		return
+	}
+	if len(jobs.Jobs) != expected {
+		t.Errorf("%s: expected %d jobs, got %d", description, expected, len(jobs.Jobs))
+		return
+	}
+	for _, job := range jobs.Jobs {
+		if job.Owner != owner {
+			t.Errorf("%s: unexpected job %q", description, job.Name)
+		}
+	}
+}
+
+func TestBleveStoreEventsQueryExactMatches(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now().UTC()
+
+	var events []Event
+	for i, value := range specialValues {
+		for _, kind := range []string{"push", "pull_request"} {
+			events = append(events, Event{
+				GUID:       fmt.Sprintf("%s %s", value, kind),
+				Owner:      value,
+				Repository: value,
+				Branch:     value,
+				Sender:     value,
+				Kind:       kind,
+				Time:       now.Add(-time.Duration(i) * time.Minute),
+			})
+		}
+	}
+	events = append(events, Event{GUID: "similar", Owner: "with", Repository: "with", Branch: "with", Sender: "with", Kind: "push", Time: now})
+	for _, event := range events {
+		if err := store.AddEvent(event); err != nil {
+			t.Fatalf("failed to add event %s: %v", event.GUID, err)
+		}
+	}
+
+	for _, value := range specialValues {
+		queries := map[string]EventsQuery{
+			"Owner":      {Owner: value},
+			"Repository": {Repository: value},
+			"Branch":     {Branch: value},
+			"Sender":     {Sender: value},
+			"GUID":       {GUID: value + " push"},
+		}
+		for field, q := range queries {
+			expected := 2
+			if field == "GUID" {
+				expected = 1
+			}
+			assertEvents(t, store, fmt.Sprintf("%s=%q", field, value), q, expected, value)
+
+			q.Query = "Kind:push"
+			assertEvents(t, store, fmt.Sprintf("%s=%q and q=%s", field, value, q.Query), q, 1, value)
+		}
+
+		assertEvents(t, store, fmt.Sprintf("Sender=%q with a suffix", value), EventsQuery{Sender: value + "x"}, 0, "")
+		assertEvents(t, store, fmt.Sprintf("GUID=%q without its kind", value), EventsQuery{GUID: value}, 0, "")
+	}
+}
+
+func assertEvents(t *testing.T, store *BleveStore, description string, q EventsQuery, expected int, owner string) {
+	t.Helper()
+	events, err := store.QueryEvents(q)
+	if err != nil {
+		t.Errorf("%s: failed to query the events: %v", description, err)
+		return
+	}
+	if len(events.Events) != expected {
+		t.Errorf("%s: expected %d events, got %d", description, expected, len(events.Events))
+		return
+	}
+	for _, event := range events.Events {
+		if event.Owner != owner {
+			t.Errorf("%s: unexpected event %q", description, event.GUID)
+		}
+	}
+}
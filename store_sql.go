@@ -0,0 +1,929 @@
+package webui
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite" // registers the sqliteDriverName driver, so that any user of NewSQLStore has it
+)
+
+const (
+	// sqliteDriverName is the name of the database/sql driver registered by modernc.org/sqlite
+	sqliteDriverName = "sqlite"
+	sqliteFileName   = "lighthouse-webui.db"
+
+	// sqlTimeFormat is a fixed-width UTC format, so that times can be sorted as strings,
+	// and understood by the SQLite date and time functions
+	sqlTimeFormat = "2006-01-02T15:04:05.000Z"
+
+	// maxQueryResults is the max number of jobs/events returned by a query - same as the Bleve store
+	maxQueryResults = 10000
+)
+
+// sqlMigrations are applied in order, and only once: the version of the schema is the number of applied migrations.
+// Never change an existing migration - add a new one instead.
+var sqlMigrations = []string{
+	`CREATE TABLE jobs (
+		name        TEXT PRIMARY KEY,
+		type        TEXT NOT NULL DEFAULT '',
+		event_guid  TEXT NOT NULL DEFAULT '',
+		owner       TEXT NOT NULL DEFAULT '',
+		repository  TEXT NOT NULL DEFAULT '',
+		branch      TEXT NOT NULL DEFAULT '',
+		build       TEXT NOT NULL DEFAULT '',
+		context     TEXT NOT NULL DEFAULT '',
+		author      TEXT NOT NULL DEFAULT '',
+		state       TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		report_url  TEXT NOT NULL DEFAULT '',
+		trace_id    TEXT NOT NULL DEFAULT '',
+		start_time  TEXT,
+		end_time    TEXT,
+		duration    INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX jobs_start_time ON jobs (start_time);
+	CREATE INDEX jobs_owner_repository_branch ON jobs (owner, repository, branch);
+	CREATE INDEX jobs_event_guid ON jobs (event_guid);
+	CREATE INDEX jobs_author ON jobs (author);
+
+	CREATE TABLE events (
+		guid       TEXT PRIMARY KEY,
+		time       TEXT,
+		owner      TEXT NOT NULL DEFAULT '',
+		repository TEXT NOT NULL DEFAULT '',
+		branch     TEXT NOT NULL DEFAULT '',
+		kind       TEXT NOT NULL DEFAULT '',
+		action     TEXT NOT NULL DEFAULT '',
+		details    TEXT NOT NULL DEFAULT '',
+		url        TEXT NOT NULL DEFAULT '',
+		sender     TEXT NOT NULL DEFAULT '',
+		labels     TEXT NOT NULL DEFAULT '[]'
+	);
+	CREATE INDEX events_time ON events (time);
+	CREATE INDEX events_owner_repository_branch ON events (owner, repository, branch);
+	CREATE INDEX events_sender ON events (sender);
+
+	CREATE TABLE merge_pools (
+		source     TEXT NOT NULL,
+		owner      TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		branch     TEXT NOT NULL,
+		pool       TEXT NOT NULL,
+		PRIMARY KEY (source, owner, repository, branch)
+	);
+
+	CREATE TABLE merge_records (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		source     TEXT NOT NULL,
+		owner      TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		branch     TEXT NOT NULL,
+		time       TEXT,
+		action     TEXT NOT NULL DEFAULT '',
+		base_sha   TEXT NOT NULL DEFAULT '',
+		record     TEXT NOT NULL
+	);
+	CREATE INDEX merge_records_source_owner_repository_branch ON merge_records (source, owner, repository, branch);
+	CREATE INDEX merge_records_time ON merge_records (time);`,
+
+	`CREATE TABLE saved_searches (
+		user   TEXT NOT NULL,
+		name   TEXT NOT NULL,
+		search TEXT NOT NULL,
+		PRIMARY KEY (user, name)
+	);`,
+
+	`ALTER TABLE jobs ADD COLUMN base_sha TEXT NOT NULL DEFAULT '';
+	ALTER TABLE jobs ADD COLUMN pull_shas TEXT NOT NULL DEFAULT '[]';
+	CREATE INDEX jobs_base_sha ON jobs (base_sha);
+
+	ALTER TABLE events ADD COLUMN sha TEXT NOT NULL DEFAULT '';
+	ALTER TABLE events ADD COLUMN before_sha TEXT NOT NULL DEFAULT '';
+	ALTER TABLE events ADD COLUMN commit_shas TEXT NOT NULL DEFAULT '[]';
+	CREATE INDEX events_sha ON events (sha);`,
+}
+
+const (
+	jobColumns   = "name, type, event_guid, owner, repository, branch, build, context, author, state, description, report_url, trace_id, start_time, end_time, duration, base_sha, pull_shas"
+	eventColumns = "guid, time, owner, repository, branch, kind, action, details, url, sender, labels, sha, before_sha, commit_shas"
+)
+
+// jobQueryFields and eventQueryFields map the query fields supported by the SQL store to their column
+var (
+	jobQueryFields = map[string]string{
+		"Name":        "name",
+		"Type":        "type",
+		"EventGUID":   "event_guid",
+		"Owner":       "owner",
+		"Repository":  "repository",
+		"Branch":      "branch",
+		"Build":       "build",
+		"Context":     "context",
+		"Author":      "author",
+		"State":       "state",
+		"Description": "description",
+		"ReportURL":   "report_url",
+		"TraceID":     "trace_id",
+		"Start":       "start_time",
+		"End":         "end_time",
+		"BaseSHA":     "base_sha",
+	}
+	eventQueryFields = map[string]string{
+		"GUID":       "guid",
+		"Time":       "time",
+		"Owner":      "owner",
+		"Repository": "repository",
+		"Branch":     "branch",
+		"Kind":       "kind",
+		"Action":     "action",
+		"Details":    "details",
+		"URL":        "url",
+		"Sender":     "sender",
+		"SHA":        "sha",
+		"BeforeSHA":  "before_sha",
+	}
+)
+
+// SQLStore stores everything in an embedded SQLite database, which can also be queried directly with SQL.
+// It only supports a subset of the query string syntax: Field:value terms, optionally prefixed with + or -,
+// with * wildcards and >, >=, <, <= comparisons.
+type SQLStore struct {
+	gcLimits   gcLimits
+	db         *sql.DB
+	logger     *logrus.Logger
+	gcStopChan chan struct{}
+}
+
+func NewSQLStore(cfg StoreConfig, logger *logrus.Logger) (*SQLStore, error) {
+	dsn := ":memory:"
+	if cfg.DataPath != "" {
+		dsn = fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", filepath.Join(cfg.DataPath, sqliteFileName))
+	}
+
+	db, err := sql.Open(sqliteDriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the SQLite database %s: %w", dsn, err)
+	}
+	if cfg.DataPath == "" {
+		// each connection has its own in-memory database, so we need to always use the same one
+		// this also means that a long export will block the other queries: the in-memory mode is meant for testing
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+	}
+
+	store := &SQLStore{
+		db:         db,
+		logger:     logger,
+		gcStopChan: make(chan struct{}),
+	}
+	store.gcLimits.set(cfg.MaxEvents, cfg.EventsMaxAge)
+	if err = store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	ticker := time.NewTicker(1 * time.Minute)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := store.CollectGarbage(); err != nil {
+					logger.WithError(err).Warning("failed to collect the SQL store garbage")
+				}
+			case <-store.gcStopChan:
+				logger.Info("Store GarbageCollector exiting...")
+				return
+			}
+		}
+	}()
+
+	return store, nil
+}
+
+// migrate applies the migrations which have not been applied yet
+func (s *SQLStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create the schema_migrations table: %w", err)
+	}
+	var version int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to retrieve the schema version: %w", err)
+	}
+
+	for i := version; i < len(sqlMigrations); i++ {
+		err := s.withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(sqlMigrations[i]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, i+1)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply the migration to schema version %d: %w", i+1, err)
+		}
+		if s.logger != nil {
+			s.logger.WithField("version", i+1).Info("Migrated the SQL store schema")
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) Close() error {
+	close(s.gcStopChan)
+	return s.db.Close()
+}
+
+func (s *SQLStore) withTx(fn func(*sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) AddJob(j Job) error {
+	pullSHAs, err := json.Marshal(j.PullSHAs)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO jobs (`+jobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		j.Name, j.Type, j.EventGUID, j.Owner, j.Repository, j.Branch, j.Build, j.Context, j.Author, j.State,
+		j.Description, j.ReportURL, j.TraceID, sqlTime(j.Start), sqlTime(j.End), int64(j.Duration), j.BaseSHA, string(pullSHAs),
+	)
+	return err
+}
+
+func (s *SQLStore) DeleteJob(name string) error {
+	_, err := s.db.Exec(`DELETE FROM jobs WHERE name = ?`, name)
+	return err
+}
+
+func (s *SQLStore) QueryJobs(q JobsQuery) (*Jobs, error) {
+	where, err := q.toSQLWhere()
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs Jobs
+	err = s.query(`SELECT `+jobColumns+` FROM jobs`+where.String()+` ORDER BY start_time DESC, name LIMIT ?`, append(where.args, maxQueryResults), func(rows *sql.Rows) error {
+		job, err := scanJob(rows)
+		if err != nil {
+			return err
+		}
+		jobs.Jobs = append(jobs.Jobs, job)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the jobs for %v: %w", q, err)
+	}
+
+	for _, facet := range []struct {
+		column string
+		size   int
+		counts *map[string]int
+	}{
+		{"state", 4, &jobs.Counts.States},
+		{"repository", 3, &jobs.Counts.Repositories},
+		{"type", 3, &jobs.Counts.Types},
+		{"author", 3, &jobs.Counts.Authors},
+	} {
+		if *facet.counts, err = s.facet("jobs", facet.column, where, facet.size); err != nil {
+			return nil, err
+		}
+	}
+	if q.Histogram {
+		if jobs.Histogram, err = s.histogram("jobs", "start_time", where, q.From, q.To); err != nil {
+			return nil, err
+		}
+	}
+	return &jobs, nil
+}
+
+func (s *SQLStore) CountJobs(q JobsQuery) (int, error) {
+	where, err := q.toSQLWhere()
+	if err != nil {
+		return 0, err
+	}
+	return s.count("jobs", where)
+}
+
+func (s *SQLStore) ExportJobs(q JobsQuery, fn func(Job) error) error {
+	where, err := q.toSQLWhere()
+	if err != nil {
+		return err
+	}
+	return s.query(`SELECT `+jobColumns+` FROM jobs`+where.String()+` ORDER BY start_time DESC, name`, where.args, func(rows *sql.Rows) error {
+		job, err := scanJob(rows)
+		if err != nil {
+			return err
+		}
+		return fn(job)
+	})
+}
+
+func (s *SQLStore) AddEvent(e Event) error {
+	labels, err := json.Marshal(e.Labels)
+	if err != nil {
+		return err
+	}
+	commitSHAs, err := json.Marshal(e.CommitSHAs)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO events (`+eventColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.GUID, sqlTime(e.Time), e.Owner, e.Repository, e.Branch, e.Kind, e.Action, e.Details, e.URL, e.Sender, string(labels),
+		e.SHA, e.BeforeSHA, string(commitSHAs),
+	)
+	return err
+}
+
+func (s *SQLStore) QueryJobFieldValues(field, prefix string, limit int) ([]FieldValue, error) {
+	return s.fieldValues("jobs", jobQueryFields[field], prefix, limit)
+}
+
+func (s *SQLStore) QueryEvents(q EventsQuery) (*Events, error) {
+	where, err := q.toSQLWhere()
+	if err != nil {
+		return nil, err
+	}
+
+	var events Events
+	err = s.query(`SELECT `+eventColumns+` FROM events`+where.String()+` ORDER BY time DESC, guid LIMIT ?`, append(where.args, maxQueryResults), func(rows *sql.Rows) error {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return err
+		}
+		events.Events = append(events.Events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the events for %v: %w", q, err)
+	}
+
+	for _, facet := range []struct {
+		column string
+		size   int
+		counts *map[string]int
+	}{
+		{"kind", 4, &events.Counts.Kinds},
+		{"repository", 3, &events.Counts.Repositories},
+		{"sender", 3, &events.Counts.Senders},
+	} {
+		if *facet.counts, err = s.facet("events", facet.column, where, facet.size); err != nil {
+			return nil, err
+		}
+	}
+	if q.Histogram {
+		if events.Histogram, err = s.histogram("events", "time", where, q.From, q.To); err != nil {
+			return nil, err
+		}
+	}
+	return &events, nil
+}
+
+func (s *SQLStore) CountEvents(q EventsQuery) (int, error) {
+	where, err := q.toSQLWhere()
+	if err != nil {
+		return 0, err
+	}
+	return s.count("events", where)
+}
+
+func (s *SQLStore) ExportEvents(q EventsQuery, fn func(Event) error) error {
+	where, err := q.toSQLWhere()
+	if err != nil {
+		return err
+	}
+	return s.query(`SELECT `+eventColumns+` FROM events`+where.String()+` ORDER BY time DESC, guid`, where.args, func(rows *sql.Rows) error {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return err
+		}
+		return fn(event)
+	})
+}
+
+func (s *SQLStore) QueryEventFieldValues(field, prefix string, limit int) ([]FieldValue, error) {
+	return s.fieldValues("events", eventQueryFields[field], prefix, limit)
+}
+
+// fieldValues returns the most frequent values of the column starting with the prefix
+func (s *SQLStore) fieldValues(table, column, prefix string, limit int) ([]FieldValue, error) {
+	if column == "" {
+		return nil, nil
+	}
+	pattern := strings.NewReplacer("%", `\%`, "_", `\_`).Replace(prefix) + "%"
+	var values []FieldValue
+	err := s.query(`SELECT `+column+`, COUNT(*) FROM `+table+` WHERE `+column+` LIKE ? ESCAPE '\' AND `+column+` != '' GROUP BY `+column+` ORDER BY COUNT(*) DESC, `+column+` LIMIT ?`, []interface{}{pattern, limit}, func(rows *sql.Rows) error {
+		var value FieldValue
+		if err := rows.Scan(&value.Value, &value.Count); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the values of %s: %w", column, err)
+	}
+	return values, nil
+}
+
+func (s *SQLStore) SetMergeStatus(source string, pools []MergePool) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		var previousPools []MergePool
+		err := queryTx(tx, `SELECT pool FROM merge_pools WHERE source = ?`, []interface{}{source}, func(rows *sql.Rows) error {
+			var pool MergePool
+			if err := scanJSON(rows, &pool); err != nil {
+				return err
+			}
+			previousPools = append(previousPools, pool)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(`DELETE FROM merge_pools WHERE source = ?`, source); err != nil {
+			return err
+		}
+		for _, pool := range mergePoolsWithPreviousState(source, previousPools, pools) {
+			data, err := json.Marshal(pool)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT OR REPLACE INTO merge_pools (source, owner, repository, branch, pool) VALUES (?, ?, ?, ?, ?)`,
+				pool.Source, pool.Owner, pool.Repository, pool.Branch, string(data),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("source", source).Error("failed to store the merge status")
+	}
+}
+
+func (s *SQLStore) QueryMergeStatus(q MergeStatusQuery) []MergePool {
+	var where sqlWhere
+	where.equals("source", q.Source)
+	where.equals("owner", q.Owner)
+	where.equals("repository", q.Repository)
+	where.equals("branch", q.Branch)
+
+	var pools []MergePool
+	err := s.query(`SELECT pool FROM merge_pools`+where.String()+` ORDER BY source, owner, repository, branch`, where.args, func(rows *sql.Rows) error {
+		var pool MergePool
+		if err := scanJSON(rows, &pool); err != nil {
+			return err
+		}
+		if q.Matches(pool) {
+			pools = append(pools, pool)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("failed to query the merge status")
+	}
+	return pools
+}
+
+func (s *SQLStore) SetMergeHistory(source string, records []MergeRecord) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM merge_records WHERE source = ?`, source); err != nil {
+			return err
+		}
+		for _, record := range records {
+			record.Source = source
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO merge_records (source, owner, repository, branch, time, action, base_sha, record) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				record.Source, record.Owner, record.Repository, record.Branch, sqlTime(record.Time), record.Action, record.BaseSHA, string(data),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("source", source).Error("failed to store the merge history")
+	}
+}
+
+func (s *SQLStore) QueryMergeHistory(q MergeHistoryQuery) []MergeRecord {
+	var where sqlWhere
+	where.equals("source", q.Source)
+	where.equals("owner", q.Owner)
+	where.equals("repository", q.Repository)
+	where.equals("branch", q.Branch)
+	where.timeRange("time", q.From, q.To)
+
+	var records []MergeRecord
+	err := s.query(`SELECT record FROM merge_records`+where.String()+` ORDER BY id`, where.args, func(rows *sql.Rows) error {
+		var record MergeRecord
+		if err := scanJSON(rows, &record); err != nil {
+			return err
+		}
+		if q.Matches(record) {
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("failed to query the merge history")
+	}
+	return records
+}
+
+func (s *SQLStore) SaveSearch(search SavedSearch) error {
+	data, err := json.Marshal(search)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO saved_searches (user, name, search) VALUES (?, ?, ?)`, search.User, search.Name, string(data))
+	return err
+}
+
+func (s *SQLStore) DeleteSavedSearch(user, name string) error {
+	_, err := s.db.Exec(`DELETE FROM saved_searches WHERE user = ? AND name = ?`, user, name)
+	return err
+}
+
+func (s *SQLStore) QuerySavedSearches(user string) ([]SavedSearch, error) {
+	var searches []SavedSearch
+	err := s.query(`SELECT search FROM saved_searches WHERE user = ? ORDER BY name`, []interface{}{user}, func(rows *sql.Rows) error {
+		var search SavedSearch
+		if err := scanJSON(rows, &search); err != nil {
+			return err
+		}
+		searches = append(searches, search)
+		return nil
+	})
+	return searches, err
+}
+
+func (s *SQLStore) ExportSavedSearches() ([]SavedSearch, error) {
+	var searches []SavedSearch
+	err := s.query(`SELECT search FROM saved_searches ORDER BY user, name`, nil, func(rows *sql.Rows) error {
+		var search SavedSearch
+		if err := scanJSON(rows, &search); err != nil {
+			return err
+		}
+		searches = append(searches, search)
+		return nil
+	})
+	return searches, err
+}
+
+func (s *SQLStore) SetGarbageCollectionLimits(maxEvents int, eventsMaxAge time.Duration) {
+	s.gcLimits.set(maxEvents, eventsMaxAge)
+}
+
+func (s *SQLStore) CollectGarbage() error {
+	maxEvents, eventsMaxAge := s.gcLimits.get()
+	if maxEvents > 0 {
+		_, err := s.db.Exec(`DELETE FROM events WHERE guid NOT IN (SELECT guid FROM events ORDER BY time DESC LIMIT ?)`, maxEvents)
+		if err != nil {
+			return err
+		}
+	}
+	if eventsMaxAge > 0 {
+		_, err := s.db.Exec(`DELETE FROM events WHERE time < ?`, sqlTime(time.Now().Add(-eventsMaxAge)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// facet returns the counts of the top values of the column, and the count of the other values as "Other"
+func (s *SQLStore) facet(table, column string, where sqlWhere, size int) (map[string]int, error) {
+	total, err := s.count(table, where)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	other := total
+	err = s.query(`SELECT `+column+`, COUNT(*) AS count FROM `+table+where.String()+` GROUP BY `+column+` ORDER BY count DESC, `+column+` LIMIT ?`, append(where.args, size), func(rows *sql.Rows) error {
+		var (
+			value string
+			count int
+		)
+		if err := rows.Scan(&value, &count); err != nil {
+			return err
+		}
+		counts[value] = count
+		other -= count
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count the %s by %s: %w", table, column, err)
+	}
+	counts["Other"] = other
+	return counts, nil
+}
+
+// histogram returns the date histogram of the given time column:
+// if there is no lower bound, we use the oldest row, and if there is no upper bound, we use now
+func (s *SQLStore) histogram(table, column string, where sqlWhere, from, to time.Time) (Histogram, error) {
+	if from.IsZero() {
+		var oldest sql.NullString
+		if err := s.db.QueryRow(`SELECT MIN(`+column+`) FROM `+table+where.String(), where.args...).Scan(&oldest); err != nil {
+			return nil, fmt.Errorf("failed to retrieve the oldest of the %s: %w", table, err)
+		}
+		from = parseSQLTime(oldest)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	histogram := histogramBuckets(from, to)
+	if len(histogram) == 0 {
+		return nil, nil
+	}
+
+	var (
+		start       = histogram[0].Start
+		interval    = histogram[0].End.Sub(start)
+		bucketWhere = where.clone()
+	)
+	bucketWhere.timeRange(column, start, histogram[len(histogram)-1].End)
+	args := append([]interface{}{start.Unix(), int64(interval.Seconds())}, bucketWhere.args...)
+	err := s.query(`SELECT (CAST(strftime('%s', `+column+`) AS INTEGER) - ?) / ? AS bucket, COUNT(*) FROM `+table+bucketWhere.String()+` GROUP BY bucket`, args, func(rows *sql.Rows) error {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return err
+		}
+		if bucket >= 0 && bucket < len(histogram) {
+			histogram[bucket].Count = count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the histogram of the %s: %w", table, err)
+	}
+	return histogram, nil
+}
+
+// count returns the number of rows of the table matching the where clause
+func (s *SQLStore) count(table string, where sqlWhere) (int, error) {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM `+table+where.String(), where.args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count the %s: %w", table, err)
+	}
+	return count, nil
+}
+
+func (s *SQLStore) query(query string, args []interface{}, fn func(*sql.Rows) error) error {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	return iterateRows(rows, fn)
+}
+
+func queryTx(tx *sql.Tx, query string, args []interface{}, fn func(*sql.Rows) error) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	return iterateRows(rows, fn)
+}
+
+func iterateRows(rows *sql.Rows, fn func(*sql.Rows) error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanJob(rows *sql.Rows) (Job, error) {
+	var (
+		job        Job
+		start, end sql.NullString
+		duration   int64
+		pullSHAs   string
+	)
+	err := rows.Scan(&job.Name, &job.Type, &job.EventGUID, &job.Owner, &job.Repository, &job.Branch, &job.Build, &job.Context,
+		&job.Author, &job.State, &job.Description, &job.ReportURL, &job.TraceID, &start, &end, &duration, &job.BaseSHA, &pullSHAs)
+	if err != nil {
+		return job, err
+	}
+	job.Start = parseSQLTime(start)
+	job.End = parseSQLTime(end)
+	job.Duration = time.Duration(duration)
+	if err = json.Unmarshal([]byte(pullSHAs), &job.PullSHAs); err != nil {
+		return job, fmt.Errorf("invalid pull SHAs for job %s: %w", job.Name, err)
+	}
+	return job, nil
+}
+
+func scanEvent(rows *sql.Rows) (Event, error) {
+	var (
+		event      Event
+		eventTime  sql.NullString
+		labels     string
+		commitSHAs string
+	)
+	err := rows.Scan(&event.GUID, &eventTime, &event.Owner, &event.Repository, &event.Branch, &event.Kind, &event.Action,
+		&event.Details, &event.URL, &event.Sender, &labels, &event.SHA, &event.BeforeSHA, &commitSHAs)
+	if err != nil {
+		return event, err
+	}
+	event.Time = parseSQLTime(eventTime)
+	if err = json.Unmarshal([]byte(labels), &event.Labels); err != nil {
+		return event, fmt.Errorf("invalid labels for event %s: %w", event.GUID, err)
+	}
+	if err = json.Unmarshal([]byte(commitSHAs), &event.CommitSHAs); err != nil {
+		return event, fmt.Errorf("invalid commit SHAs for event %s: %w", event.GUID, err)
+	}
+	return event, nil
+}
+
+// scanJSON decodes a JSON column, keeping the numbers as json.Number - like when we parse the Keeper documents
+func scanJSON(rows *sql.Rows, v interface{}) error {
+	var data []byte
+	if err := rows.Scan(&data); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func sqlTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(sqlTimeFormat)
+}
+
+func parseSQLTime(value sql.NullString) time.Time {
+	if !value.Valid {
+		return time.Time{}
+	}
+	t, _ := time.Parse(sqlTimeFormat, value.String)
+	return t
+}
+
+// sqlWhere is a WHERE clause made of conditions which must all be true
+type sqlWhere struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *sqlWhere) add(condition string, args ...interface{}) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+func (w *sqlWhere) equals(column, value string) {
+	if value != "" {
+		w.add(column+" = ?", value)
+	}
+}
+
+// sha restricts the rows to the ones with the commit in the column, or in the JSON array of the list column
+func (w *sqlWhere) sha(sha, column, listColumn string) {
+	if sha != "" {
+		w.add("("+column+" = ? OR EXISTS (SELECT 1 FROM json_each("+listColumn+") WHERE value = ?))", sha, sha)
+	}
+}
+
+// timeRange restricts the column to the range between from (inclusive) and to (exclusive)
+func (w *sqlWhere) timeRange(column string, from, to time.Time) {
+	if !from.IsZero() {
+		w.add(column+" >= ?", sqlTime(from))
+	}
+	if !to.IsZero() {
+		w.add(column+" < ?", sqlTime(to))
+	}
+}
+
+func (w sqlWhere) clone() sqlWhere {
+	return sqlWhere{
+		conditions: append([]string(nil), w.conditions...),
+		args:       append([]interface{}(nil), w.args...),
+	}
+}
+
+func (w sqlWhere) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+func (q JobsQuery) toSQLWhere() (sqlWhere, error) {
+	var where sqlWhere
+	if err := where.queryString(q.Query, JobQueryFields, jobQueryFields); err != nil {
+		return where, err
+	}
+	where.equals("name", q.Name)
+	where.equals("event_guid", q.EventGUID)
+	where.equals("owner", q.Owner)
+	where.equals("repository", q.Repository)
+	where.equals("branch", q.Branch)
+	where.equals("author", q.Author)
+	where.sha(q.SHA, "base_sha", "jobs.pull_shas")
+	where.timeRange("start_time", q.From, q.To)
+	return where, nil
+}
+
+func (q EventsQuery) toSQLWhere() (sqlWhere, error) {
+	var where sqlWhere
+	if err := where.queryString(q.Query, EventQueryFields, eventQueryFields); err != nil {
+		return where, err
+	}
+	where.equals("guid", q.GUID)
+	where.equals("owner", q.Owner)
+	where.equals("repository", q.Repository)
+	where.equals("branch", q.Branch)
+	where.equals("sender", q.Sender)
+	where.sha(q.SHA, "sha", "events.commit_shas")
+	where.timeRange("time", q.From, q.To)
+	return where, nil
+}
+
+// queryString translates the supported subset of the query string syntax to SQL conditions, like the Bleve store:
+// the first term is required unless it is prefixed with + or -, the terms prefixed with + must match,
+// the ones prefixed with - must not match, and the other terms only need to match - at least one of them - if no term is required
+func (w *sqlWhere) queryString(queryString string, fields []QueryField, columns map[string]string) error {
+	terms, err := ParseQueryString(queryString, fields)
+	if err != nil {
+		return err
+	}
+
+	fieldTypes := make(map[string]string, len(fields))
+	for _, field := range fields {
+		fieldTypes[field.Name] = field.Type
+	}
+
+	var (
+		shouldConditions []string
+		shouldArgs       []interface{}
+		hasRequiredTerms bool
+	)
+	for i, term := range terms {
+		if i == 0 && term.Occur == 0 {
+			term.Occur = '+'
+		}
+		if term.Field == "" {
+			return &QueryError{Query: queryString, Message: fmt.Sprintf("unsupported term %q: the SQL store only supports Field:value terms", term.Value)}
+		}
+		column, found := columns[term.Field]
+		if !found {
+			return &QueryError{Query: queryString, Message: fmt.Sprintf("the %s field is not supported by the SQL store", term.Field)}
+		}
+
+		operator, value := term.Operator, term.Value
+		if operator == "" {
+			operator = "="
+		}
+		if fieldTypes[term.Field] == QueryFieldTypeDate {
+			// the dates are stored in a fixed-width format, to be compared as strings - and were validated by ParseQueryString
+			date, _ := parseQueryDate(value)
+			value = date.UTC().Format(sqlTimeFormat)
+		}
+		if operator == "=" && strings.ContainsAny(value, "*?") {
+			operator = "LIKE"
+			value = strings.NewReplacer("%", `\%`, "_", `\_`, "*", "%", "?", "_").Replace(value)
+		}
+		condition := column + " " + operator + " ?"
+		if operator == "LIKE" {
+			condition += ` ESCAPE '\'`
+		}
+
+		switch term.Occur {
+		case '+':
+			hasRequiredTerms = true
+			w.add(condition, value)
+		case '-':
+			w.add("NOT ("+condition+")", value)
+		default:
+			shouldConditions = append(shouldConditions, condition)
+			shouldArgs = append(shouldArgs, value)
+		}
+	}
+	if len(shouldConditions) > 0 && !hasRequiredTerms {
+		w.add("("+strings.Join(shouldConditions, " OR ")+")", shouldArgs...)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package webui
+
+import (
+	"database/sql"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestSQLStore(t *testing.T, dataPath string) *SQLStore {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store, err := NewSQLStore(StoreConfig{DataPath: dataPath}, logger)
+	if err != nil {
+		t.Fatalf("failed to create the SQL store: %v", err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+	})
+	return store
+}
+
+// storeFixtures are the jobs and events indexed in both stores, to compare their results
+func storeFixtures() ([]Job, []Event) {
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	jobs := []Job{
+		{Name: "job-1", Type: "presubmit", Owner: "my-org", Repository: "app", Branch: "PR-1", Context: "unit-tests", Author: "alice", State: "failure", BaseSHA: "base1", PullSHAs: []string{"pull1"}},
+		{Name: "job-2", Type: "presubmit", Owner: "my-org", Repository: "app", Branch: "PR-1", Context: "lint", Author: "alice", State: "success", BaseSHA: "base1", PullSHAs: []string{"pull1"}},
+		{Name: "job-3", Type: "postsubmit", Owner: "my-org", Repository: "app", Branch: "main", Context: "release", Author: "bob", State: "success", BaseSHA: "base2"},
+		{Name: "job-4", Type: "batch", Owner: "my-org", Repository: "app", Branch: "batch", Context: "unit-tests", Author: "bob", State: "failure", BaseSHA: "base2", PullSHAs: []string{"pull1", "pull2"}},
+		{Name: "job-5", Type: "presubmit", Owner: "my-org", Repository: "lib", Branch: "PR-7", Context: "unit-tests", Author: "carol", State: "pending", BaseSHA: "base3"},
+		{Name: "job-6", Type: "periodic", Owner: "other-org", Repository: "app", Branch: "main", Context: "nightly", State: "error"},
+		{Name: "job-7", Type: "postsubmit", Owner: "other-org", Repository: "app", Branch: "feature/x", Context: "release", Author: "alice", State: "aborted", BaseSHA: "base4"},
+	}
+	for i := range jobs {
+		jobs[i].Start = start.Add(time.Duration(i) * 30 * time.Minute)
+		jobs[i].End = jobs[i].Start.Add(10 * time.Minute)
+		jobs[i].Duration = 10 * time.Minute
+	}
+
+	events := []Event{
+		{GUID: "event-1", Owner: "my-org", Repository: "app", Branch: "PR-1", Kind: "pull_request", Action: "opened", Sender: "alice", SHA: "pull1"},
+		{GUID: "event-2", Owner: "my-org", Repository: "app", Branch: "PR-1", Kind: "pull_request", Action: "labeled", Sender: "bob", SHA: "pull1", Labels: []string{"approved"}},
+		{GUID: "event-3", Owner: "my-org", Repository: "app", Branch: "main", Kind: "push", Sender: "bob", SHA: "base2", BeforeSHA: "base1", CommitSHAs: []string{"base2", "pull1"}},
+		{GUID: "event-4", Owner: "my-org", Repository: "lib", Branch: "PR-7", Kind: "issue_comment", Action: "created", Sender: "carol", Details: "/retest"},
+		{GUID: "event-5", Owner: "other-org", Repository: "app", Branch: "feature/x", Kind: "push", Sender: "alice", SHA: "base4"},
+		{GUID: "event-6", Owner: "other-org", Repository: "app", Branch: "main", Kind: "push", Sender: "dave", SHA: "base5"},
+	}
+	for i := range events {
+		events[i].Time = start.Add(time.Duration(i) * 30 * time.Minute)
+	}
+	return jobs, events
+}
+
+// TestStoresReturnTheSameResults runs the same queries against the Bleve and SQL stores:
+// the SQL store only supports a subset of the query string syntax, but must give the same results for it
+func TestStoresReturnTheSameResults(t *testing.T) {
+	var (
+		bleveStore   = newTestStore(t)
+		sqlStore     = newTestSQLStore(t, "")
+		stores       = map[string]Store{"bleve": bleveStore, "sql": sqlStore}
+		jobs, events = storeFixtures()
+		start        = jobs[0].Start
+	)
+	for _, store := range stores {
+		for _, job := range jobs {
+			if err := store.AddJob(job); err != nil {
+				t.Fatalf("failed to add job %s: %v", job.Name, err)
+			}
+		}
+		for _, event := range events {
+			if err := store.AddEvent(event); err != nil {
+				t.Fatalf("failed to add event %s: %v", event.GUID, err)
+			}
+		}
+	}
+
+	jobsQueries := []struct {
+		name     string
+		query    JobsQuery
+		expected []string
+	}{
+		{name: "all", query: JobsQuery{}, expected: []string{"job-7", "job-6", "job-5", "job-4", "job-3", "job-2", "job-1"}},
+		{name: "name", query: JobsQuery{Name: "job-3"}, expected: []string{"job-3"}},
+		{name: "repository", query: JobsQuery{Owner: "my-org", Repository: "app"}, expected: []string{"job-4", "job-3", "job-2", "job-1"}},
+		{name: "branch with a slash", query: JobsQuery{Owner: "other-org", Repository: "app", Branch: "feature/x"}, expected: []string{"job-7"}},
+		{name: "author", query: JobsQuery{Author: "alice"}, expected: []string{"job-7", "job-2", "job-1"}},
+		{name: "base SHA", query: JobsQuery{SHA: "base2"}, expected: []string{"job-4", "job-3"}},
+		{name: "pull SHA", query: JobsQuery{SHA: "pull2"}, expected: []string{"job-4"}},
+		{name: "time range", query: JobsQuery{From: start.Add(30 * time.Minute), To: start.Add(90 * time.Minute)}, expected: []string{"job-3", "job-2"}},
+		{name: "single term", query: JobsQuery{Query: "State:failure"}, expected: []string{"job-4", "job-1"}},
+		{name: "first term is required", query: JobsQuery{Query: "State:failure Type:presubmit"}, expected: []string{"job-4", "job-1"}},
+		{name: "required terms", query: JobsQuery{Query: "+State:failure +Type:presubmit"}, expected: []string{"job-1"}},
+		{name: "excluded term", query: JobsQuery{Query: "Context:unit-tests -Type:batch"}, expected: []string{"job-5", "job-1"}},
+		{name: "excluded first term", query: JobsQuery{Query: "-Owner:my-org State:error State:aborted"}, expected: []string{"job-7", "job-6"}},
+		{name: "wildcard", query: JobsQuery{Query: "Branch:PR-*"}, expected: []string{"job-5", "job-2", "job-1"}},
+		{name: "quoted value", query: JobsQuery{Query: `Branch:"feature/x"`}, expected: []string{"job-7"}},
+		{name: "date comparison", query: JobsQuery{Query: `Start:>="2021-06-01T11:30:00Z"`}, expected: []string{"job-7", "job-6", "job-5", "job-4"}},
+		{name: "date only comparison", query: JobsQuery{Query: `Start:<"2021-06-02"`}, expected: []string{"job-7", "job-6", "job-5", "job-4", "job-3", "job-2", "job-1"}},
+		{name: "query and fields", query: JobsQuery{Owner: "my-org", Query: "State:success"}, expected: []string{"job-3", "job-2"}},
+		{name: "no match", query: JobsQuery{Query: "State:unknown"}, expected: nil},
+	}
+	for _, test := range jobsQueries {
+		t.Run("jobs "+test.name, func(t *testing.T) {
+			for name, store := range stores {
+				result, err := store.QueryJobs(test.query)
+				if err != nil {
+					t.Fatalf("%s: failed to query the jobs: %v", name, err)
+				}
+				var actual []string
+				for _, job := range result.Jobs {
+					actual = append(actual, job.Name)
+				}
+				if !reflect.DeepEqual(actual, test.expected) {
+					t.Errorf("%s: expected the jobs %v, got %v", name, test.expected, actual)
+				}
+
+				count, err := store.CountJobs(test.query)
+				if err != nil {
+					t.Fatalf("%s: failed to count the jobs: %v", name, err)
+				}
+				if count != len(test.expected) {
+					t.Errorf("%s: expected a count of %d jobs, got %d", name, len(test.expected), count)
+				}
+
+				var exported []string
+				err = store.ExportJobs(test.query, func(job Job) error {
+					exported = append(exported, job.Name)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("%s: failed to export the jobs: %v", name, err)
+				}
+				if !reflect.DeepEqual(exported, test.expected) {
+					t.Errorf("%s: expected the exported jobs %v, got %v", name, test.expected, exported)
+				}
+			}
+		})
+	}
+
+	eventsQueries := []struct {
+		name     string
+		query    EventsQuery
+		expected []string
+	}{
+		{name: "all", query: EventsQuery{}, expected: []string{"event-6", "event-5", "event-4", "event-3", "event-2", "event-1"}},
+		{name: "guid", query: EventsQuery{GUID: "event-4"}, expected: []string{"event-4"}},
+		{name: "branch", query: EventsQuery{Owner: "my-org", Repository: "app", Branch: "PR-1"}, expected: []string{"event-2", "event-1"}},
+		{name: "sender", query: EventsQuery{Sender: "bob"}, expected: []string{"event-3", "event-2"}},
+		{name: "head SHA", query: EventsQuery{SHA: "base4"}, expected: []string{"event-5"}},
+		{name: "pushed SHA", query: EventsQuery{SHA: "pull1"}, expected: []string{"event-3", "event-2", "event-1"}},
+		{name: "time range", query: EventsQuery{From: start.Add(60 * time.Minute), To: start.Add(120 * time.Minute)}, expected: []string{"event-4", "event-3"}},
+		{name: "single term", query: EventsQuery{Query: "Kind:push"}, expected: []string{"event-6", "event-5", "event-3"}},
+		{name: "first term is required", query: EventsQuery{Query: "Kind:push Sender:alice"}, expected: []string{"event-6", "event-5", "event-3"}},
+		{name: "required terms", query: EventsQuery{Query: "+Kind:push +Sender:alice"}, expected: []string{"event-5"}},
+		{name: "excluded term", query: EventsQuery{Query: "Owner:my-org -Kind:pull_request"}, expected: []string{"event-4", "event-3"}},
+		{name: "excluded first term", query: EventsQuery{Query: "-Kind:push Sender:carol Sender:alice"}, expected: []string{"event-4", "event-1"}},
+		{name: "wildcard", query: EventsQuery{Query: "Details:*retest"}, expected: []string{"event-4"}},
+		{name: "date comparison", query: EventsQuery{Query: `Time:<"2021-06-01T11:00:00Z"`}, expected: []string{"event-2", "event-1"}},
+		{name: "no match", query: EventsQuery{Query: "Kind:unknown"}, expected: nil},
+	}
+	for _, test := range eventsQueries {
+		t.Run("events "+test.name, func(t *testing.T) {
+			for name, store := range stores {
+				result, err := store.QueryEvents(test.query)
+				if err != nil {
+					t.Fatalf("%s: failed to query the events: %v", name, err)
+				}
+				var actual []string
+				for _, event := range result.Events {
+					actual = append(actual, event.GUID)
+				}
+				if !reflect.DeepEqual(actual, test.expected) {
+					t.Errorf("%s: expected the events %v, got %v", name, test.expected, actual)
+				}
+
+				count, err := store.CountEvents(test.query)
+				if err != nil {
+					t.Fatalf("%s: failed to count the events: %v", name, err)
+				}
+				if count != len(test.expected) {
+					t.Errorf("%s: expected a count of %d events, got %d", name, len(test.expected), count)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLStoreMigratesThePreviousSchema(t *testing.T) {
+	dataPath := t.TempDir()
+
+	// a database at the first version of the schema
+	db, err := sql.Open(sqliteDriverName, "file:"+dataPath+"/"+sqliteFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		`CREATE TABLE schema_migrations (version INTEGER NOT NULL)`,
+		sqlMigrations[0],
+		`INSERT INTO schema_migrations (version) VALUES (1)`,
+		`INSERT INTO jobs (name, owner, repository, branch, state, start_time) VALUES ('job-1', 'my-org', 'app', 'main', 'success', '2021-06-01T10:00:00.000Z')`,
+		`INSERT INTO events (guid, time, owner, repository, kind) VALUES ('event-1', '2021-06-01T10:00:00.000Z', 'my-org', 'app', 'push')`,
+	} {
+		if _, err = db.Exec(statement); err != nil {
+			t.Fatalf("failed to create the v1 database: %v", err)
+		}
+	}
+	if err = db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newTestSQLStore(t, dataPath)
+	var version int
+	if err = store.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(sqlMigrations) {
+		t.Errorf("expected the schema version %d, got %d", len(sqlMigrations), version)
+	}
+
+	jobs, err := store.QueryJobs(JobsQuery{Owner: "my-org", Query: "State:success"})
+	if err != nil {
+		t.Fatalf("failed to query the migrated jobs: %v", err)
+	}
+	if len(jobs.Jobs) != 1 || jobs.Jobs[0].Name != "job-1" || len(jobs.Jobs[0].PullSHAs) != 0 {
+		t.Errorf("expected the migrated job-1, got %+v", jobs.Jobs)
+	}
+	events, err := store.QueryEvents(EventsQuery{Query: "Kind:push"})
+	if err != nil {
+		t.Fatalf("failed to query the migrated events: %v", err)
+	}
+	if len(events.Events) != 1 || events.Events[0].GUID != "event-1" {
+		t.Errorf("expected the migrated event-1, got %+v", events.Events)
+	}
+
+	// the new columns can be used
+	err = store.AddJob(Job{Name: "job-2", Owner: "my-org", State: "success", BaseSHA: "base1", PullSHAs: []string{"pull1"}, Start: time.Now()})
+	if err != nil {
+		t.Fatalf("failed to add a job to the migrated store: %v", err)
+	}
+	jobs, err = store.QueryJobs(JobsQuery{SHA: "pull1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Jobs) != 1 || jobs.Jobs[0].Name != "job-2" {
+		t.Errorf("expected job-2 for its pull SHA, got %+v", jobs.Jobs)
+	}
+}
@@ -0,0 +1,176 @@
+package webui
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+// tracedStore records the store operations as spans - but only as part of an existing trace,
+// to avoid creating new traces for every lookup done by the templates
+type tracedStore struct {
+	Store
+	ctx context.Context
+}
+
+// NewTracedStore returns a store which can record its operations as spans, once bound to a context with StoreWithContext
+func NewTracedStore(store Store) Store {
+	return &tracedStore{
+		Store: store,
+		ctx:   context.Background(),
+	}
+}
+
+// StoreWithContext returns a store which records its operations as children of the span of the given context - if the store is traced
+func StoreWithContext(ctx context.Context, store Store) Store {
+	if traced, ok := store.(*tracedStore); ok {
+		return &tracedStore{
+			Store: traced.Store,
+			ctx:   ctx,
+		}
+	}
+	return store
+}
+
+func (s *tracedStore) startSpan(operation string, attributes ...attribute.KeyValue) trace.Span {
+	if !trace.SpanContextFromContext(s.ctx).IsValid() {
+		return trace.SpanFromContext(s.ctx)
+	}
+	_, span := otel.Tracer(tracerName).Start(s.ctx, "store."+operation,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attributes...),
+	)
+	return span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedStore) AddJob(j Job) (err error) {
+	span := s.startSpan("AddJob", attribute.String("job.name", j.Name))
+	defer func() { endSpan(span, err) }()
+	return s.Store.AddJob(j)
+}
+
+func (s *tracedStore) DeleteJob(name string) (err error) {
+	span := s.startSpan("DeleteJob", attribute.String("job.name", name))
+	defer func() { endSpan(span, err) }()
+	return s.Store.DeleteJob(name)
+}
+
+func (s *tracedStore) QueryJobs(q JobsQuery) (jobs *Jobs, err error) {
+	span := s.startSpan("QueryJobs", attribute.String("query", q.Query))
+	defer func() { endSpan(span, err) }()
+	return s.Store.QueryJobs(q)
+}
+
+func (s *tracedStore) CountJobs(q JobsQuery) (count int, err error) {
+	span := s.startSpan("CountJobs", attribute.String("query", q.Query))
+	defer func() { endSpan(span, err) }()
+	return s.Store.CountJobs(q)
+}
+
+func (s *tracedStore) QueryJobFieldValues(field, prefix string, limit int) (values []FieldValue, err error) {
+	span := s.startSpan("QueryJobFieldValues", attribute.String("field", field), attribute.String("prefix", prefix))
+	defer func() { endSpan(span, err) }()
+	return s.Store.QueryJobFieldValues(field, prefix, limit)
+}
+
+func (s *tracedStore) ExportJobs(q JobsQuery, fn func(Job) error) (err error) {
+	span := s.startSpan("ExportJobs", attribute.String("query", q.Query))
+	defer func() { endSpan(span, err) }()
+	return s.Store.ExportJobs(q, fn)
+}
+
+func (s *tracedStore) AddEvent(e Event) (err error) {
+	span := s.startSpan("AddEvent", attribute.String("event.guid", e.GUID), attribute.String("event.kind", e.Kind))
+	defer func() { endSpan(span, err) }()
+	return s.Store.AddEvent(e)
+}
+
+func (s *tracedStore) QueryEvents(q EventsQuery) (events *Events, err error) {
+	span := s.startSpan("QueryEvents", attribute.String("query", q.Query))
+	defer func() { endSpan(span, err) }()
+	return s.Store.QueryEvents(q)
+}
+
+func (s *tracedStore) CountEvents(q EventsQuery) (count int, err error) {
+	span := s.startSpan("CountEvents", attribute.String("query", q.Query))
+	defer func() { endSpan(span, err) }()
+	return s.Store.CountEvents(q)
+}
+
+func (s *tracedStore) ExportEvents(q EventsQuery, fn func(Event) error) (err error) {
+	span := s.startSpan("ExportEvents", attribute.String("query", q.Query))
+	defer func() { endSpan(span, err) }()
+	return s.Store.ExportEvents(q, fn)
+}
+
+func (s *tracedStore) QueryEventFieldValues(field, prefix string, limit int) (values []FieldValue, err error) {
+	span := s.startSpan("QueryEventFieldValues", attribute.String("field", field), attribute.String("prefix", prefix))
+	defer func() { endSpan(span, err) }()
+	return s.Store.QueryEventFieldValues(field, prefix, limit)
+}
+
+func (s *tracedStore) SetMergeStatus(source string, pools []MergePool) {
+	span := s.startSpan("SetMergeStatus", attribute.String("keeper", source), attribute.Int("pools", len(pools)))
+	defer span.End()
+	s.Store.SetMergeStatus(source, pools)
+}
+
+func (s *tracedStore) QueryMergeStatus(q MergeStatusQuery) []MergePool {
+	span := s.startSpan("QueryMergeStatus")
+	defer span.End()
+	return s.Store.QueryMergeStatus(q)
+}
+
+func (s *tracedStore) SetMergeHistory(source string, records []MergeRecord) {
+	span := s.startSpan("SetMergeHistory", attribute.String("keeper", source), attribute.Int("records", len(records)))
+	defer span.End()
+	s.Store.SetMergeHistory(source, records)
+}
+
+func (s *tracedStore) QueryMergeHistory(q MergeHistoryQuery) []MergeRecord {
+	span := s.startSpan("QueryMergeHistory")
+	defer span.End()
+	return s.Store.QueryMergeHistory(q)
+}
+
+func (s *tracedStore) SaveSearch(search SavedSearch) (err error) {
+	span := s.startSpan("SaveSearch", attribute.String("search.name", search.Name))
+	defer func() { endSpan(span, err) }()
+	return s.Store.SaveSearch(search)
+}
+
+func (s *tracedStore) DeleteSavedSearch(user, name string) (err error) {
+	span := s.startSpan("DeleteSavedSearch", attribute.String("search.name", name))
+	defer func() { endSpan(span, err) }()
+	return s.Store.DeleteSavedSearch(user, name)
+}
+
+func (s *tracedStore) QuerySavedSearches(user string) (searches []SavedSearch, err error) {
+	span := s.startSpan("QuerySavedSearches")
+	defer func() { endSpan(span, err) }()
+	return s.Store.QuerySavedSearches(user)
+}
+
+func (s *tracedStore) ExportSavedSearches() (searches []SavedSearch, err error) {
+	span := s.startSpan("ExportSavedSearches")
+	defer func() { endSpan(span, err) }()
+	return s.Store.ExportSavedSearches()
+}
+
+func (s *tracedStore) SetGarbageCollectionLimits(maxEvents int, eventsMaxAge time.Duration) {
+	s.Store.SetGarbageCollectionLimits(maxEvents, eventsMaxAge)
+}
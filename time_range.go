@@ -0,0 +1,127 @@
+package webui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHistogramBuckets is the maximum number of buckets of a date histogram
+const maxHistogramBuckets = 48
+
+// histogramIntervals are the "human friendly" intervals we can use for the buckets of a date histogram
+var histogramIntervals = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	3 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+}
+
+// Histogram is the number of documents over time, sorted by time
+type Histogram []HistogramBucket
+
+type HistogramBucket struct {
+	// Start is inclusive, End is exclusive
+	Start time.Time
+	End   time.Time
+	Count int
+}
+
+// MaxCount returns the count of the biggest bucket
+func (h Histogram) MaxCount() int {
+	var maxCount int
+	for _, bucket := range h {
+		if bucket.Count > maxCount {
+			maxCount = bucket.Count
+		}
+	}
+	return maxCount
+}
+
+// ParseTime parses either an absolute time or a time relative to now.
+// It supports:
+// - RFC3339 timestamps: 2021-06-01T10:00:00Z
+// - dates and times, in UTC: 2021-06-01 or 2021-06-01T10:00
+// - now
+// - relative times: "last 24h", "now-24h" or "-24h", with the "d" (day) and "w" (week) units in addition to the Go duration units
+// The empty string is parsed as the zero time.
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if value == "now" {
+		return now, nil
+	}
+
+	for _, prefix := range []string{"last ", "now-", "-"} {
+		if !strings.HasPrefix(value, prefix) {
+			continue
+		}
+		d, err := ParseDuration(strings.TrimPrefix(value, prefix))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q: %w", value, err)
+		}
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected a date such as 2021-06-01, a RFC3339 timestamp or a relative time such as 'last 24h'", value)
+}
+
+// ParseDuration is like time.ParseDuration, but it also supports the "d" (day) and "w" (week) units,
+// as a single unit - such as "7d" or "2w"
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if !strings.HasSuffix(value, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(value, suffix))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * unit, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// histogramBuckets splits the given time range in (empty) buckets of the same size
+func histogramBuckets(from, to time.Time) Histogram {
+	if from.IsZero() || !to.After(from) {
+		return nil
+	}
+
+	span := to.Sub(from)
+	interval := histogramIntervals[len(histogramIntervals)-1]
+	for _, i := range histogramIntervals {
+		if span/i < maxHistogramBuckets {
+			interval = i
+			break
+		}
+	}
+	if span/interval >= maxHistogramBuckets {
+		interval = (span / maxHistogramBuckets).Truncate(24 * time.Hour)
+	}
+
+	var histogram Histogram
+	for start := from.Truncate(interval); start.Before(to); start = start.Add(interval) {
+		histogram = append(histogram, HistogramBucket{
+			Start: start,
+			End:   start.Add(interval),
+		})
+	}
+	return histogram
+}
@@ -0,0 +1,118 @@
+package webui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		value       string
+		expected    time.Time
+		expectedErr bool
+	}{
+		{value: "", expected: time.Time{}},
+		{value: "  ", expected: time.Time{}},
+		{value: "now", expected: now},
+		{value: "last 7d", expected: now.Add(-7 * 24 * time.Hour)},
+		{value: "last 2w", expected: now.Add(-14 * 24 * time.Hour)},
+		{value: "last 24h", expected: now.Add(-24 * time.Hour)},
+		{value: "now-90m", expected: now.Add(-90 * time.Minute)},
+		{value: "-1h30m", expected: now.Add(-90 * time.Minute)},
+		{value: " last 7d ", expected: now.Add(-7 * 24 * time.Hour)},
+		{value: "2021-06-01", expected: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2021-06-01T10:00", expected: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2021-06-01T10:00:30", expected: time.Date(2021, 6, 1, 10, 0, 30, 0, time.UTC)},
+		{value: "2021-06-01T10:00:00Z", expected: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2021-06-01T10:00:00+02:00", expected: time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{value: "last", expectedErr: true},
+		{value: "last 7", expectedErr: true},
+		{value: "last 1.5d", expectedErr: true},
+		{value: "last week", expectedErr: true},
+		{value: "yesterday", expectedErr: true},
+		{value: "2021-13-01", expectedErr: true},
+		{value: "01/06/2021", expectedErr: true},
+		{value: "2021-06-01 10:00", expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			actual, err := ParseTime(test.value, now)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !actual.Equal(test.expected) {
+				t.Errorf("expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	from := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		from             time.Time
+		to               time.Time
+		expectedInterval time.Duration
+		expectedBuckets  int
+	}{
+		{name: "no start", to: from, expectedBuckets: 0},
+		{name: "empty range", from: from, to: from, expectedBuckets: 0},
+		{name: "reversed range", from: from, to: from.Add(-time.Hour), expectedBuckets: 0},
+		{name: "last 30m", from: from, to: from.Add(30 * time.Minute), expectedInterval: time.Minute, expectedBuckets: 30},
+		{name: "last 1h", from: from, to: from.Add(time.Hour), expectedInterval: 5 * time.Minute, expectedBuckets: 12},
+		{name: "last 24h", from: from, to: from.Add(24 * time.Hour), expectedInterval: time.Hour, expectedBuckets: 24},
+		{name: "last 7d", from: from, to: from.Add(7 * 24 * time.Hour), expectedInterval: 6 * time.Hour, expectedBuckets: 28},
+		{name: "last 30d", from: from, to: from.Add(30 * 24 * time.Hour), expectedInterval: 24 * time.Hour, expectedBuckets: 31},
+		{name: "last 365d", from: from, to: from.Add(365 * 24 * time.Hour), expectedInterval: 30 * 24 * time.Hour, expectedBuckets: 13},
+		{name: "unaligned start", from: from.Add(7 * time.Minute), to: from.Add(time.Hour), expectedInterval: 5 * time.Minute, expectedBuckets: 11},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			histogram := histogramBuckets(test.from, test.to)
+			if len(histogram) != test.expectedBuckets {
+				t.Fatalf("expected %d buckets, got %d: %v", test.expectedBuckets, len(histogram), histogram)
+			}
+			if len(histogram) > maxHistogramBuckets {
+				t.Errorf("expected at most %d buckets, got %d", maxHistogramBuckets, len(histogram))
+			}
+			for i, bucket := range histogram {
+				if interval := bucket.End.Sub(bucket.Start); interval != test.expectedInterval {
+					t.Errorf("expected an interval of %s for bucket %d, got %s", test.expectedInterval, i, interval)
+				}
+				if i > 0 && !bucket.Start.Equal(histogram[i-1].End) {
+					t.Errorf("expected bucket %d to start at the end of the previous bucket %s, got %s", i, histogram[i-1].End, bucket.Start)
+				}
+			}
+			if len(histogram) > 0 {
+				if first := histogram[0]; first.Start.After(test.from) || !first.End.After(test.from) {
+					t.Errorf("expected the first bucket %s-%s to contain the start %s", first.Start, first.End, test.from)
+				}
+				if last := histogram[len(histogram)-1]; !last.End.After(test.to.Add(-time.Nanosecond)) || !last.Start.Before(test.to) {
+					t.Errorf("expected the last bucket %s-%s to contain the end %s", last.Start, last.End, test.to)
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramMaxCount(t *testing.T) {
+	histogram := Histogram{{Count: 2}, {Count: 7}, {Count: 0}}
+	if maxCount := histogram.MaxCount(); maxCount != 7 {
+		t.Errorf("expected a max count of 7, got %d", maxCount)
+	}
+	if maxCount := (Histogram{}).MaxCount(); maxCount != 0 {
+		t.Errorf("expected a max count of 0 for an empty histogram, got %d", maxCount)
+	}
+}
@@ -0,0 +1,163 @@
+package webui
+
+import (
+	"time"
+)
+
+// approvedLabel is the label added on a PR once it has been approved
+const approvedLabel = "approved"
+
+// UserActivity is everything a user did: the webhook events they sent, the jobs on their PRs,
+// their PRs currently in the merge pools, and their merged PRs
+type UserActivity struct {
+	Login     string
+	Events    []Event
+	Jobs      []Job
+	PoolPRs   []UserPoolPullRequest
+	MergedPRs []UserMergedPullRequest
+	Stats     UserStats
+}
+
+// UserPoolPullRequest is a PR in a merge pool
+type UserPoolPullRequest struct {
+	PullRequest
+	Source     string
+	Owner      string
+	Repository string
+	Branch     string
+	// Status is the list of the pool the PR is in: success, pending, missing or batch
+	Status string
+}
+
+// UserMergedPullRequest is a PR from a merge record
+type UserMergedPullRequest struct {
+	PullRequest
+	Source     string
+	Owner      string
+	Repository string
+	Branch     string
+	MergedAt   time.Time
+	// ApprovedAt is zero if we don't have any event with the approved label for this PR
+	ApprovedAt time.Time
+}
+
+func (pr UserMergedPullRequest) ApprovalToMerge() time.Duration {
+	if pr.ApprovedAt.IsZero() || pr.MergedAt.Before(pr.ApprovedAt) {
+		return 0
+	}
+	return pr.MergedAt.Sub(pr.ApprovedAt)
+}
+
+type UserStats struct {
+	MergedPRs     int
+	CompletedJobs int
+	FailedJobs    int
+	// JobFailureRate is the ratio (between 0 and 1) of failed jobs on the user's PRs
+	JobFailureRate float64
+	// AvgApprovalToMerge is the average duration between the approval and the merge of the user's PRs
+	AvgApprovalToMerge time.Duration
+}
+
+func userPoolPullRequests(login string, pools []MergePool) []UserPoolPullRequest {
+	var prs []UserPoolPullRequest
+	for _, pool := range pools {
+		for _, poolPRs := range []struct {
+			status string
+			prs    []PullRequest
+		}{
+			{"success", pool.SuccessPRs},
+			{"pending", pool.PendingPRs},
+			{"missing", pool.MissingPRs},
+			{"batch", pool.BatchPending},
+		} {
+			for _, pr := range poolPRs.prs {
+				if pr.Author != login {
+					continue
+				}
+				prs = append(prs, UserPoolPullRequest{
+					PullRequest: pr,
+					Source:      pool.Source,
+					Owner:       pool.Owner,
+					Repository:  pool.Repository,
+					Branch:      pool.Branch,
+					Status:      poolPRs.status,
+				})
+			}
+		}
+	}
+	return prs
+}
+
+func userMergedPullRequests(login string, records []MergeRecord) []UserMergedPullRequest {
+	var prs []UserMergedPullRequest
+	for _, record := range records {
+		if record.Action != "MERGE" && record.Action != "MERGE_BATCH" {
+			continue
+		}
+		for _, pr := range record.PRs {
+			if pr.Author != login {
+				continue
+			}
+			prs = append(prs, UserMergedPullRequest{
+				PullRequest: pr,
+				Source:      record.Source,
+				Owner:       record.Owner,
+				Repository:  record.Repository,
+				Branch:      record.Branch,
+				MergedAt:    record.Time,
+			})
+		}
+	}
+	return prs
+}
+
+// approvalTime returns the time of the first pull_request event with the approved label
+func approvalTime(events []Event) time.Time {
+	var approvedAt time.Time
+	for _, event := range events {
+		if event.Kind != "pull_request" {
+			continue
+		}
+		for _, label := range event.Labels {
+			if label == approvedLabel && (approvedAt.IsZero() || event.Time.Before(approvedAt)) {
+				approvedAt = event.Time
+			}
+		}
+	}
+	return approvedAt
+}
+
+func computeUserStats(jobs []Job, mergedPRs []UserMergedPullRequest) UserStats {
+	stats := UserStats{
+		MergedPRs: len(mergedPRs),
+	}
+
+	for _, job := range jobs {
+		switch job.State {
+		case "success":
+			stats.CompletedJobs++
+		case "failure", "error", "aborted":
+			stats.CompletedJobs++
+			stats.FailedJobs++
+		}
+	}
+	if stats.CompletedJobs > 0 {
+		stats.JobFailureRate = float64(stats.FailedJobs) / float64(stats.CompletedJobs)
+	}
+
+	var (
+		totalApprovalToMerge time.Duration
+		approvedPRs          int
+	)
+	for _, pr := range mergedPRs {
+		if d := pr.ApprovalToMerge(); d > 0 {
+			totalApprovalToMerge += d
+			approvedPRs++
+		}
+	}
+	if approvedPRs > 0 {
+		stats.AvgApprovalToMerge = (totalApprovalToMerge / time.Duration(approvedPRs)).Round(time.Second)
+	}
+
+	return stats
+}
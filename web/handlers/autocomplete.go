@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+// maxSuggestions is the maximum number of suggestions returned for a query
+const maxSuggestions = 10
+
+// AutocompleteHandler suggests the field names and their most frequent values, to complete the last term of a query string
+type AutocompleteHandler struct {
+	Store  webui.Store
+	Render *render.Render
+	Logger *logrus.Logger
+}
+
+// suggestion is a completion of the last term of the query
+type suggestion struct {
+	// Query is the full query, with the completed term
+	Query string
+	Term  string
+	// Count is the number of jobs or events with the suggested value - or zero for a field name
+	Count int
+}
+
+func (h *AutocompleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		page   = mux.Vars(r)["page"]
+		query  = r.URL.Query().Get("q")
+		fields = webui.JobQueryFields
+		store  = webui.StoreWithContext(r.Context(), h.Store)
+		values = store.QueryJobFieldValues
+	)
+	if page == webui.SavedSearchPageEvents {
+		fields = webui.EventQueryFields
+		values = store.QueryEventFieldValues
+	}
+
+	// only the last term is completed - unless it is a quoted value which is still being typed
+	base, term := "", query
+	if i := strings.LastIndexAny(query, " \t"); i >= 0 {
+		base, term = query[:i+1], query[i+1:]
+	}
+	occur := ""
+	if strings.HasPrefix(term, "+") || strings.HasPrefix(term, "-") {
+		occur, term = term[:1], term[1:]
+	}
+
+	suggestions := []suggestion{}
+	if field, valuePrefix, found := strings.Cut(term, ":"); found {
+		for _, f := range fields {
+			if f.Name != field || f.Type != webui.QueryFieldTypeKeyword || strings.ContainsAny(valuePrefix, `"*?<>`) {
+				continue
+			}
+			fieldValues, err := values(field, valuePrefix, maxSuggestions)
+			if err != nil {
+				h.Logger.WithError(err).WithField("field", field).Warning("failed to retrieve the values to autocomplete")
+				break
+			}
+			for _, value := range fieldValues {
+				completedTerm := occur + field + ":" + quoteQueryValue(value.Value)
+				suggestions = append(suggestions, suggestion{
+					Query: base + completedTerm,
+					Term:  completedTerm,
+					Count: value.Count,
+				})
+			}
+		}
+	} else {
+		for _, f := range fields {
+			if len(suggestions) < maxSuggestions && strings.HasPrefix(strings.ToLower(f.Name), strings.ToLower(term)) {
+				completedTerm := occur + f.Name + ":"
+				suggestions = append(suggestions, suggestion{
+					Query: base + completedTerm,
+					Term:  completedTerm,
+				})
+			}
+		}
+	}
+
+	err := h.Render.JSON(w, http.StatusOK, struct {
+		Fields      []webui.QueryField
+		Suggestions []suggestion
+	}{
+		fields,
+		suggestions,
+	})
+	if err != nil {
+		h.Logger.WithError(err).Error("failed to encode suggestions in JSON")
+	}
+}
+
+// quoteQueryValue quotes the values with spaces or characters which have a meaning in the query string syntax
+func quoteQueryValue(value string) string {
+	if !strings.ContainsAny(value, " \t:\"\\^~*?()") && !strings.ContainsAny(value[:1], "+-<>/") {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+// storeErrorStatus returns the HTTP status for an error of the store - which is a bad request for an invalid query
+func storeErrorStatus(err error) int {
+	var queryErr *webui.QueryError
+	if errors.As(err, &queryErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
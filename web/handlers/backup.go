@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+// BackupHandler downloads (GET /backup) or restores (POST /restore) a backup archive of the store
+// both are authenticated with a bearer token
+type BackupHandler struct {
+	Store  webui.Store
+	Token  string
+	Render *render.Render
+	Logger *logrus.Logger
+}
+
+func (h *BackupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !hasBearerToken(r, h.Token) {
+		h.Logger.WithField("path", r.URL.Path).WithField("UA", r.UserAgent()).Warning("Rejected unauthorized backup request")
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/backup" && r.Method == http.MethodGet:
+		h.backup(w)
+	case r.URL.Path == "/restore" && r.Method == http.MethodPost:
+		h.restore(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *BackupHandler) backup(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="lighthouse-webui-backup-%s.tar.gz"`, time.Now().UTC().Format("20060102150405")))
+
+	// the archive is only written once it has been fully built, so we can still return an error status
+	manifest, err := webui.Backup(h.Store, w)
+	if err != nil {
+		h.Logger.WithError(err).Error("failed to backup the store")
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.Logger.WithField("events", manifest.Events).WithField("jobs", manifest.Jobs).WithField("mergeRecords", manifest.MergeRecords).Info("Backed up the store")
+}
+
+func (h *BackupHandler) restore(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	manifest, err := webui.Restore(h.Store, r.Body)
+	if err != nil {
+		h.Logger.WithError(err).Error("failed to restore the store")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.Logger.WithField("events", manifest.Events).WithField("jobs", manifest.Jobs).WithField("mergeRecords", manifest.MergeRecords).Info("Restored the store")
+	if manifest.MergeRecords > 0 {
+		h.Logger.Warning("The restored merge history will be replaced by the next sync of each Keeper")
+	}
+
+	if err = h.Render.JSON(w, http.StatusOK, manifest); err != nil {
+		h.Logger.WithError(err).Error("failed to encode the restored backup manifest in JSON")
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// BadgeHandler renders an SVG badge with the state of the latest postsubmit jobs of a branch
+type BadgeHandler struct {
+	Store webui.Store
+	// AllowedRepositories is a list of owner/repository patterns (in the path.Match format)
+	// for which badges can be rendered - if empty, all repositories are allowed
+	AllowedRepositories []string
+	CacheMaxAge         time.Duration
+	Logger              *logrus.Logger
+}
+
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{ .Width }}" height="20" role="img" aria-label="{{ html .Label }}: {{ html .Message }}">
+  <title>{{ html .Label }}: {{ html .Message }}</title>
+  <linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+  <clipPath id="r"><rect width="{{ .Width }}" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="{{ .LabelWidth }}" height="20" fill="#555"/>
+    <rect x="{{ .LabelWidth }}" width="{{ .MessageWidth }}" height="20" fill="{{ .Color }}"/>
+    <rect width="{{ .Width }}" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="{{ .LabelX }}" y="14">{{ html .Label }}</text>
+    <text x="{{ .MessageX }}" y="14">{{ html .Message }}</text>
+  </g>
+</svg>
+`))
+
+var badgeStates = map[string]struct {
+	message string
+	color   string
+}{
+	webui.HealthSuccess: {"passing", "#4c1"},
+	webui.HealthFailure: {"failing", "#e05d44"},
+	webui.HealthPending: {"running", "#007ec6"},
+	webui.HealthUnknown: {"unknown", "#9f9f9f"},
+}
+
+func (h *BadgeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars       = mux.Vars(r)
+		owner      = vars["owner"]
+		repository = vars["repository"]
+		branch     = vars["branch"]
+		context    = r.URL.Query().Get("context")
+	)
+
+	if !h.isAllowed(owner, repository) {
+		http.NotFound(w, r)
+		return
+	}
+
+	health, err := webui.QueryBranchHealth(webui.StoreWithContext(r.Context(), h.Store), webui.BranchHealthQuery{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     branch,
+		Context:    context,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	state, found := badgeStates[health.Health]
+	if !found {
+		state = badgeStates[webui.HealthUnknown]
+	}
+	label := context
+	if label == "" {
+		label = branch
+	}
+
+	sb := new(strings.Builder)
+	labelWidth, messageWidth := badgeTextWidth(label), badgeTextWidth(state.message)
+	err = badgeTemplate.Execute(sb, map[string]interface{}{
+		"Label":        label,
+		"Message":      state.message,
+		"Color":        state.color,
+		"Width":        labelWidth + messageWidth,
+		"LabelWidth":   labelWidth,
+		"MessageWidth": messageWidth,
+		"LabelX":       labelWidth / 2,
+		"MessageX":     labelWidth + messageWidth/2,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	badge := sb.String()
+	sum := sha1.Sum([]byte(badge))
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	w.Header().Set("Content-Type", "image/svg+xml;charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.CacheMaxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write([]byte(badge))
+}
+
+func (h *BadgeHandler) isAllowed(owner, repository string) bool {
+	if len(h.AllowedRepositories) == 0 {
+		return true
+	}
+
+	fullName := owner + "/" + repository
+	for _, pattern := range h.AllowedRepositories {
+		if matched, _ := path.Match(pattern, fullName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// badgeTextWidth is an approximation of the width of the text, with its padding
+func badgeTextWidth(text string) int {
+	return len(text)*7 + 10
+}
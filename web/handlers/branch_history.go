@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+type BranchHistoryHandler struct {
+	Store  webui.Store
+	Render *render.Render
+	Logger *logrus.Logger
+}
+
+func (h *BranchHistoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars       = mux.Vars(r)
+		owner      = vars["owner"]
+		repository = vars["repository"]
+		branch     = vars["branch"]
+		renderJSON = strings.HasSuffix(branch, ".json")
+	)
+	branch = strings.TrimSuffix(branch, ".json")
+
+	from, to, err := timeRangeFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := webui.QueryBranchHistory(webui.StoreWithContext(r.Context(), h.Store), webui.BranchHistoryQuery{
+		Owner:      owner,
+		Repository: repository,
+		Branch:     branch,
+		From:       from,
+		To:         to,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if renderJSON {
+		err = h.Render.JSON(w, http.StatusOK, history)
+		if err != nil {
+			h.Logger.WithError(err).Error("failed to encode branch history in JSON")
+		}
+		return
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "branch_history", struct {
+		History *webui.BranchHistory
+		From    string
+		To      string
+	}{
+		history,
+		r.URL.Query().Get("from"),
+		r.URL.Query().Get("to"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+// shaRegExp matches the full SHA-1 or SHA-256 commits - the abbreviated commits can't be matched exactly
+var shaRegExp = regexp.MustCompile("^[0-9a-f]{40}([0-9a-f]{24})?$")
+
+type CommitHandler struct {
+	Store  webui.Store
+	Render *render.Render
+	Logger *logrus.Logger
+}
+
+func (h *CommitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars       = mux.Vars(r)
+		owner      = vars["owner"]
+		repository = vars["repository"]
+		sha        = strings.ToLower(vars["sha"])
+		renderJSON = strings.HasSuffix(r.URL.Path, ".json")
+	)
+
+	if !shaRegExp.MatchString(sha) {
+		http.Error(w, "invalid commit SHA: the full SHA is required", http.StatusBadRequest)
+		return
+	}
+
+	activity, err := webui.QueryCommit(webui.StoreWithContext(r.Context(), h.Store), webui.CommitQuery{
+		Owner:      owner,
+		Repository: repository,
+		SHA:        sha,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if renderJSON {
+		err = h.Render.JSON(w, http.StatusOK, activity)
+		if err != nil {
+			h.Logger.WithError(err).Error("failed to encode commit activity in JSON")
+		}
+		return
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "commit", struct {
+		Activity *webui.CommitActivity
+	}{
+		activity,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
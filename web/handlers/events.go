@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
@@ -12,32 +14,61 @@ import (
 )
 
 type EventsHandler struct {
-	Store  *webui.Store
+	Store  webui.Store
 	Render *render.Render
 	Logger *logrus.Logger
 }
 
 func (h *EventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var (
-		vars       = mux.Vars(r)
-		owner      = vars["owner"]
-		repository = vars["repository"]
-		branch     = vars["branch"]
-		query      = r.URL.Query().Get("q")
+		vars         = mux.Vars(r)
+		owner        = vars["owner"]
+		repository   = vars["repository"]
+		branch       = vars["branch"]
+		query        = r.URL.Query().Get("q")
+		feedFormat   = feedFormatFromPath(r.URL.Path)
+		exportFormat = exportFormatFromPath(r.URL.Path)
 	)
 
 	if strings.HasPrefix(branch, "pr-") {
 		branch = strings.ToUpper(branch)
 	}
 
-	events, err := h.Store.QueryEvents(webui.EventsQuery{
+	from, to, err := timeRangeFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q := webui.EventsQuery{
 		Owner:      owner,
 		Repository: repository,
 		Branch:     branch,
 		Query:      query,
-	})
+		From:       from,
+		To:         to,
+	}
+	if err = q.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if exportFormat != "" {
+		h.export(w, r, exportFormat, q)
+		return
+	}
+
+	// the histogram is only displayed on the HTML page
+	q.Histogram = feedFormat == ""
+	events, err := webui.StoreWithContext(r.Context(), h.Store).QueryEvents(q)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	if feedFormat != "" {
+		if err = writeFeed(w, feedFormat, eventsFeed(r, events)); err != nil {
+			h.Logger.WithError(err).Error("failed to write events feed")
+		}
 		return
 	}
 
@@ -47,15 +78,70 @@ func (h *EventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Repository string
 		Branch     string
 		Query      string
+		// From and To are the raw values of the time range, as entered by the user
+		From string
+		To   string
 	}{
 		events,
 		owner,
 		repository,
 		branch,
 		query,
+		r.URL.Query().Get("from"),
+		r.URL.Query().Get("to"),
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
+
+// export streams all the events matching the query, without any limit
+func (h *EventsHandler) export(w http.ResponseWriter, r *http.Request, format string, q webui.EventsQuery) {
+	e, err := newExporter(w, r, format, "events", eventExportColumns)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = webui.StoreWithContext(r.Context(), h.Store).ExportEvents(q, func(event webui.Event) error {
+		return e.write(eventExportValues(event))
+	})
+	if err == nil {
+		err = e.close()
+	}
+	if err != nil {
+		// the headers have already been written, so we can only log the error
+		h.Logger.WithError(err).Error("failed to export events")
+	}
+}
+
+func eventsFeed(r *http.Request, events *webui.Events) feed {
+	var (
+		baseURL = requestBaseURL(r)
+		f       = feed{
+			Title: strings.TrimSpace("Lighthouse Events " + strings.TrimPrefix(uiPath(r.URL.Path), "/events/")),
+			Link:  baseURL + uiPath(r.URL.Path),
+			Self:  baseURL + r.URL.RequestURI(),
+		}
+	)
+	if q := r.URL.Query().Get("q"); q != "" {
+		f.Link += "?q=" + url.QueryEscape(q)
+	}
+
+	for _, event := range events.Events {
+		title := event.Kind
+		if event.Details != "" {
+			title = fmt.Sprintf("%s: %s", event.Kind, event.Details)
+		}
+		f.Entries = append(f.Entries, feedEntry{
+			ID:      "urn:lighthouse-webui:event:" + event.GUID,
+			Title:   fmt.Sprintf("[%s/%s %s] %s", event.Owner, event.Repository, event.Branch, title),
+			Link:    fmt.Sprintf("%s/events/%s/%s/%s?q=GUID:%s", baseURL, event.Owner, event.Repository, event.Branch, url.QueryEscape(event.GUID)),
+			Summary: event.Details,
+			Author:  event.Sender,
+			Updated: event.Time,
+		})
+	}
+	return f
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportFlushInterval is the number of rows after which we flush the response, so that big exports are streamed
+const exportFlushInterval = 500
+
+var (
+	jobExportColumns = []string{
+		"Name", "Type", "EventGUID", "Owner", "Repository", "Branch", "Build", "Context",
+		"Author", "State", "Description", "ReportURL", "TraceID", "Start", "End", "Duration", "BaseSHA", "PullSHAs",
+	}
+	eventExportColumns = []string{
+		"GUID", "Time", "Owner", "Repository", "Branch", "Kind", "Action", "Details", "URL", "Sender", "Labels", "SHA", "BeforeSHA", "CommitSHAs",
+	}
+	// merge records are exported with 1 row per PR
+	mergeRecordExportColumns = []string{
+		"Time", "Source", "Owner", "Repository", "Branch", "Action", "BaseSHA", "Number", "Title", "Author", "SHA",
+	}
+)
+
+func exportFormatFromPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, "."+exportFormatCSV):
+		return exportFormatCSV
+	case strings.HasSuffix(path, "."+exportFormatNDJSON):
+		return exportFormatNDJSON
+	default:
+		return ""
+	}
+}
+
+// exporter writes rows in CSV or NDJSON, with only the selected columns
+type exporter struct {
+	w       http.ResponseWriter
+	format  string
+	columns []string
+	csv     *csv.Writer
+	json    *json.Encoder
+	rows    int
+}
+
+// newExporter writes the headers of the response - so it must be called once we know the export won't fail early.
+// The columns are read from the comma-separated "columns" query parameter, and default to all the available columns.
+func newExporter(w http.ResponseWriter, r *http.Request, format, name string, availableColumns []string) (*exporter, error) {
+	columns := availableColumns
+	if selected := r.URL.Query().Get("columns"); selected != "" {
+		columns = nil
+		for _, column := range strings.Split(selected, ",") {
+			column = strings.TrimSpace(column)
+			if !containsString(availableColumns, column) {
+				return nil, fmt.Errorf("unknown column %q - available columns are %s", column, strings.Join(availableColumns, ","))
+			}
+			columns = append(columns, column)
+		}
+	}
+
+	e := &exporter{
+		w:       w,
+		format:  format,
+		columns: columns,
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	switch format {
+	case exportFormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		e.csv = csv.NewWriter(w)
+		if err := e.csv.Write(columns); err != nil {
+			return nil, err
+		}
+	default:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		e.json = json.NewEncoder(w)
+	}
+	return e, nil
+}
+
+func (e *exporter) write(values map[string]interface{}) error {
+	if e.csv != nil {
+		record := make([]string, 0, len(e.columns))
+		for _, column := range e.columns {
+			record = append(record, exportCSVValue(values[column]))
+		}
+		if err := e.csv.Write(record); err != nil {
+			return err
+		}
+	} else {
+		row := make(map[string]interface{}, len(e.columns))
+		for _, column := range e.columns {
+			row[column] = values[column]
+		}
+		if err := e.json.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	e.rows++
+	if e.rows%exportFlushInterval == 0 {
+		e.flush()
+	}
+	return nil
+}
+
+func (e *exporter) close() error {
+	e.flush()
+	if e.csv != nil {
+		return e.csv.Error()
+	}
+	return nil
+}
+
+func (e *exporter) flush() {
+	if e.csv != nil {
+		e.csv.Flush()
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func exportCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ",")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// exportTime returns an empty string for the zero time, so that it is easier to handle in a spreadsheet
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func jobExportValues(job webui.Job) map[string]interface{} {
+	pullSHAs := job.PullSHAs
+	if pullSHAs == nil {
+		pullSHAs = []string{}
+	}
+	return map[string]interface{}{
+		"Name":        job.Name,
+		"Type":        job.Type,
+		"EventGUID":   job.EventGUID,
+		"Owner":       job.Owner,
+		"Repository":  job.Repository,
+		"Branch":      job.Branch,
+		"Build":       job.Build,
+		"Context":     job.Context,
+		"Author":      job.Author,
+		"State":       job.State,
+		"Description": job.Description,
+		"ReportURL":   job.ReportURL,
+		"TraceID":     job.TraceID,
+		"Start":       exportTime(job.Start),
+		"End":         exportTime(job.End),
+		// in seconds
+		"Duration": job.Duration.Seconds(),
+		"BaseSHA":  job.BaseSHA,
+		"PullSHAs": pullSHAs,
+	}
+}
+
+func eventExportValues(event webui.Event) map[string]interface{} {
+	labels := event.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	commitSHAs := event.CommitSHAs
+	if commitSHAs == nil {
+		commitSHAs = []string{}
+	}
+	return map[string]interface{}{
+		"GUID":       event.GUID,
+		"Time":       exportTime(event.Time),
+		"Owner":      event.Owner,
+		"Repository": event.Repository,
+		"Branch":     event.Branch,
+		"Kind":       event.Kind,
+		"Action":     event.Action,
+		"Details":    event.Details,
+		"URL":        event.URL,
+		"Sender":     event.Sender,
+		"Labels":     labels,
+		"SHA":        event.SHA,
+		"BeforeSHA":  event.BeforeSHA,
+		"CommitSHAs": commitSHAs,
+	}
+}
+
+func mergeRecordExportValues(record webui.MergeRecord, pr webui.PullRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"Time":       exportTime(record.Time),
+		"Source":     record.Source,
+		"Owner":      record.Owner,
+		"Repository": record.Repository,
+		"Branch":     record.Branch,
+		"Action":     record.Action,
+		"BaseSHA":    record.BaseSHA,
+		"Number":     pr.Number,
+		"Title":      pr.Title,
+		"Author":     pr.Author,
+		"SHA":        pr.SHA,
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	feedFormatAtom = "atom"
+	feedFormatRSS  = "rss"
+
+	// maxFeedEntries is the max number of (most recent) entries in a feed
+	maxFeedEntries = 50
+)
+
+type feed struct {
+	Title   string
+	Link    string
+	Self    string
+	Updated time.Time
+	Entries []feedEntry
+}
+
+type feedEntry struct {
+	// ID must be stable, so that feed readers don't see the same entry twice
+	ID      string
+	Title   string
+	Link    string
+	Summary string
+	Author  string
+	Updated time.Time
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Summary string      `xml:"summary,omitempty"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	GUID        rssGUID `xml:"guid"`
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	Author      string  `xml:"author,omitempty"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// feedFormatFromPath returns the feed format from the path extension, or an empty string if it's not a feed
+func feedFormatFromPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".atom"):
+		return feedFormatAtom
+	case strings.HasSuffix(path, ".rss"):
+		return feedFormatRSS
+	default:
+		return ""
+	}
+}
+
+// requestBaseURL returns the scheme://host of the request, to build absolute links
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// uiPath returns the path of the UI page for a feed path
+func uiPath(feedPath string) string {
+	feedPath = strings.TrimSuffix(feedPath, "."+feedFormatAtom)
+	feedPath = strings.TrimSuffix(feedPath, "."+feedFormatRSS)
+	return feedPath
+}
+
+func writeFeed(w http.ResponseWriter, format string, f feed) error {
+	if len(f.Entries) > maxFeedEntries {
+		f.Entries = f.Entries[:maxFeedEntries]
+	}
+	for _, entry := range f.Entries {
+		if entry.Updated.After(f.Updated) {
+			f.Updated = entry.Updated
+		}
+	}
+
+	var doc interface{}
+	switch format {
+	case feedFormatRSS:
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		channel := rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Title,
+		}
+		if !f.Updated.IsZero() {
+			channel.LastBuildDate = f.Updated.Format(time.RFC1123Z)
+		}
+		for _, entry := range f.Entries {
+			channel.Items = append(channel.Items, rssItem{
+				GUID:        rssGUID{Value: entry.ID},
+				Title:       entry.Title,
+				Link:        entry.Link,
+				Description: entry.Summary,
+				Author:      entry.Author,
+				PubDate:     entry.Updated.Format(time.RFC1123Z),
+			})
+		}
+		doc = rssFeed{Version: "2.0", Channel: channel}
+	default:
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		atom := atomFeed{
+			ID:      f.Self,
+			Title:   f.Title,
+			Updated: f.Updated.Format(time.RFC3339),
+			Links: []atomLink{
+				{Href: f.Link, Rel: "alternate"},
+				{Href: f.Self, Rel: "self"},
+			},
+		}
+		for _, entry := range f.Entries {
+			atomEntry := atomEntry{
+				ID:      entry.ID,
+				Title:   entry.Title,
+				Updated: entry.Updated.Format(time.RFC3339),
+				Link:    atomLink{Href: entry.Link, Rel: "alternate"},
+				Summary: entry.Summary,
+			}
+			if entry.Author != "" {
+				atomEntry.Author = &atomAuthor{Name: entry.Author}
+			}
+			atom.Entries = append(atom.Entries, atomEntry)
+		}
+		doc = atom
+	}
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
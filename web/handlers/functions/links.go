@@ -1,13 +1,57 @@
 package functions
 
 import (
+	"fmt"
 	"strings"
+	"sync/atomic"
 	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
 )
 
-func TraceURLFunc(eventTraceURLTemplate *template.Template) func(string) string {
+// URLTemplate is a Go template used to build URLs, which can be changed at runtime
+type URLTemplate struct {
+	value atomic.Value
+}
+
+// parsedURLTemplate wraps the template, because an atomic.Value can't store a nil value
+type parsedURLTemplate struct {
+	template *template.Template
+}
+
+func NewURLTemplate(text string) (*URLTemplate, error) {
+	t := new(URLTemplate)
+	if err := t.Set(text); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Set parses and replaces the template - an empty text disables the URLs
+func (t *URLTemplate) Set(text string) error {
+	var parsed parsedURLTemplate
+	if len(text) > 0 {
+		tpl, err := template.New("url").Funcs(sprig.TxtFuncMap()).Parse(text)
+		if err != nil {
+			return err
+		}
+		parsed.template = tpl
+	}
+	t.value.Store(parsed)
+	return nil
+}
+
+func (t *URLTemplate) template() *template.Template {
+	if t == nil {
+		return nil
+	}
+	parsed, _ := t.value.Load().(parsedURLTemplate)
+	return parsed.template
+}
+
+func TraceURLFunc(eventTraceURLTemplate *URLTemplate) func(string) string {
 	return func(traceID string) string {
-		return traceIDToTraceURL(traceID, eventTraceURLTemplate)
+		return traceIDToTraceURL(traceID, eventTraceURLTemplate.template())
 	}
 }
 
@@ -28,3 +72,79 @@ func traceIDToTraceURL(traceID string, eventTraceURLTemplate *template.Template)
 	}
 	return sb.String()
 }
+
+// Link is an external link - to the logs, a dashboard, ... - of a job or event
+type Link struct {
+	Name string
+	URL  string
+}
+
+// NamedTemplate is the text of a named link template
+type NamedTemplate struct {
+	Name string
+	Text string
+}
+
+// LinkTemplates are the named Go templates used to build the external links of the jobs and events, which can be changed at runtime
+type LinkTemplates struct {
+	value atomic.Value
+}
+
+type parsedLinkTemplate struct {
+	name     string
+	template *template.Template
+}
+
+func NewLinkTemplates(templates []NamedTemplate) (*LinkTemplates, error) {
+	t := new(LinkTemplates)
+	if err := t.Set(templates); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Set parses and replaces all the templates
+func (t *LinkTemplates) Set(templates []NamedTemplate) error {
+	parsed := make([]parsedLinkTemplate, 0, len(templates))
+	for _, namedTemplate := range templates {
+		tpl, err := template.New(namedTemplate.Name).Funcs(sprig.TxtFuncMap()).Parse(namedTemplate.Text)
+		if err != nil {
+			return fmt.Errorf("invalid link template %s: %w", namedTemplate.Name, err)
+		}
+		parsed = append(parsed, parsedLinkTemplate{
+			name:     namedTemplate.Name,
+			template: tpl,
+		})
+	}
+	t.value.Store(parsed)
+	return nil
+}
+
+func (t *LinkTemplates) templates() []parsedLinkTemplate {
+	if t == nil {
+		return nil
+	}
+	parsed, _ := t.value.Load().([]parsedLinkTemplate)
+	return parsed
+}
+
+// LinksFunc returns the links of a job or event.
+// A template which can't be executed - for example because it uses a field of a job on an event - or which renders an empty URL, is skipped.
+func LinksFunc(linkTemplates *LinkTemplates) func(interface{}) []Link {
+	return func(data interface{}) []Link {
+		var links []Link
+		for _, t := range linkTemplates.templates() {
+			sb := new(strings.Builder)
+			if err := t.template.Execute(sb, data); err != nil {
+				continue
+			}
+			if url := strings.TrimSpace(sb.String()); url != "" {
+				links = append(links, Link{
+					Name: t.name,
+					URL:  url,
+				})
+			}
+		}
+		return links
+	}
+}
@@ -4,13 +4,13 @@ import (
 	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
 )
 
-func LoadJobsForEventFunc(store *webui.Store) func(string) []webui.Job {
+func LoadJobsForEventFunc(store webui.Store) func(string) []webui.Job {
 	return func(eventGUID string) []webui.Job {
 		return loadJobsForEvent(eventGUID, store)
 	}
 }
 
-func loadJobsForEvent(eventGUID string, store *webui.Store) []webui.Job {
+func loadJobsForEvent(eventGUID string, store webui.Store) []webui.Job {
 	if store == nil {
 		return nil
 	}
@@ -28,13 +28,13 @@ func loadJobsForEvent(eventGUID string, store *webui.Store) []webui.Job {
 	return jobs.Jobs
 }
 
-func LoadEventForJobFunc(store *webui.Store) func(string) *webui.Event {
+func LoadEventForJobFunc(store webui.Store) func(string) *webui.Event {
 	return func(eventGUID string) *webui.Event {
 		return loadEventForJob(eventGUID, store)
 	}
 }
 
-func loadEventForJob(eventGUID string, store *webui.Store) *webui.Event {
+func loadEventForJob(eventGUID string, store webui.Store) *webui.Event {
 	if store == nil {
 		return nil
 	}
@@ -55,3 +55,29 @@ func loadEventForJob(eventGUID string, store *webui.Store) *webui.Event {
 
 	return &(events.Events)[0]
 }
+
+func LoadBlockingReasonsFunc(store webui.Store) func(string, string, int) []webui.BlockingReason {
+	return func(owner, repository string, number int) []webui.BlockingReason {
+		return loadBlockingReasons(owner, repository, number, store)
+	}
+}
+
+func loadBlockingReasons(owner, repository string, number int, store webui.Store) []webui.BlockingReason {
+	if store == nil {
+		return nil
+	}
+	if number <= 0 {
+		return nil
+	}
+
+	reasons, err := webui.QueryBlockingReasons(store, webui.BlockingReasonsQuery{
+		Owner:      owner,
+		Repository: repository,
+		Number:     number,
+	})
+	if err != nil {
+		return nil
+	}
+
+	return reasons
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"strings"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
 
 	"github.com/gorilla/mux"
 	lighthousev1alpha1 "github.com/jenkins-x/lighthouse/pkg/client/clientset/versioned/typed/lighthouse/v1alpha1"
@@ -10,41 +14,115 @@ import (
 	"github.com/unrolled/render"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/cli-runtime/pkg/printers"
 )
 
 type JobHandler struct {
+	Store               webui.Store
 	LighthouseJobClient lighthousev1alpha1.LighthouseJobInterface
+	Pipelines           *webui.PipelineInformer
+	Archive             *webui.JobArchive
 	Render              *render.Render
 	Logger              *logrus.Logger
 }
 
 func (h *JobHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var (
-		vars    = mux.Vars(r)
-		jobName = vars["job"]
+		vars       = mux.Vars(r)
+		jobName    = vars["job"]
+		renderYAML = strings.HasSuffix(r.URL.Path, ".yaml")
 	)
 
+	if !renderYAML {
+		h.renderHTML(w, r, jobName)
+		return
+	}
+
 	ctx := context.Background()
 	job, err := h.LighthouseJobClient.Get(ctx, jobName, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			http.NotFound(w, r)
+			h.renderArchivedYAML(w, r, jobName)
 			return
 		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if job.APIVersion == "" {
-		job.APIVersion = "lighthouse.jenkins.io/v1alpha1"
+	err = webui.WriteLighthouseJobYAML(job, w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// renderHTML renders the details of a job from the store - which is faster than retrieving it from the Kubernetes API
+// or from the archive, once the job has been garbage-collected
+func (h *JobHandler) renderHTML(w http.ResponseWriter, r *http.Request, jobName string) {
+	jobs, err := webui.StoreWithContext(r.Context(), h.Store).QueryJobs(webui.JobsQuery{
+		Name: jobName,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	archived, err := h.Archive.Get(jobName)
+	if err != nil {
+		h.Logger.WithError(err).WithField("job", jobName).Warning("failed to retrieve the archived job")
+	}
+
+	var job webui.Job
+	switch {
+	case len(jobs.Jobs) > 0:
+		job = jobs.Jobs[0]
+	case archived != nil:
+		job = archived.Job
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	pipelineRuns, err := h.Pipelines.PipelineRunsForJob(jobName)
+	if err != nil {
+		h.Logger.WithError(err).WithField("job", jobName).Warning("failed to retrieve the pipeline runs")
+	}
+	if len(pipelineRuns) == 0 && archived != nil {
+		pipelineRuns = archived.PipelineRuns
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "job", struct {
+		Job          webui.Job
+		PipelineRuns []webui.PipelineRun
+		Archived     *webui.ArchivedJob
+	}{
+		job,
+		pipelineRuns,
+		archived,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// renderArchivedYAML renders the YAML of a job which has been garbage-collected, from the archive
+func (h *JobHandler) renderArchivedYAML(w http.ResponseWriter, r *http.Request, jobName string) {
+	archived, err := h.Archive.Get(jobName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	if job.Kind == "" {
-		job.Kind = "LighthouseJob"
+	if archived == nil {
+		http.NotFound(w, r)
+		return
 	}
-	err = new(printers.YAMLPrinter).PrintObj(job, w)
+
+	jobYAML, err := h.Archive.OpenBlob(archived.YAMLDigest)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer jobYAML.Close()
+	if _, err = io.Copy(w, jobYAML); err != nil {
+		h.Logger.WithError(err).WithField("job", jobName).Warning("failed to render the archived YAML")
+	}
 }
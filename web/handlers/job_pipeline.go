@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+// JobPipelineHandler renders the Tekton PipelineRuns of a job, and streams the logs of their steps
+// from the cluster - or from the archive, once they have been garbage-collected
+type JobPipelineHandler struct {
+	Store     webui.Store
+	Pipelines *webui.PipelineInformer
+	PodLogs   webui.PodLogsStreamer
+	Archive   *webui.JobArchive
+	Render    *render.Render
+	Logger    *logrus.Logger
+}
+
+func (h *JobPipelineHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars       = mux.Vars(r)
+		jobName    = vars["job"]
+		taskRun    = vars["taskrun"]
+		step       = vars["step"]
+		renderJSON = strings.HasSuffix(r.URL.Path, ".json")
+	)
+
+	pipelineRuns, err := h.Pipelines.PipelineRunsForJob(jobName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var archived *webui.ArchivedJob
+	if len(pipelineRuns) == 0 {
+		archived, err = h.Archive.Get(jobName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if archived != nil {
+			pipelineRuns = archived.PipelineRuns
+		}
+	}
+
+	if step != "" {
+		if archived != nil {
+			h.renderArchivedLogs(w, r, archived, taskRun, step)
+			return
+		}
+		h.streamLogs(w, r, pipelineRuns, taskRun, step)
+		return
+	}
+
+	if renderJSON {
+		if pipelineRuns == nil {
+			pipelineRuns = []webui.PipelineRun{}
+		}
+		err = h.Render.JSON(w, http.StatusOK, pipelineRuns)
+		if err != nil {
+			h.Logger.WithError(err).Error("failed to encode pipeline runs in JSON")
+		}
+		return
+	}
+
+	jobs, err := webui.StoreWithContext(r.Context(), h.Store).QueryJobs(webui.JobsQuery{
+		Name: jobName,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(jobs.Jobs) == 0 && len(pipelineRuns) == 0 && archived == nil {
+		http.NotFound(w, r)
+		return
+	}
+	job := webui.Job{Name: jobName}
+	switch {
+	case len(jobs.Jobs) > 0:
+		job = jobs.Jobs[0]
+	case archived != nil:
+		job = archived.Job
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "job_pipeline", struct {
+		Job          webui.Job
+		PipelineRuns []webui.PipelineRun
+		Archived     *webui.ArchivedJob
+		Synced       bool
+	}{
+		job,
+		pipelineRuns,
+		archived,
+		h.Pipelines == nil || h.Pipelines.HasSynced(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// streamLogs streams the logs of a step - following them while the step is running.
+// The pod and container are retrieved from the TaskRun, so that only the logs of the pipelines can be read.
+func (h *JobPipelineHandler) streamLogs(w http.ResponseWriter, r *http.Request, pipelineRuns []webui.PipelineRun, taskRunName, stepName string) {
+	var (
+		namespace string
+		taskRun   *webui.TaskRun
+	)
+	for _, pipelineRun := range pipelineRuns {
+		if taskRun = pipelineRun.TaskRun(taskRunName); taskRun != nil {
+			namespace = pipelineRun.Namespace
+			break
+		}
+	}
+	if taskRun == nil {
+		http.NotFound(w, r)
+		return
+	}
+	step := taskRun.Step(stepName)
+	if step == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if taskRun.PodName == "" || step.State == webui.PipelineStatePending {
+		http.Error(w, fmt.Sprintf("step %s has not started yet", stepName), http.StatusConflict)
+		return
+	}
+	if h.PodLogs == nil {
+		http.Error(w, "logs are not available", http.StatusNotImplemented)
+		return
+	}
+
+	logs, err := h.PodLogs.StreamLogs(r.Context(), namespace, taskRun.PodName, step.Container, step.State == webui.PipelineStateRunning)
+	if err != nil {
+		h.Logger.WithError(err).WithField("pod", taskRun.PodName).WithField("container", step.Container).Warning("failed to stream the logs")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer logs.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := logs.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF && r.Context().Err() == nil {
+				h.Logger.WithError(err).WithField("pod", taskRun.PodName).WithField("container", step.Container).Warning("failed to read the logs")
+			}
+			return
+		}
+	}
+}
+
+// renderArchivedLogs renders the logs of a step from the archive
+func (h *JobPipelineHandler) renderArchivedLogs(w http.ResponseWriter, r *http.Request, archived *webui.ArchivedJob, taskRunName, stepName string) {
+	digest := archived.LogsDigest(taskRunName, stepName)
+	if digest == "" {
+		http.NotFound(w, r)
+		return
+	}
+	logs, err := h.Archive.OpenBlob(digest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer logs.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err = io.Copy(w, logs); err != nil {
+		h.Logger.WithError(err).WithField("job", archived.Job.Name).Warning("failed to render the archived logs")
+	}
+}
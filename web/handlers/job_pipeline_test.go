@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakePodLogsStreamer struct {
+	logs string
+	err  error
+
+	namespace, pod, container string
+	follow                    bool
+}
+
+func (s *fakePodLogsStreamer) StreamLogs(ctx context.Context, namespace, pod, container string, follow bool) (io.ReadCloser, error) {
+	s.namespace, s.pod, s.container, s.follow = namespace, pod, container, follow
+	if s.err != nil {
+		return nil, s.err
+	}
+	return io.NopCloser(strings.NewReader(s.logs)), nil
+}
+
+func TestJobPipelineHandlerStreamLogs(t *testing.T) {
+	pipelineRuns := []webui.PipelineRun{
+		{
+			Name:      "pr-1",
+			Namespace: "jx",
+			TaskRuns: []webui.TaskRun{
+				{
+					Name:    "tr-1",
+					PodName: "tr-1-pod",
+					Steps: []webui.Step{
+						{Name: "build", Container: "step-build", State: webui.PipelineStateSucceeded},
+						{Name: "test", Container: "step-test", State: webui.PipelineStateRunning},
+						{Name: "publish", Container: "step-publish", State: webui.PipelineStatePending},
+					},
+				},
+				{
+					Name: "tr-2",
+					Steps: []webui.Step{
+						{Name: "build", Container: "step-build", State: webui.PipelineStatePending},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		taskRun, step  string
+		streamer       *fakePodLogsStreamer
+		noStreamer     bool
+		expectedStatus int
+		expectedBody   string
+		expectedFollow bool
+	}{
+		{
+			name:           "completed step",
+			taskRun:        "tr-1",
+			step:           "build",
+			streamer:       &fakePodLogsStreamer{logs: "building...\ndone\n"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "building...\ndone\n",
+		},
+		{
+			name:           "running step",
+			taskRun:        "tr-1",
+			step:           "test",
+			streamer:       &fakePodLogsStreamer{logs: "testing...\n"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "testing...\n",
+			expectedFollow: true,
+		},
+		{
+			name:           "pending step",
+			taskRun:        "tr-1",
+			step:           "publish",
+			streamer:       &fakePodLogsStreamer{},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "TaskRun without pod",
+			taskRun:        "tr-2",
+			step:           "build",
+			streamer:       &fakePodLogsStreamer{},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "unknown TaskRun",
+			taskRun:        "tr-3",
+			step:           "build",
+			streamer:       &fakePodLogsStreamer{},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown step",
+			taskRun:        "tr-1",
+			step:           "deploy",
+			streamer:       &fakePodLogsStreamer{},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "no logs streamer",
+			taskRun:        "tr-1",
+			step:           "build",
+			noStreamer:     true,
+			expectedStatus: http.StatusNotImplemented,
+		},
+		{
+			name:           "streamer error",
+			taskRun:        "tr-1",
+			step:           "build",
+			streamer:       &fakePodLogsStreamer{err: errors.New("pod not found")},
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			h := &JobPipelineHandler{Logger: logger}
+			if !test.noStreamer {
+				h.PodLogs = test.streamer
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/job/job-1/logs/"+test.taskRun+"/"+test.step, nil)
+			h.streamLogs(rec, req, pipelineRuns, test.taskRun, test.step)
+
+			if rec.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", test.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+			if rec.Body.String() != test.expectedBody {
+				t.Errorf("expected the logs %q, got %q", test.expectedBody, rec.Body.String())
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+				t.Errorf("unexpected content type %s", contentType)
+			}
+			if test.streamer.namespace != "jx" || test.streamer.pod != "tr-1-pod" || test.streamer.container != "step-"+test.step {
+				t.Errorf("unexpected container %s/%s/%s", test.streamer.namespace, test.streamer.pod, test.streamer.container)
+			}
+			if test.streamer.follow != test.expectedFollow {
+				t.Errorf("expected follow=%v, got %v", test.expectedFollow, test.streamer.follow)
+			}
+		})
+	}
+}
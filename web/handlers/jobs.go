@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
@@ -12,32 +14,61 @@ import (
 )
 
 type JobsHandler struct {
-	Store  *webui.Store
+	Store  webui.Store
 	Render *render.Render
 	Logger *logrus.Logger
 }
 
 func (h *JobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var (
-		vars       = mux.Vars(r)
-		owner      = vars["owner"]
-		repository = vars["repository"]
-		branch     = vars["branch"]
-		query      = r.URL.Query().Get("q")
+		vars         = mux.Vars(r)
+		owner        = vars["owner"]
+		repository   = vars["repository"]
+		branch       = vars["branch"]
+		query        = r.URL.Query().Get("q")
+		feedFormat   = feedFormatFromPath(r.URL.Path)
+		exportFormat = exportFormatFromPath(r.URL.Path)
 	)
 
 	if strings.HasPrefix(branch, "pr-") {
 		branch = strings.ToUpper(branch)
 	}
 
-	jobs, err := h.Store.QueryJobs(webui.JobsQuery{
+	from, to, err := timeRangeFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q := webui.JobsQuery{
 		Owner:      owner,
 		Repository: repository,
 		Branch:     branch,
 		Query:      query,
-	})
+		From:       from,
+		To:         to,
+	}
+	if err = q.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if exportFormat != "" {
+		h.export(w, r, exportFormat, q)
+		return
+	}
+
+	// the histogram is only displayed on the HTML page
+	q.Histogram = feedFormat == ""
+	jobs, err := webui.StoreWithContext(r.Context(), h.Store).QueryJobs(q)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	if feedFormat != "" {
+		if err = writeFeed(w, feedFormat, jobsFeed(r, jobs)); err != nil {
+			h.Logger.WithError(err).Error("failed to write jobs feed")
+		}
 		return
 	}
 
@@ -47,15 +78,74 @@ func (h *JobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Repository string
 		Branch     string
 		Query      string
+		// From and To are the raw values of the time range, as entered by the user
+		From string
+		To   string
 	}{
 		jobs,
 		owner,
 		repository,
 		branch,
 		query,
+		r.URL.Query().Get("from"),
+		r.URL.Query().Get("to"),
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
+
+// export streams all the jobs matching the query, without any limit
+func (h *JobsHandler) export(w http.ResponseWriter, r *http.Request, format string, q webui.JobsQuery) {
+	e, err := newExporter(w, r, format, "jobs", jobExportColumns)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = webui.StoreWithContext(r.Context(), h.Store).ExportJobs(q, func(job webui.Job) error {
+		return e.write(jobExportValues(job))
+	})
+	if err == nil {
+		err = e.close()
+	}
+	if err != nil {
+		// the headers have already been written, so we can only log the error
+		h.Logger.WithError(err).Error("failed to export jobs")
+	}
+}
+
+func jobsFeed(r *http.Request, jobs *webui.Jobs) feed {
+	var (
+		baseURL = requestBaseURL(r)
+		f       = feed{
+			Title: strings.TrimSpace("Lighthouse Jobs " + strings.TrimPrefix(uiPath(r.URL.Path), "/jobs/")),
+			Link:  baseURL + uiPath(r.URL.Path),
+			Self:  baseURL + r.URL.RequestURI(),
+		}
+	)
+	if q := r.URL.Query().Get("q"); q != "" {
+		f.Link += "?q=" + url.QueryEscape(q)
+	}
+
+	for _, job := range jobs.Jobs {
+		updated := job.End
+		if updated.IsZero() {
+			updated = job.Start
+		}
+		build := job.Context
+		if job.Build != "" {
+			build = fmt.Sprintf("%s #%s", job.Context, job.Build)
+		}
+		f.Entries = append(f.Entries, feedEntry{
+			ID:      "urn:lighthouse-webui:job:" + job.Name,
+			Title:   fmt.Sprintf("[%s/%s %s] %s: %s", job.Owner, job.Repository, job.Branch, build, job.State),
+			Link:    fmt.Sprintf("%s/jobs/%s/%s/%s?q=Name:%s", baseURL, job.Owner, job.Repository, job.Branch, url.QueryEscape(job.Name)),
+			Summary: job.Description,
+			Author:  job.Author,
+			Updated: updated,
+		})
+	}
+	return f
+}
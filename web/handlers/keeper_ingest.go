@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxKeeperDocumentSize is the max size of a document pushed to the ingestion endpoint
+const maxKeeperDocumentSize = 32 << 20
+
+// KeeperIngestHandler receives the merge pools and merge history JSON documents pushed by Keeper (or a sidecar)
+// which are the same documents that the KeeperSyncer retrieves when polling Keeper
+// the optional "source" query parameter is the name of the Keeper which pushed the documents
+type KeeperIngestHandler struct {
+	Store  webui.Store
+	Token  string
+	Logger *logrus.Logger
+}
+
+func (h *KeeperIngestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars     = mux.Vars(r)
+		document = vars["document"]
+		source   = r.URL.Query().Get("source")
+	)
+
+	if source == "" {
+		source = webui.DefaultKeeperName
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthorized(r) {
+		h.Logger.WithField("document", document).WithField("keeper", source).WithField("UA", r.UserAgent()).Warning("Rejected unauthorized Keeper state push")
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxKeeperDocumentSize)
+	defer body.Close()
+
+	switch document {
+	case "pools":
+		pools, err := webui.ParseKeeperPools(source, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		webui.StoreWithContext(r.Context(), h.Store).SetMergeStatus(source, pools)
+		h.Logger.WithField("keeper", source).WithField("pools", len(pools)).Trace("Ingested Keeper merge status")
+	case "history":
+		records, err := webui.ParseKeeperHistory(source, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		webui.StoreWithContext(r.Context(), h.Store).SetMergeHistory(source, records)
+		h.Logger.WithField("keeper", source).WithField("records", len(records)).Trace("Ingested Keeper merge history")
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *KeeperIngestHandler) isAuthorized(r *http.Request) bool {
+	return hasBearerToken(r, h.Token)
+}
+
+// hasBearerToken returns true if the request has the expected (non-empty) bearer token
+func hasBearerToken(r *http.Request, expectedToken string) bool {
+	if expectedToken == "" {
+		return false
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken)) == 1
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+type MergeBlockedHandler struct {
+	Store  webui.Store
+	Render *render.Render
+	Logger *logrus.Logger
+}
+
+func (h *MergeBlockedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars       = mux.Vars(r)
+		owner      = vars["owner"]
+		repository = vars["repository"]
+		source     = r.URL.Query().Get("source")
+	)
+
+	pools := webui.StoreWithContext(r.Context(), h.Store).QueryMergeStatus(webui.MergeStatusQuery{
+		Source:     source,
+		Owner:      owner,
+		Repository: repository,
+		Blocked:    true,
+	})
+
+	err := h.Render.HTML(w, http.StatusOK, "merge_blocked", struct {
+		Pools      []webui.MergePool
+		Owner      string
+		Repository string
+		Source     string
+	}{
+		pools,
+		owner,
+		repository,
+		source,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
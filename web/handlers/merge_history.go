@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
 
@@ -13,26 +16,44 @@ import (
 )
 
 type MergeHistoryHandler struct {
-	Store  *webui.Store
+	Store  webui.Store
 	Render *render.Render
 	Logger *logrus.Logger
 }
 
 func (h *MergeHistoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var (
-		vars       = mux.Vars(r)
-		owner      = vars["owner"]
-		repository = vars["repository"]
-		branch     = vars["branch"]
-		renderYAML = strings.HasSuffix(r.RequestURI, ".yaml")
+		vars         = mux.Vars(r)
+		owner        = vars["owner"]
+		repository   = vars["repository"]
+		branch       = vars["branch"]
+		source       = r.URL.Query().Get("source")
+		renderYAML   = strings.HasSuffix(r.URL.Path, ".yaml")
+		renderJSON   = strings.HasSuffix(r.URL.Path, ".json")
+		feedFormat   = feedFormatFromPath(r.URL.Path)
+		exportFormat = exportFormatFromPath(r.URL.Path)
 	)
 
-	records := h.Store.QueryMergeHistory(webui.MergeHistoryQuery{
+	from, to, err := timeRangeFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records := webui.StoreWithContext(r.Context(), h.Store).QueryMergeHistory(webui.MergeHistoryQuery{
+		Source:     source,
 		Owner:      owner,
 		Repository: repository,
 		Branch:     branch,
+		From:       from,
+		To:         to,
 	})
 
+	if exportFormat != "" {
+		h.export(w, r, exportFormat, records)
+		return
+	}
+
 	if renderYAML {
 		var keeperRecords []interface{}
 		for _, record := range records {
@@ -51,19 +72,106 @@ func (h *MergeHistoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := h.Render.HTML(w, http.StatusOK, "merge_history", struct {
+	// the full records - unlike the exports which have 1 row per PR - used to bootstrap a new replica
+	if renderJSON {
+		if records == nil {
+			records = []webui.MergeRecord{}
+		}
+		if err := h.Render.JSON(w, http.StatusOK, records); err != nil {
+			h.Logger.WithError(err).Error("failed to encode merge history in JSON")
+		}
+		return
+	}
+
+	if feedFormat != "" {
+		if err := writeFeed(w, feedFormat, mergeHistoryFeed(r, records)); err != nil {
+			h.Logger.WithError(err).Error("failed to write merge history feed")
+		}
+		return
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "merge_history", struct {
 		Records    []webui.MergeRecord
 		Owner      string
 		Repository string
 		Branch     string
+		Source     string
 	}{
 		records,
 		owner,
 		repository,
 		branch,
+		source,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
+
+// export writes 1 row per PR of each record, most recent first
+func (h *MergeHistoryHandler) export(w http.ResponseWriter, r *http.Request, format string, records []webui.MergeRecord) {
+	e, err := newExporter(w, r, format, "merge-history", mergeRecordExportColumns)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sortedRecords := make([]webui.MergeRecord, len(records))
+	copy(sortedRecords, records)
+	sort.SliceStable(sortedRecords, func(i, j int) bool {
+		return sortedRecords[i].Time.After(sortedRecords[j].Time)
+	})
+
+	err = func() error {
+		for _, record := range sortedRecords {
+			for _, pr := range record.PRs {
+				if err := e.write(mergeRecordExportValues(record, pr)); err != nil {
+					return err
+				}
+			}
+		}
+		return e.close()
+	}()
+	if err != nil {
+		// the headers have already been written, so we can only log the error
+		h.Logger.WithError(err).Error("failed to export merge history")
+	}
+}
+
+func mergeHistoryFeed(r *http.Request, records []webui.MergeRecord) feed {
+	var (
+		baseURL = requestBaseURL(r)
+		f       = feed{
+			Title: strings.TrimSpace("Lighthouse Merge History " + strings.TrimPrefix(uiPath(r.URL.Path), "/merge/history/")),
+			Link:  baseURL + uiPath(r.URL.Path),
+			Self:  baseURL + r.URL.RequestURI(),
+		}
+	)
+
+	sortedRecords := make([]webui.MergeRecord, len(records))
+	copy(sortedRecords, records)
+	sort.SliceStable(sortedRecords, func(i, j int) bool {
+		return sortedRecords[i].Time.After(sortedRecords[j].Time)
+	})
+
+	for _, record := range sortedRecords {
+		var (
+			prs     []string
+			authors []string
+		)
+		for _, pr := range record.PRs {
+			prs = append(prs, fmt.Sprintf("#%d %s", pr.Number, pr.Title))
+			authors = append(authors, pr.Author)
+		}
+		f.Entries = append(f.Entries, feedEntry{
+			ID:      fmt.Sprintf("urn:lighthouse-webui:merge:%s/%s/%s:%s:%s", record.Owner, record.Repository, record.Branch, record.Time.Format(time.RFC3339Nano), record.BaseSHA),
+			Title:   fmt.Sprintf("[%s/%s %s] %s", record.Owner, record.Repository, record.Branch, record.Action),
+			Link:    fmt.Sprintf("%s/merge/history/%s/%s/%s", baseURL, record.Owner, record.Repository, record.Branch),
+			Summary: strings.Join(prs, ", "),
+			Author:  strings.Join(authors, ", "),
+			Updated: record.Time,
+		})
+	}
+	return f
+}
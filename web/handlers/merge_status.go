@@ -13,7 +13,7 @@ import (
 )
 
 type MergeStatusHandler struct {
-	Store  *webui.Store
+	Store  webui.Store
 	Render *render.Render
 	Logger *logrus.Logger
 }
@@ -24,10 +24,13 @@ func (h *MergeStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		owner      = vars["owner"]
 		repository = vars["repository"]
 		branch     = vars["branch"]
-		renderYAML = strings.HasSuffix(r.RequestURI, ".yaml")
+		source     = r.URL.Query().Get("source")
+		renderYAML = strings.HasSuffix(r.URL.Path, ".yaml")
+		renderJSON = strings.HasSuffix(r.URL.Path, ".json")
 	)
 
-	pools := h.Store.QueryMergeStatus(webui.MergeStatusQuery{
+	pools := webui.StoreWithContext(r.Context(), h.Store).QueryMergeStatus(webui.MergeStatusQuery{
+		Source:     source,
 		Owner:      owner,
 		Repository: repository,
 		Branch:     branch,
@@ -51,16 +54,36 @@ func (h *MergeStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.Render.HTML(w, http.StatusOK, "merge_status", struct {
-		Pools      []webui.MergePool
-		Owner      string
-		Repository string
-		Branch     string
+	if renderJSON {
+		if pools == nil {
+			pools = []webui.MergePool{}
+		}
+		if err := h.Render.JSON(w, http.StatusOK, pools); err != nil {
+			h.Logger.WithError(err).Error("failed to encode merge status in JSON")
+		}
+		return
+	}
+
+	blockingReasons, err := webui.QueryPoolsBlockingReasons(webui.StoreWithContext(r.Context(), h.Store), pools)
+	if err != nil {
+		// the merge status is still useful without the reasons
+		h.Logger.WithError(err).Warning("failed to load the blocking reasons of the missing PRs")
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "merge_status", struct {
+		Pools           []webui.MergePool
+		BlockingReasons map[string]map[int][]webui.BlockingReason
+		Owner           string
+		Repository      string
+		Branch          string
+		Source          string
 	}{
 		pools,
+		blockingReasons,
 		owner,
 		repository,
 		branch,
+		source,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+type RepositoriesHandler struct {
+	Store  webui.Store
+	Render *render.Render
+	Logger *logrus.Logger
+}
+
+func (h *RepositoriesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars  = mux.Vars(r)
+		owner = vars["owner"]
+	)
+
+	repositories, err := webui.QueryRepositories(webui.StoreWithContext(r.Context(), h.Store), webui.RepositoriesQuery{
+		Owner: owner,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "repositories", struct {
+		Repositories []webui.RepositorySummary
+		Owner        string
+	}{
+		repositories,
+		owner,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+// maxRepositoryRecentItems is the max number of recent events and merges displayed for a repository
+const maxRepositoryRecentItems = 20
+
+type RepositoryHandler struct {
+	Store  webui.Store
+	Render *render.Render
+	Logger *logrus.Logger
+}
+
+func (h *RepositoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars       = mux.Vars(r)
+		owner      = vars["owner"]
+		repository = vars["repository"]
+	)
+
+	overview, err := webui.QueryRepositoryOverview(webui.StoreWithContext(r.Context(), h.Store), webui.RepositoryOverviewQuery{
+		Owner:      owner,
+		Repository: repository,
+		MaxItems:   maxRepositoryRecentItems,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "repository", struct {
+		Overview   *webui.RepositoryOverview
+		Owner      string
+		Repository string
+	}{
+		overview,
+		owner,
+		repository,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
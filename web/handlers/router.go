@@ -3,7 +3,7 @@ package handlers
 import (
 	htmltemplate "html/template"
 	"net/http"
-	"text/template"
+	"time"
 
 	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
 	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/internal/lighthouse"
@@ -19,26 +19,26 @@ import (
 )
 
 type Router struct {
-	Store                 *webui.Store
+	Store                 webui.Store
 	LighthouseHandler     *lighthouse.Handler
 	LighthouseJobClient   lighthousev1alpha1.LighthouseJobInterface
-	EventTraceURLTemplate string
+	Replicator            *webui.Replicator
+	EventTraceURLTemplate *functions.URLTemplate
+	LinkTemplates         *functions.LinkTemplates
+	PipelineInformer      *webui.PipelineInformer
+	PodLogs               webui.PodLogsStreamer
+	JobArchive            *webui.JobArchive
+	GlobalSearches        *webui.GlobalSavedSearches
+	UserHeader            string
+	KeeperIngestToken     string
+	BackupToken           string
+	BadgeRepositories     []string
+	BadgeCacheMaxAge      time.Duration
 	Logger                *logrus.Logger
 	render                *render.Render
 }
 
 func (r Router) Handler() (http.Handler, error) {
-	var (
-		eventTraceURLTemplate *template.Template
-		err                   error
-	)
-	if len(r.EventTraceURLTemplate) > 0 {
-		eventTraceURLTemplate, err = template.New("eventTraceURL").Funcs(sprig.TxtFuncMap()).Parse(r.EventTraceURLTemplate)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	r.render = render.New(render.Options{
 		Directory:     "web/templates",
 		Layout:        "layout",
@@ -46,21 +46,69 @@ func (r Router) Handler() (http.Handler, error) {
 		Funcs: []htmltemplate.FuncMap{
 			sprig.HtmlFuncMap(),
 			htmltemplate.FuncMap{
-				"traceURL":         functions.TraceURLFunc(eventTraceURLTemplate),
-				"loadJobsForEvent": functions.LoadJobsForEventFunc(r.Store),
-				"loadEventForJob":  functions.LoadEventForJobFunc(r.Store),
-				"sortFacets":       functions.SortFacets,
-				"vdate":            functions.VDate,
-				"appVersion":       functions.AppVersion,
+				"traceURL":            functions.TraceURLFunc(r.EventTraceURLTemplate),
+				"links":               functions.LinksFunc(r.LinkTemplates),
+				"loadJobsForEvent":    functions.LoadJobsForEventFunc(r.Store),
+				"loadEventForJob":     functions.LoadEventForJobFunc(r.Store),
+				"loadBlockingReasons": functions.LoadBlockingReasonsFunc(r.Store),
+				"sortFacets":          functions.SortFacets,
+				"vdate":               functions.VDate,
+				"appVersion":          functions.AppVersion,
 			},
 		},
 	})
 
 	router := mux.NewRouter()
 	router.StrictSlash(true)
+	router.Use(tracingMiddleware)
 
-	router.Handle("/healthz", healthzHandler())
-	router.Handle("/lighthouse/events", r.LighthouseHandler) // TODO move to its own server?
+	router.Handle("/healthz", r.healthzHandler())
+	// the branch can contain slashes, such as feature/xyz
+	router.Handle("/badge/{owner}/{repository}/{branch:.+}.svg", &BadgeHandler{
+		Store:               r.Store,
+		AllowedRepositories: r.BadgeRepositories,
+		CacheMaxAge:         r.BadgeCacheMaxAge,
+		Logger:              r.Logger,
+	})
+	router.Handle("/lighthouse/events", r.replicated(r.LighthouseHandler)) // TODO move to its own server?
+	if r.Replicator != nil {
+		router.Handle(webui.SavedSearchesReplicationPath, r.Replicator.SavedSearchesHandler())
+	}
+
+	var deliveries *lighthouse.Deliveries
+	if r.LighthouseHandler != nil {
+		deliveries = r.LighthouseHandler.Deliveries
+	}
+	webhookDeliveriesHandler := &WebhookDeliveriesHandler{
+		Deliveries: deliveries,
+		Render:     r.render,
+		Logger:     r.Logger,
+	}
+	router.Handle("/admin/webhooks.json", webhookDeliveriesHandler)
+	router.Handle("/admin/webhooks", webhookDeliveriesHandler)
+	router.Handle("/metrics", &MetricsHandler{
+		Deliveries: deliveries,
+		Logger:     r.Logger,
+	})
+
+	if len(r.KeeperIngestToken) > 0 {
+		router.Handle("/keeper/{document:pools|history}", r.replicated(&KeeperIngestHandler{
+			Store:  r.Store,
+			Token:  r.KeeperIngestToken,
+			Logger: r.Logger,
+		}))
+	}
+
+	if len(r.BackupToken) > 0 {
+		backupHandler := &BackupHandler{
+			Store:  r.Store,
+			Token:  r.BackupToken,
+			Render: r.render,
+			Logger: r.Logger,
+		}
+		router.Handle("/backup", backupHandler)
+		router.Handle("/restore", r.replicated(backupHandler))
+	}
 
 	mergeStatusHandler := &MergeStatusHandler{
 		Store:  r.Store,
@@ -68,6 +116,10 @@ func (r Router) Handler() (http.Handler, error) {
 		Logger: r.Logger,
 	}
 	router.Handle("/merge/status", mergeStatusHandler)
+	router.Handle("/merge/status.json", mergeStatusHandler)
+	router.Handle("/merge/status/{owner}.json", mergeStatusHandler)
+	router.Handle("/merge/status/{owner}/{repository}.json", mergeStatusHandler)
+	router.Handle("/merge/status/{owner}/{repository}/{branch}.json", mergeStatusHandler)
 	router.Handle("/merge/status.yaml", mergeStatusHandler)
 	router.Handle("/merge/status/{owner}.yaml", mergeStatusHandler)
 	router.Handle("/merge/status/{owner}/{repository}.yaml", mergeStatusHandler)
@@ -76,11 +128,40 @@ func (r Router) Handler() (http.Handler, error) {
 	router.Handle("/merge/status/{owner}/{repository}", mergeStatusHandler)
 	router.Handle("/merge/status/{owner}/{repository}/{branch}", mergeStatusHandler)
 
+	mergeBlockedHandler := &MergeBlockedHandler{
+		Store:  r.Store,
+		Render: r.render,
+		Logger: r.Logger,
+	}
+	router.Handle("/merge/blocked", mergeBlockedHandler)
+	router.Handle("/merge/blocked/{owner}", mergeBlockedHandler)
+	router.Handle("/merge/blocked/{owner}/{repository}", mergeBlockedHandler)
+
 	mergeHistoryHandler := &MergeHistoryHandler{
 		Store:  r.Store,
 		Render: r.render,
 		Logger: r.Logger,
 	}
+	router.Handle("/merge/history.csv", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}.csv", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}.csv", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}/{branch}.csv", mergeHistoryHandler)
+	router.Handle("/merge/history.ndjson", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}.ndjson", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}.ndjson", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}/{branch}.ndjson", mergeHistoryHandler)
+	router.Handle("/merge/history.json", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}.json", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}.json", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}/{branch}.json", mergeHistoryHandler)
+	router.Handle("/merge/history.atom", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}.atom", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}.atom", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}/{branch}.atom", mergeHistoryHandler)
+	router.Handle("/merge/history.rss", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}.rss", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}.rss", mergeHistoryHandler)
+	router.Handle("/merge/history/{owner}/{repository}/{branch}.rss", mergeHistoryHandler)
 	router.Handle("/merge/history", mergeHistoryHandler)
 	router.Handle("/merge/history.yaml", mergeHistoryHandler)
 	router.Handle("/merge/history/{owner}.yaml", mergeHistoryHandler)
@@ -91,31 +172,135 @@ func (r Router) Handler() (http.Handler, error) {
 	router.Handle("/merge/history/{owner}/{repository}/{branch}", mergeHistoryHandler)
 
 	jobHandler := &JobHandler{
+		Store:               r.Store,
 		LighthouseJobClient: r.LighthouseJobClient,
+		Pipelines:           r.PipelineInformer,
+		Archive:             r.JobArchive,
 		Render:              r.render,
 		Logger:              r.Logger,
 	}
+	if r.PipelineInformer != nil || r.JobArchive != nil {
+		jobPipelineHandler := &JobPipelineHandler{
+			Store:     r.Store,
+			Pipelines: r.PipelineInformer,
+			PodLogs:   r.PodLogs,
+			Archive:   r.JobArchive,
+			Render:    r.render,
+			Logger:    r.Logger,
+		}
+		router.Handle("/job/{job}/pipeline.json", jobPipelineHandler)
+		router.Handle("/job/{job}/pipeline", jobPipelineHandler)
+		router.Handle("/job/{job}/logs/{taskrun}/{step}", jobPipelineHandler)
+	}
 	router.Handle("/job/{job}.yaml", jobHandler)
+	router.Handle("/job/{job}", jobHandler)
 
 	jobsHandler := &JobsHandler{
 		Store:  r.Store,
 		Render: r.render,
 		Logger: r.Logger,
 	}
+	router.Handle("/jobs.csv", jobsHandler)
+	router.Handle("/jobs/{owner}.csv", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}.csv", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}/{branch:.+}.csv", jobsHandler)
+	router.Handle("/jobs.ndjson", jobsHandler)
+	router.Handle("/jobs/{owner}.ndjson", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}.ndjson", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}/{branch:.+}.ndjson", jobsHandler)
+	router.Handle("/jobs.atom", jobsHandler)
+	router.Handle("/jobs/{owner}.atom", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}.atom", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}/{branch:.+}.atom", jobsHandler)
+	router.Handle("/jobs.rss", jobsHandler)
+	router.Handle("/jobs/{owner}.rss", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}.rss", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}/{branch:.+}.rss", jobsHandler)
 	router.Handle("/jobs/", jobsHandler)
 	router.Handle("/jobs/{owner}", jobsHandler)
 	router.Handle("/jobs/{owner}/{repository}", jobsHandler)
-	router.Handle("/jobs/{owner}/{repository}/{branch}", jobsHandler)
+	router.Handle("/jobs/{owner}/{repository}/{branch:.+}", jobsHandler)
 
 	eventsHandler := &EventsHandler{
 		Store:  r.Store,
 		Render: r.render,
 		Logger: r.Logger,
 	}
+	router.Handle("/events.csv", eventsHandler)
+	router.Handle("/events/{owner}.csv", eventsHandler)
+	router.Handle("/events/{owner}/{repository}.csv", eventsHandler)
+	router.Handle("/events/{owner}/{repository}/{branch:.+}.csv", eventsHandler)
+	router.Handle("/events.ndjson", eventsHandler)
+	router.Handle("/events/{owner}.ndjson", eventsHandler)
+	router.Handle("/events/{owner}/{repository}.ndjson", eventsHandler)
+	router.Handle("/events/{owner}/{repository}/{branch:.+}.ndjson", eventsHandler)
+	router.Handle("/events.atom", eventsHandler)
+	router.Handle("/events/{owner}.atom", eventsHandler)
+	router.Handle("/events/{owner}/{repository}.atom", eventsHandler)
+	router.Handle("/events/{owner}/{repository}/{branch:.+}.atom", eventsHandler)
+	router.Handle("/events.rss", eventsHandler)
+	router.Handle("/events/{owner}.rss", eventsHandler)
+	router.Handle("/events/{owner}/{repository}.rss", eventsHandler)
+	router.Handle("/events/{owner}/{repository}/{branch:.+}.rss", eventsHandler)
 	router.Handle("/events", eventsHandler)
 	router.Handle("/events/{owner}", eventsHandler)
 	router.Handle("/events/{owner}/{repository}", eventsHandler)
-	router.Handle("/events/{owner}/{repository}/{branch}", eventsHandler)
+	router.Handle("/events/{owner}/{repository}/{branch:.+}", eventsHandler)
+
+	router.Handle("/autocomplete/{page:jobs|events}", &AutocompleteHandler{
+		Store:  r.Store,
+		Render: r.render,
+		Logger: r.Logger,
+	})
+
+	repositoriesHandler := &RepositoriesHandler{
+		Store:  r.Store,
+		Render: r.render,
+		Logger: r.Logger,
+	}
+	router.Handle("/repos", repositoriesHandler)
+	router.Handle("/repos/{owner}", repositoriesHandler)
+	branchHistoryHandler := &BranchHistoryHandler{
+		Store:  r.Store,
+		Render: r.render,
+		Logger: r.Logger,
+	}
+	// the branch can contain slashes, and the handler strips the .json suffix
+	router.Handle("/repos/{owner}/{repository}/branches/{branch:.+}", branchHistoryHandler)
+	router.Handle("/repos/{owner}/{repository}", &RepositoryHandler{
+		Store:  r.Store,
+		Render: r.render,
+		Logger: r.Logger,
+	})
+
+	userHandler := &UserHandler{
+		Store:  r.Store,
+		Render: r.render,
+		Logger: r.Logger,
+	}
+	router.Handle("/users/{login}.json", userHandler)
+	router.Handle("/users/{login}", userHandler)
+
+	commitHandler := &CommitHandler{
+		Store:  r.Store,
+		Render: r.render,
+		Logger: r.Logger,
+	}
+	router.Handle("/commit/{owner}/{repository}/{sha}.json", commitHandler)
+	router.Handle("/commit/{owner}/{repository}/{sha}", commitHandler)
+
+	savedSearchesHandler := r.replicated(&SavedSearchesHandler{
+		Store:          r.Store,
+		GlobalSearches: r.GlobalSearches,
+		UserHeader:     r.UserHeader,
+		Render:         r.render,
+		Logger:         r.Logger,
+	})
+	router.Handle("/searches.json", savedSearchesHandler)
+	router.Handle("/searches/{name}/delete", savedSearchesHandler).Methods(http.MethodPost)
+	router.Handle("/searches/{name}/open", savedSearchesHandler).Methods(http.MethodPost)
+	router.Handle("/searches/{name}", savedSearchesHandler)
+	router.Handle("/searches", savedSearchesHandler)
 
 	router.Handle("/", http.RedirectHandler("/events", http.StatusPermanentRedirect))
 	router.Handle("/merge", http.RedirectHandler("/merge/status", http.StatusPermanentRedirect))
@@ -133,8 +318,21 @@ func (r Router) Handler() (http.Handler, error) {
 	return handler, nil
 }
 
-func healthzHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// healthzHandler is used as the readiness probe: when replicated, a replica is not ready until it has loaded the events of its peers
+func (r Router) healthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.Replicator != nil && !r.Replicator.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 	})
 }
+
+// replicated forwards the requests received by the given ingestion handler to the other replicas, if any
+func (r Router) replicated(handler http.Handler) http.Handler {
+	if r.Replicator == nil {
+		return handler
+	}
+	return r.Replicator.Handler(handler)
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+// userCookieName is the name of the cookie which identifies the users, when there is no authenticating proxy
+const userCookieName = "lighthouse-webui-user"
+
+var userCookieRegExp = regexp.MustCompile("^[a-f0-9]{32}$")
+
+// SavedSearchesHandler manages the searches saved by the current user, and lists them with the global searches.
+// The users are identified by the UserHeader set by an authenticating proxy - such as X-Forwarded-User -
+// or if there is no UserHeader, by a random ID stored in a cookie.
+type SavedSearchesHandler struct {
+	Store          webui.Store
+	GlobalSearches *webui.GlobalSavedSearches
+	UserHeader     string
+	Render         *render.Render
+	Logger         *logrus.Logger
+}
+
+// savedSearchView is a saved search, with the number of results since it was last viewed
+type savedSearchView struct {
+	webui.SavedSearch
+	Global     bool
+	URL        string
+	FeedURL    string
+	NewResults int
+}
+
+func (h *SavedSearchesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars       = mux.Vars(r)
+		name       = vars["name"]
+		renderJSON = strings.HasSuffix(r.URL.Path, ".json")
+	)
+
+	switch {
+	case r.Method == http.MethodPost && name == "":
+		h.save(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/delete"):
+		h.delete(w, r, name)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/open"):
+		h.open(w, r, name, true)
+	case r.Method == http.MethodPost:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	case name != "":
+		h.open(w, r, name, false)
+	default:
+		h.list(w, r, renderJSON)
+	}
+}
+
+func (h *SavedSearchesHandler) list(w http.ResponseWriter, r *http.Request, renderJSON bool) {
+	store := webui.StoreWithContext(r.Context(), h.Store)
+	user := h.user(w, r, false)
+
+	var userSearches []webui.SavedSearch
+	if user != "" {
+		var err error
+		userSearches, err = store.QuerySavedSearches(user)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	searches := make([]savedSearchView, 0, len(userSearches))
+	for _, search := range userSearches {
+		// the ID of the user is in an HttpOnly cookie, which must not be readable by the scripts
+		search.User = ""
+		view := savedSearchView{
+			SavedSearch: search,
+			URL:         "/searches/" + url.PathEscape(search.Name),
+			FeedURL:     search.FeedURL(),
+		}
+		if search.Notify {
+			view.NewResults = h.countNewResults(store, search)
+		}
+		searches = append(searches, view)
+	}
+	for _, search := range h.GlobalSearches.Get() {
+		searches = append(searches, savedSearchView{
+			SavedSearch: search,
+			Global:      true,
+			URL:         search.URL(),
+			FeedURL:     search.FeedURL(),
+		})
+	}
+
+	if renderJSON {
+		err := h.Render.JSON(w, http.StatusOK, searches)
+		if err != nil {
+			h.Logger.WithError(err).Error("failed to encode saved searches in JSON")
+		}
+		return
+	}
+
+	err := h.Render.HTML(w, http.StatusOK, "saved_searches", struct {
+		Searches []savedSearchView
+		CanSave  bool
+	}{
+		searches,
+		user != "" || h.UserHeader == "",
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// countNewResults returns the number of results since the search was last viewed
+func (h *SavedSearchesHandler) countNewResults(store webui.Store, search webui.SavedSearch) int {
+	since := search.LastViewed
+	if since.IsZero() {
+		since = search.Created
+	}
+
+	var (
+		count int
+		err   error
+	)
+	switch search.Page {
+	case webui.SavedSearchPageJobs:
+		var q webui.JobsQuery
+		if q, err = search.JobsQuery(since); err == nil {
+			count, err = store.CountJobs(q)
+		}
+	case webui.SavedSearchPageEvents:
+		var q webui.EventsQuery
+		if q, err = search.EventsQuery(since); err == nil {
+			count, err = store.CountEvents(q)
+		}
+	}
+	if err != nil {
+		h.Logger.WithError(err).WithField("search", search.Name).Warning("failed to count the new results of the saved search")
+	}
+	return count
+}
+
+func (h *SavedSearchesHandler) save(w http.ResponseWriter, r *http.Request) {
+	user := h.user(w, r, true)
+	if user == "" {
+		http.Error(w, "the searches can only be saved by an authenticated user", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now().UTC()
+	search := webui.SavedSearch{
+		User:       user,
+		Name:       strings.TrimSpace(r.PostFormValue("name")),
+		Page:       r.PostFormValue("page"),
+		Path:       strings.Trim(r.PostFormValue("path"), "/"),
+		Query:      r.PostFormValue("q"),
+		From:       r.PostFormValue("from"),
+		To:         r.PostFormValue("to"),
+		Notify:     r.PostFormValue("notify") != "",
+		LastViewed: now,
+		Created:    now,
+	}
+	if err := search.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := webui.StoreWithContext(r.Context(), h.Store).SaveSearch(search); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, search.URL(), http.StatusSeeOther)
+}
+
+func (h *SavedSearchesHandler) delete(w http.ResponseWriter, r *http.Request, name string) {
+	user := h.user(w, r, false)
+	if user == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if err := webui.StoreWithContext(r.Context(), h.Store).DeleteSavedSearch(user, name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/searches", http.StatusSeeOther)
+}
+
+// open redirects to the page of the search - and if markViewed is true, marks its results as viewed.
+// Marking the results as viewed is only done on a POST, so that it is replicated to the other replicas.
+func (h *SavedSearchesHandler) open(w http.ResponseWriter, r *http.Request, name string, markViewed bool) {
+	user := h.user(w, r, false)
+	if user == "" {
+		http.NotFound(w, r)
+		return
+	}
+	store := webui.StoreWithContext(r.Context(), h.Store)
+	searches, err := store.QuerySavedSearches(user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, search := range searches {
+		if search.Name != name {
+			continue
+		}
+		if markViewed {
+			search.LastViewed = time.Now().UTC()
+			if err = store.SaveSearch(search); err != nil {
+				h.Logger.WithError(err).WithField("search", name).Warning("failed to update the saved search")
+			}
+		}
+		http.Redirect(w, r, search.URL(), http.StatusSeeOther)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// user returns the current user - and if create is true and there is no authenticating proxy, identifies a new user with a cookie
+func (h *SavedSearchesHandler) user(w http.ResponseWriter, r *http.Request, create bool) string {
+	if h.UserHeader != "" {
+		return strings.TrimSpace(r.Header.Get(h.UserHeader))
+	}
+
+	if cookie, err := r.Cookie(userCookieName); err == nil && userCookieRegExp.MatchString(cookie.Value) {
+		return cookie.Value
+	}
+	if !create {
+		return ""
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		h.Logger.WithError(err).Error("failed to generate a user ID")
+		return ""
+	}
+	user := hex.EncodeToString(id)
+	cookie := &http.Cookie{
+		Name:     userCookieName,
+		Value:    user,
+		Path:     "/",
+		MaxAge:   10 * 365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, cookie)
+	// so that the same user is used when the request is forwarded to the other replicas
+	r.AddCookie(cookie)
+	return user
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestSavedSearchesHandler(t *testing.T) *SavedSearchesHandler {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store, err := webui.NewBleveStore(webui.StoreConfig{}, logger)
+	if err != nil {
+		t.Fatalf("failed to create the store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return &SavedSearchesHandler{
+		Store:      store,
+		UserHeader: "X-Forwarded-User",
+		Logger:     logger,
+	}
+}
+
+func TestSavedSearchesHandlerOpen(t *testing.T) {
+	h := newTestSavedSearchesHandler(t)
+	lastViewed := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	err := h.Store.SaveSearch(webui.SavedSearch{User: "alice", Name: "pushes", Page: webui.SavedSearchPageEvents, Query: "Kind:push", Notify: true, LastViewed: lastViewed, Created: lastViewed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Forwarded-User", "alice")
+		h.ServeHTTP(rec, mux.SetURLVars(req, map[string]string{"name": "pushes"}))
+		return rec
+	}
+	lastViewedTime := func() time.Time {
+		searches, err := h.Store.QuerySavedSearches("alice")
+		if err != nil || len(searches) != 1 {
+			t.Fatalf("expected 1 saved search, got %v (%v)", searches, err)
+		}
+		return searches[0].LastViewed
+	}
+
+	rec := open(http.MethodGet, "/searches/pushes")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/events?q=Kind%3Apush" {
+		t.Errorf("expected a redirect to the events page, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if viewed := lastViewedTime(); !viewed.Equal(lastViewed) {
+		t.Errorf("expected a GET not to mark the results as viewed, got %s", viewed)
+	}
+
+	rec = open(http.MethodPost, "/searches/pushes/open")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/events?q=Kind%3Apush" {
+		t.Errorf("expected a redirect to the events page, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if viewed := lastViewedTime(); !viewed.After(lastViewed) {
+		t.Errorf("expected a POST to mark the results as viewed, got %s", viewed)
+	}
+}
+
+func TestSavedSearchesHandlerCountNewResults(t *testing.T) {
+	h := newTestSavedSearchesHandler(t)
+	now := time.Now().UTC()
+	search := webui.SavedSearch{User: "alice", Name: "pushes", Page: webui.SavedSearchPageEvents, Query: "Kind:push", Notify: true, LastViewed: now.Add(-time.Hour)}
+
+	for i := 0; i < 3; i++ {
+		if err := h.Store.AddEvent(webui.Event{GUID: fmt.Sprintf("guid-%d", i), Kind: "push", Time: now.Add(-time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, event := range []webui.Event{
+		{GUID: "old", Kind: "push", Time: now.Add(-2 * time.Hour)},
+		{GUID: "other", Kind: "pull_request", Time: now},
+	} {
+		if err := h.Store.AddEvent(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := h.countNewResults(h.Store, search); count != 3 {
+		t.Errorf("expected 3 new results, got %d", count)
+	}
+}
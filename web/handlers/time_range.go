@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+)
+
+// timeRangeFromRequest parses the "from" and "to" query parameters,
+// as absolute or relative times - see webui.ParseTime for the supported formats
+func timeRangeFromRequest(r *http.Request) (from, to time.Time, err error) {
+	now := time.Now()
+	if from, err = webui.ParseTime(r.URL.Query().Get("from"), now); err != nil {
+		return from, to, fmt.Errorf("invalid from parameter: %w", err)
+	}
+	if to, err = webui.ParseTime(r.URL.Query().Get("to"), now); err != nil {
+		return from, to, fmt.Errorf("invalid to parameter: %w", err)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	webui "github.com/jenkins-x-plugins/lighthouse-webui-plugin"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+type UserHandler struct {
+	Store  webui.Store
+	Render *render.Render
+	Logger *logrus.Logger
+}
+
+func (h *UserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars       = mux.Vars(r)
+		login      = vars["login"]
+		renderJSON = strings.HasSuffix(r.URL.Path, ".json")
+	)
+
+	activity, err := webui.QueryUserActivity(webui.StoreWithContext(r.Context(), h.Store), webui.UserActivityQuery{
+		Login: login,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if renderJSON {
+		err = h.Render.JSON(w, http.StatusOK, activity)
+		if err != nil {
+			h.Logger.WithError(err).Error("failed to encode user activity in JSON")
+		}
+		return
+	}
+
+	err = h.Render.HTML(w, http.StatusOK, "user", struct {
+		Activity *webui.UserActivity
+		Login    string
+	}{
+		activity,
+		login,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/lighthouse-webui-plugin/internal/lighthouse"
+
+	"github.com/sirupsen/logrus"
+	"github.com/unrolled/render"
+)
+
+// WebhookDeliveriesHandler shows the health of the deliveries of the Lighthouse events to this replica
+type WebhookDeliveriesHandler struct {
+	Deliveries *lighthouse.Deliveries
+	Render     *render.Render
+	Logger     *logrus.Logger
+}
+
+func (h *WebhookDeliveriesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stats := h.Deliveries.Stats()
+
+	if strings.HasSuffix(r.URL.Path, ".json") {
+		err := h.Render.JSON(w, http.StatusOK, stats)
+		if err != nil {
+			h.Logger.WithError(err).Error("failed to encode webhook deliveries in JSON")
+		}
+		return
+	}
+
+	err := h.Render.HTML(w, http.StatusOK, "webhook_deliveries", struct {
+		Stats lighthouse.DeliveryStats
+	}{
+		stats,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// MetricsHandler exposes the webhook deliveries in the Prometheus text format
+type MetricsHandler struct {
+	Deliveries *lighthouse.Deliveries
+	Logger     *logrus.Logger
+}
+
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	stats := h.Deliveries.Stats()
+
+	var b strings.Builder
+	b.WriteString("# HELP lighthouse_webui_webhook_deliveries_total The number of events received from Lighthouse, by result.\n")
+	b.WriteString("# TYPE lighthouse_webui_webhook_deliveries_total counter\n")
+	for _, result := range stats.Results() {
+		fmt.Fprintf(&b, "lighthouse_webui_webhook_deliveries_total{result=%q} %d\n", result, stats.Counts[result])
+	}
+	b.WriteString("# HELP lighthouse_webui_webhook_last_success_timestamp_seconds The time of the last event successfully handled.\n")
+	b.WriteString("# TYPE lighthouse_webui_webhook_last_success_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "lighthouse_webui_webhook_last_success_timestamp_seconds %d\n", unixTime(stats.LastSuccess))
+	b.WriteString("# HELP lighthouse_webui_webhook_last_failure_timestamp_seconds The time of the last event which could not be handled.\n")
+	b.WriteString("# TYPE lighthouse_webui_webhook_last_failure_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "lighthouse_webui_webhook_last_failure_timestamp_seconds %d\n", unixTime(stats.LastFailure))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := w.Write([]byte(b.String())); err != nil {
+		h.Logger.WithError(err).Error("failed to write metrics")
+	}
+}
+
+// unixTime returns the Unix time in seconds - or zero for the zero time, as Prometheus expects
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -44,6 +44,18 @@ $(document).ready(() => {
         }
     });
 
+    $('#blocked').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 25,
+        order: [[0, 'asc']],
+        columnDefs: [
+            { targets: 'since', orderDataType: 'dom-order' }
+        ],
+        language: {
+            emptyTable: "No branch is currently blocked.<br>See the <a href='/merge/status'>Merge Status</a> instead?"
+        }
+    });
+
     $('#records').DataTable({
         lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
         pageLength: 25,
@@ -56,6 +68,132 @@ $(document).ready(() => {
         }
     });
 
+    $('#user-pool-prs').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 10,
+        order: [[3, 'desc']],
+        columnDefs: [
+            { targets: 'updatedAt', orderDataType: 'dom-order' }
+        ],
+        language: {
+            emptyTable: "No Pull Request in the Merge Pools at the moment."
+        }
+    });
+
+    $('#user-merged-prs').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 10,
+        order: [[0, 'desc']],
+        columnDefs: [
+            { targets: 'time', orderDataType: 'dom-order' },
+            { targets: 'duration', orderDataType: 'dom-order', type: 'numeric' }
+        ],
+        language: {
+            emptyTable: "No merged Pull Request in the Merge History."
+        }
+    });
+
+    $('#user-jobs').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 10,
+        order: [[0, 'desc']],
+        columnDefs: [
+            { targets: 'start', orderDataType: 'dom-order' },
+            { targets: 'duration', orderDataType: 'dom-order', type: 'numeric' }
+        ]
+    });
+
+    $('#user-events').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 10,
+        order: [[0, 'desc']],
+        columnDefs: [
+            { targets: 'time', orderDataType: 'dom-order' }
+        ]
+    });
+
+    $('#commit-produced-by, #commit-merges').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 10,
+        order: [[0, 'desc']],
+        columnDefs: [
+            { targets: 'time', orderDataType: 'dom-order' }
+        ],
+        language: {
+            emptyTable: "No merge with this commit in the Merge History."
+        }
+    });
+
+    $('#commit-events').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 10,
+        order: [[0, 'desc']],
+        columnDefs: [
+            { targets: 'time', orderDataType: 'dom-order' }
+        ]
+    });
+
+    $('#commit-jobs').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 10,
+        order: [[0, 'desc']],
+        columnDefs: [
+            { targets: 'start', orderDataType: 'dom-order' },
+            { targets: 'duration', orderDataType: 'dom-order', type: 'numeric' }
+        ]
+    });
+
+    $('#branch-contexts').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 25,
+        order: [[0, 'asc'], [1, 'asc']],
+        language: {
+            emptyTable: "No postsubmit job for this branch."
+        }
+    });
+
+    $('#webhook-failures').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 25,
+        order: [[0, 'desc']],
+        columnDefs: [
+            { targets: 'time', orderDataType: 'dom-order' }
+        ],
+        language: {
+            emptyTable: "No failed delivery since the start of this replica."
+        }
+    });
+
+    $('#repositories').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 50,
+        order: [[1, 'asc']],
+        columnDefs: [
+            { targets: 'time', orderDataType: 'dom-order' }
+        ],
+        language: {
+            emptyTable: "No repository known yet - we need at least an event or a job."
+        }
+    });
+
+    $('#saved-searches').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 50,
+        order: [[0, 'asc']],
+        language: {
+            emptyTable: "No saved search yet - save one from the events or jobs page."
+        }
+    });
+
+    $('.overview-table').DataTable({
+        lengthMenu: [ [10, 25, 50, 100, -1], [10, 25, 50, 100, "All"] ],
+        pageLength: 10,
+        order: [],
+        columnDefs: [
+            { targets: 'time', orderDataType: 'dom-order' }
+        ]
+    });
+
 });
 
 (function(){
@@ -81,4 +219,141 @@ $(document).ready(() => {
             }
         })
     });
-})();
\ No newline at end of file
+})();
+(function(){
+    const logsContainer = document.querySelector('.step-logs-container');
+    if (!logsContainer) {
+        return;
+    }
+    const logsTitle = logsContainer.querySelector('.step-logs-title');
+    const logs = logsContainer.querySelector('.step-logs');
+    let abortController = null;
+
+    const streamLogs = (url, title) => {
+        if (abortController) {
+            abortController.abort();
+        }
+        abortController = new AbortController();
+        const signal = abortController.signal;
+
+        logsTitle.textContent = title;
+        logs.textContent = '';
+        logsContainer.classList.remove('hidden');
+
+        fetch(url, {signal}).then(response => {
+            if (!response.ok) {
+                return response.text().then(text => {
+                    logs.textContent = text;
+                });
+            }
+            const reader = response.body.getReader();
+            const decoder = new TextDecoder();
+            const read = () => reader.read().then(({done, value}) => {
+                if (done) {
+                    return;
+                }
+                const scrolledToBottom = logs.scrollTop + logs.clientHeight >= logs.scrollHeight - 5;
+                logs.textContent += decoder.decode(value, {stream: true});
+                if (scrolledToBottom) {
+                    logs.scrollTop = logs.scrollHeight;
+                }
+                return read();
+            });
+            return read();
+        }).catch(error => {
+            if (error.name != 'AbortError') {
+                console.log("oops, failed to stream the logs", error);
+            }
+        });
+    };
+
+    document.querySelectorAll('.step-logs-button').forEach(element => {
+        element.addEventListener('click', event => {
+            streamLogs(event.target.dataset.url, event.target.dataset.title);
+        })
+    });
+})();
+(function(){
+    const menu = document.querySelector('.saved-searches-menu');
+    if (!menu) {
+        return;
+    }
+    const list = menu.querySelector('.saved-searches-list');
+    const badge = menu.querySelector('.saved-searches-new');
+
+    fetch('/searches.json').then(response => response.json()).then(searches => {
+        let newResults = 0;
+        searches.forEach(search => {
+            const item = document.createElement('li');
+            const link = document.createElement('a');
+            link.href = search.URL;
+            link.textContent = search.Name;
+            if (!search.Global) {
+                // opening a saved search marks its results as viewed, which must be a POST
+                link.addEventListener('click', event => {
+                    event.preventDefault();
+                    const form = document.createElement('form');
+                    form.method = 'post';
+                    form.action = search.URL + '/open';
+                    document.body.appendChild(form);
+                    form.submit();
+                });
+            }
+            item.appendChild(link);
+            if (search.NewResults > 0) {
+                item.appendChild(document.createTextNode(' (' + search.NewResults + ' new)'));
+                newResults += search.NewResults;
+            }
+            list.appendChild(item);
+        });
+        if (newResults > 0) {
+            badge.textContent = newResults;
+            badge.classList.remove('hidden');
+        }
+    }).catch(error => {
+        console.log("oops, failed to load the saved searches", error);
+    });
+})();
+(function(){
+    const input = document.querySelector('.query-input');
+    if (!input) {
+        return;
+    }
+    const suggestions = document.getElementById(input.getAttribute('list'));
+    const fields = document.querySelector('.query-fields');
+    let timeout = null;
+    let abortController = null;
+
+    const autocomplete = () => {
+        if (abortController) {
+            abortController.abort();
+        }
+        abortController = new AbortController();
+        const signal = abortController.signal;
+
+        fetch(input.dataset.autocomplete + '?q=' + encodeURIComponent(input.value), {signal}).then(response => response.json()).then(result => {
+            suggestions.textContent = '';
+            result.Suggestions.forEach(suggestion => {
+                const option = document.createElement('option');
+                option.value = suggestion.Query;
+                option.label = suggestion.Count > 0 ? suggestion.Term + ' (' + suggestion.Count + ')' : suggestion.Term;
+                suggestions.appendChild(option);
+            });
+            if (fields && fields.classList.contains('hidden')) {
+                fields.textContent = 'Fields: ' + result.Fields.map(field => field.Name).join(', ');
+                fields.classList.remove('hidden');
+            }
+        }).catch(error => {
+            if (error.name != 'AbortError') {
+                console.log("oops, failed to autocomplete the query", error);
+            }
+        });
+    };
+
+    ['input', 'focus'].forEach(eventName => {
+        input.addEventListener(eventName, () => {
+            clearTimeout(timeout);
+            timeout = setTimeout(autocomplete, 200);
+        });
+    });
+})();